@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"awesome-veganism-feed/feedgen"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// names of batch sources end up in paths and must not leave the templated directory
+var sourceNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// batchSource is a list to generate feeds for in a batch run
+type batchSource struct {
+	Name   string
+	Source string
+	// flags of this source only, given as name=value columns
+	Flags []string
+}
+
+// batchResult is the outcome of generating the feeds of one source
+type batchResult struct {
+	Destdir  string
+	Duration time.Duration
+	Err      error
+	// output of the run, shown for failed sources
+	Log []byte
+}
+
+// runBatch generates the feeds of many lists, each one in a process of its own so a failing
+// list never takes the others down; arguments after the batch flags are passed to every run
+func runBatch(args []string) {
+	var template, file, cacheDir string
+	var jobs int
+
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	fs.StringVar(&template, "destdir-template", "", "destination directory of every source with {name} replaced by its name")
+	fs.StringVar(&file, "file", "-", "file with one source per line: name, local directory or remote url, then name=value flags")
+	fs.IntVar(&jobs, "jobs", 4, "number of sources generated at the same time")
+	fs.StringVar(&cacheDir, "cache-dir", "", "directory remote sources are cloned into, in the user cache directory by default")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s: %s [flags] [-- flags of every run]\n", fs.Name(), fs.Name())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if !strings.Contains(template, "{name}") {
+		log.Fatalf("missing {name} in -destdir-template: %q", template)
+	}
+	if jobs < 1 {
+		log.Fatalf("invalid -jobs: %d", jobs)
+	}
+	if cacheDir == "" {
+		dir, err := defaultCacheDir()
+		if err != nil {
+			log.Fatalf("missing -cache-dir: %v", err)
+		}
+		cacheDir = dir
+	}
+
+	// clones are only pruned while no batch run is updating them
+	lock, err := feedgen.LockDir(cacheDir)
+	if err != nil {
+		log.Fatalf("failed to lock cache directory: %v", err)
+	}
+	defer lock.Close()
+
+	in := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			log.Fatalf("failed to open sources: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	sources, err := readSources(in)
+	if err != nil {
+		log.Fatalf("failed to read sources: %v", err)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		log.Fatalf("failed to find own executable: %v", err)
+	}
+	feedgen.InstallGitTransport(feedgen.NewHTTPClient(feedgen.DefaultUserAgent(), ""))
+
+	results := make([]batchResult, len(sources))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range queue {
+				results[n] = generateSource(self, sources[n], template, cacheDir, fs.Args())
+			}
+		}()
+	}
+	for n := range sources {
+		queue <- n
+	}
+	close(queue)
+	wg.Wait()
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tDURATION\tDESTDIR")
+	for n, s := range sources {
+		r := results[n]
+		status := "ok"
+		if r.Err != nil {
+			status = "failed: " + r.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, status, r.Duration.Round(time.Millisecond), r.Destdir)
+	}
+	w.Flush()
+
+	for n, s := range sources {
+		if r := results[n]; r.Err != nil && len(r.Log) > 0 {
+			fmt.Fprintf(os.Stderr, "\n==> %s\n%s", s.Name, r.Log)
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// defaultCacheDir is the directory in the user cache directory clones and cached responses are kept in
+func defaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "awesome-veganism-feed"), nil
+}
+
+// readSources parses source lines, skipping blank lines and comments starting with #
+func readSources(r io.Reader) ([]batchSource, error) {
+	var sources []batchSource
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		cols, err := feedgen.SplitColumns(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(cols) < 2 {
+			return nil, fmt.Errorf("line %d: expected a name and a source", line)
+		}
+
+		s := batchSource{Name: cols[0], Source: cols[1]}
+		if !sourceNameRe.MatchString(s.Name) {
+			return nil, fmt.Errorf("line %d: invalid name: %s", line, s.Name)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("line %d: duplicate name: %s", line, s.Name)
+		}
+		seen[s.Name] = true
+
+		for _, c := range cols[2:] {
+			name, value, found := strings.Cut(c, "=")
+			if !found || name == "" {
+				return nil, fmt.Errorf("line %d: expected name=value: %s", line, c)
+			}
+			s.Flags = append(s.Flags, "-"+strings.TrimLeft(name, "-")+"="+value)
+		}
+
+		sources = append(sources, s)
+	}
+
+	return sources, scanner.Err()
+}
+
+// generateSource runs the generation of one source as a child process
+func generateSource(self string, s batchSource, template string, cacheDir string, common []string) batchResult {
+	start := time.Now()
+	r := batchResult{Destdir: strings.ReplaceAll(template, "{name}", s.Name)}
+
+	workdir := s.Source
+	if isRemote(s.Source) {
+		workdir = filepath.Join(cacheDir, s.Name)
+		if err := syncClone(s.Source, workdir); err != nil {
+			r.Err = fmt.Errorf("failed to fetch %s: %v", s.Source, err)
+			r.Duration = time.Since(start)
+			return r
+		}
+	}
+
+	// flags of the source come last and win over the common ones
+	args := append(append([]string{}, common...), "-workdir", workdir, "-destdir", r.Destdir)
+	args = append(args, s.Flags...)
+
+	var out bytes.Buffer
+	cmd := exec.Command(self, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		r.Err = err
+	}
+	r.Log = out.Bytes()
+	r.Duration = time.Since(start)
+
+	return r
+}
+
+// isRemote tells remote urls, including scp like ones, apart from local directories
+func isRemote(source string) bool {
+	if strings.Contains(source, "://") {
+		return true
+	}
+
+	// user@host:path, but not a windows drive letter
+	host, _, found := strings.Cut(source, ":")
+	return found && len(host) > 1 && !strings.ContainsAny(host, `/\`)
+}
+
+// syncClone brings a clone of a remote up to date, cloning it again when history was rewritten
+func syncClone(url string, dir string) error {
+	r, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		_, err = git.PlainClone(dir, false, &git.CloneOptions{URL: url})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	wt, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	err = wt.Pull(&git.PullOptions{RemoteName: "origin"})
+	if err == nil || errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	if !errors.Is(err, git.ErrNonFastForwardUpdate) {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	_, err = git.PlainClone(dir, false, &git.CloneOptions{URL: url})
+
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfig sets every flag not given on the command line or in the environment from a
+// yaml file keyed by flag name, lists are used for repeatable flags
+func applyConfig(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for _, name := range sortedKeys(values) {
+		if name == "config" || fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown setting: %s", path, name)
+		}
+		if explicit[name] {
+			continue
+		}
+
+		list, ok := values[name].([]interface{})
+		if !ok {
+			list = []interface{}{values[name]}
+		}
+		for _, v := range list {
+			if v == nil {
+				continue
+			}
+			if err := fs.Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("%s: invalid value %v for %s: %v", path, v, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// logSettings prints every flag with its effective value and where the value came from
+func logSettings(fs *flag.FlagSet, source map[string]string) {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	sort.Strings(names)
+
+	log.Printf("effective configuration:")
+	for _, name := range names {
+		s := source[name]
+		if s == "" {
+			s = "default"
+		}
+		log.Printf("  %s=%q (%s)", name, fs.Lookup(name).Value.String(), s)
+	}
+}
+
+// sortedKeys returns the keys of a map in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"awesome-veganism-feed/feedgen"
+	"awesome-veganism-feed/feedgentest"
+)
+
+// demoSection is a heading of the demo list with its entries as markdown lines
+type demoSection struct {
+	Name    string
+	Entries []string
+}
+
+// demoStep changes the demo list in one commit, based on the list after an earlier step
+type demoStep struct {
+	Message string
+	Author  string
+	// indexes of the steps the commit is based on, the previous one by default
+	Parents []int
+	// where the list is kept, the work file by default
+	Path string
+	Edit func(list []demoSection) []demoSection
+}
+
+// demoEntry is the markdown line of an entry, its url derived from the name
+func demoEntry(name string, desc string) string {
+	return fmt.Sprintf("- [%s](https://%s.example/) - %s", name, strings.ReplaceAll(strings.ToLower(name), " ", "-"), desc)
+}
+
+// demoAdd appends an entry to a section
+func demoAdd(section string, line string) func([]demoSection) []demoSection {
+	return func(list []demoSection) []demoSection {
+		for n := range list {
+			if list[n].Name == section {
+				list[n].Entries = append(list[n].Entries, line)
+			}
+		}
+		return list
+	}
+}
+
+// demoRemove drops the entry with the given name from any section and returns its line
+func demoRemove(list []demoSection, name string) string {
+	for n := range list {
+		for i, l := range list[n].Entries {
+			if strings.HasPrefix(l, "- ["+name+"](") {
+				list[n].Entries = append(list[n].Entries[:i:i], list[n].Entries[i+1:]...)
+				return l
+			}
+		}
+	}
+
+	return ""
+}
+
+// demoReplace swaps the line of an entry for another one in place
+func demoReplace(name string, line string) func([]demoSection) []demoSection {
+	return func(list []demoSection) []demoSection {
+		for n := range list {
+			for i, l := range list[n].Entries {
+				if strings.HasPrefix(l, "- ["+name+"](") {
+					list[n].Entries[i] = line
+				}
+			}
+		}
+		return list
+	}
+}
+
+// demoSteps is the history of the demo list: additions, removals, updates, a rename, a move
+// between sections, a merged branch, a hashtag and the list moved away for a while
+var demoSteps = []demoStep{
+	{Message: "Start the list", Author: "Alice", Edit: func(list []demoSection) []demoSection {
+		return []demoSection{{Name: "Restaurants"}, {Name: "Products"}, {Name: "Blogs"}}
+	}},
+	{Message: "Add Tofu Town", Author: "Alice", Edit: demoAdd("Restaurants", demoEntry("Tofu Town", "All things tofu."))},
+	{Message: "Add Oat Dream", Author: "Bob", Edit: demoAdd("Products", demoEntry("Oat Dream", "Oat milk."))},
+	{Message: "Add Green Blog", Author: "Carol", Edit: demoAdd("Blogs", demoEntry("Green Blog", "Recipes and stories."))},
+	{Message: "Add Seitan Shop", Author: "Bob", Edit: demoAdd("Products", demoEntry("Seitan Shop", "Wheat meat to go."))},
+	{Message: "Add Bean Bistro", Author: "Alice", Edit: demoAdd("Restaurants", demoEntry("Bean Bistro", "Beans, mostly."))},
+	{Message: "Describe Oat Dream better", Author: "Carol", Edit: demoReplace("Oat Dream", demoEntry("Oat Dream", "Barista oat milk in returnable bottles."))},
+	{Message: "Add Plant Post", Author: "Dan", Edit: demoAdd("Blogs", demoEntry("Plant Post", "Weekly plant based news."))},
+	{Message: "Remove Bean Bistro, it closed", Author: "Alice", Edit: func(list []demoSection) []demoSection {
+		demoRemove(list, "Bean Bistro")
+		return list
+	}},
+	{Message: "Rename Green Blog", Author: "Carol", Edit: demoReplace("Green Blog", "- [Green Kitchen Blog](https://green-blog.example/) - Recipes and stories.")},
+	{Message: "Seitan Shop is a restaurant now", Author: "Bob", Edit: func(list []demoSection) []demoSection {
+		return demoAdd("Restaurants", demoRemove(list, "Seitan Shop"))(list)
+	}},
+	{Message: "Add Lentil Lab", Author: "Dan", Edit: demoAdd("Products", demoEntry("Lentil Lab", "Lentil pasta."))},
+	{Message: "Add Kale Cafe", Author: "Erin", Parents: []int{10}, Edit: demoAdd("Restaurants", demoEntry("Kale Cafe", "Green smoothies and bowls."))},
+	{Message: "Add Nut Butter Co", Author: "Erin", Edit: demoAdd("Products", demoEntry("Nut Butter Co", "Spreads without palm oil."))},
+	{Message: "Merge branch 'more-entries'", Author: "Alice", Parents: []int{11, 13}, Edit: func(list []demoSection) []demoSection {
+		list = demoAdd("Restaurants", demoEntry("Kale Cafe", "Green smoothies and bowls."))(list)
+		return demoAdd("Products", demoEntry("Nut Butter Co", "Spreads without palm oil."))(list)
+	}},
+	{Message: "Add Vegan Voice", Author: "Dan", Edit: demoAdd("Blogs", demoEntry("Vegan Voice", "Interviews with activists."))},
+	{Message: "Plant Post moved", Author: "Dan", Edit: demoReplace("Plant Post", "- [Plant Post](https://news.plant-post.example/) - Weekly plant based news.")},
+	{Message: "Add Soy Stories", Author: "Carol", Edit: demoAdd("Blogs", demoEntry("Soy Stories", "A cookbook in weekly posts. #books"))},
+	{Message: "Remove Lentil Lab, out of business", Author: "Bob", Edit: func(list []demoSection) []demoSection {
+		demoRemove(list, "Lentil Lab")
+		return list
+	}},
+	{Message: "Add Chickpea Corner", Author: "Erin", Edit: demoAdd("Restaurants", demoEntry("Chickpea Corner", "Falafel and hummus."))},
+	{Message: "Move the list to docs", Author: "Alice", Path: "docs/README.md", Edit: demoAdd("Blogs", demoEntry("Sprout Notes", "Growing your own greens."))},
+	{Message: "Move the list back", Author: "Alice", Edit: demoAdd("Products", demoEntry("Miso More", "Miso pastes from small farms."))},
+}
+
+// demoSnapshots plays the demo steps, every commit a day after the previous one
+func demoSnapshots() []feedgentest.Snapshot {
+	var lists [][]demoSection
+	var snapshots []feedgentest.Snapshot
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for n, s := range demoSteps {
+		base := n - 1
+		if len(s.Parents) > 0 {
+			base = s.Parents[0]
+		}
+
+		// every step works on a copy of the list it is based on
+		var list []demoSection
+		if base >= 0 {
+			for _, sec := range lists[base] {
+				list = append(list, demoSection{Name: sec.Name, Entries: append([]string{}, sec.Entries...)})
+			}
+		}
+		list = s.Edit(list)
+		lists = append(lists, list)
+
+		var b strings.Builder
+		b.WriteString("# Awesome Demo\n\nA list made up by the demo command.\n")
+		for _, sec := range list {
+			fmt.Fprintf(&b, "\n## %s\n\n", sec.Name)
+			for _, l := range sec.Entries {
+				b.WriteString(l + "\n")
+			}
+		}
+
+		path := s.Path
+		if path == "" {
+			path = feedgen.NewConfig().Workfile
+		}
+		snapshots = append(snapshots, feedgentest.Snapshot{
+			Files:   map[string][]byte{path: feedgentest.File(b.String())},
+			Author:  s.Author,
+			Email:   strings.ToLower(s.Author) + "@users.noreply.github.com",
+			When:    start.AddDate(0, 0, n),
+			Message: s.Message,
+			Parents: s.Parents,
+		})
+	}
+
+	return snapshots
+}
+
+// runDemo generates all outputs from a made up list in an in-memory repository, taking the
+// same flags as a normal run; without -destdir they go to a new temporary directory
+func runDemo(args []string) {
+	var cfg feedgen.Config
+
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	explicit := parseFlags(fs, &cfg, args)
+
+	if cfg.FollowSubmodule {
+		log.Fatal("-follow-submodule is not supported by demo")
+	}
+	if !explicit["destdir"] {
+		dir, err := os.MkdirTemp("", "avfeed-demo-")
+		if err != nil {
+			log.Fatalf("failed to create destination directory: %v", err)
+		}
+		cfg.Destdir = dir
+	}
+
+	r, err := feedgentest.NewRepository(demoSnapshots()...)
+	if err != nil {
+		log.Fatalf("failed to build demo repository: %v", err)
+	}
+
+	cfg.Repository = r
+	if _, err := feedgen.GenerateFeed(context.Background(), cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("generated from %d commits of a made up list into %s:\n", len(demoSteps), cfg.Destdir)
+	if cfg.Sink.Output != "fs" {
+		return
+	}
+	err = filepath.WalkDir(cfg.Destdir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(cfg.Destdir, p)
+		fmt.Printf("  %s\n", rel)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("failed to list outputs: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "write the generated files as the new golden files")
+
+// TestDemo runs the demo command as a user would, with a few features turned on, and pins
+// what it generates
+func TestDemo(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{
+		"-destdir", dir, "-section-categories", "-category-move-items", "-tag-feeds",
+		"-registry", "registry.json", "-removed-page", "removed.html", "-manifest",
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	printed := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		printed <- data
+	}()
+	runDemo(args)
+	os.Stdout = stdout
+	w.Close()
+	out := string(<-printed)
+
+	files := feedgentest.ReadTree(t, dir)
+	feedgentest.CheckGolden(t, filepath.Join("testdata", "golden", "demo"), files, *updateGolden)
+
+	// the listing names every file generated
+	var names []string
+	for name := range files {
+		names = append(names, "  "+filepath.FromSlash(name))
+	}
+	sort.Strings(names)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if want := fmt.Sprintf("generated from %d commits of a made up list into %s:", len(demoSteps), dir); lines[0] != want {
+		t.Errorf("got %q, want %q", lines[0], want)
+	}
+	listed := append([]string{}, lines[1:]...)
+	sort.Strings(listed)
+	if strings.Join(listed, "\n") != strings.Join(names, "\n") {
+		t.Errorf("listed\n%s\nwant\n%s", strings.Join(listed, "\n"), strings.Join(names, "\n"))
+	}
+
+	// every kind of change the demo history has shows up
+	feed := files["feed.xml"]
+	for _, title := range []string{
+		"Addition of Tofu Town", "Update of Oat Dream", "Removal of Bean Bistro",
+		"Addition of Kale Cafe", "Moved Seitan Shop from Products to Restaurants", "README.md moved to docs/README.md",
+		"Addition of Sprout Notes", "Addition of Miso More",
+	} {
+		if !bytes.Contains(feed, []byte("<title>"+title)) {
+			t.Errorf("%s missing", title)
+		}
+	}
+}
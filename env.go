@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefix for environment variables overriding flags
+const envPrefix = "AVFEED_"
+
+// envName maps a flag name to its environment variable, e.g. feed-title to AVFEED_FEED_TITLE
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// applyEnv sets every flag not given on the command line from its environment
+// variable, using the flag's own parser so values are interpreted identically
+func applyEnv(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+
+		name := envName(f.Name)
+		v, found := os.LookupEnv(name)
+		if !found {
+			return
+		}
+
+		if e := fs.Set(f.Name, v); e != nil {
+			err = fmt.Errorf("invalid value %q for environment variable %s: %v", v, name, e)
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"awesome-veganism-feed/feedgen"
+)
+
+// runEventlog checks event logs, the only command being verify
+func runEventlog(args []string) {
+	fs := flag.NewFlagSet("eventlog verify", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s: %s <file>\n", fs.Name(), fs.Name())
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "verify" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	fs.Parse(args[1:])
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("failed to read event log: %v", err)
+	}
+	n, err := feedgen.VerifyEventLog(path)
+	if err != nil {
+		log.Fatalf("invalid event log: %s: %v", path, err)
+	}
+
+	fmt.Printf("%s: %d events, sequence and hash chain intact\n", path, n)
+}
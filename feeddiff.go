@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"awesome-veganism-feed/feedgen"
+)
+
+// feedEntry is an item of a published feed in any of the three formats
+type feedEntry struct {
+	ID      string
+	Fields  map[string]string
+	ordered []string
+}
+
+// key identifies an item across two versions of a feed: its id, or link and title without one
+func (e *feedEntry) key() string {
+	if e.ID != "" {
+		return "id " + e.ID
+	}
+
+	return "link " + e.Fields["link"] + " title " + e.Fields["title"]
+}
+
+func (e *feedEntry) set(name string, value string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string]string)
+	}
+	if _, found := e.Fields[name]; !found {
+		e.ordered = append(e.ordered, name)
+	}
+	e.Fields[name] = strings.TrimSpace(value)
+}
+
+type atomDoc struct {
+	Entries []struct {
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Summary    string `xml:"summary"`
+		Content    string `xml:"content"`
+		Author     string `xml:"author>name"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+type rssDoc struct {
+	Items []struct {
+		GUID        string   `xml:"guid"`
+		Title       string   `xml:"title"`
+		Link        string   `xml:"link"`
+		Description string   `xml:"description"`
+		Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		PubDate     string   `xml:"pubDate"`
+		Categories  []string `xml:"category"`
+	} `xml:"channel>item"`
+}
+
+type jsonFeedDoc struct {
+	Items []struct {
+		ID            string   `json:"id"`
+		Title         string   `json:"title"`
+		URL           string   `json:"url"`
+		Summary       string   `json:"summary"`
+		ContentHTML   string   `json:"content_html"`
+		DatePublished string   `json:"date_published"`
+		DateModified  string   `json:"date_modified"`
+		Tags          []string `json:"tags"`
+		Author        *struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"items"`
+}
+
+// parseFeedEntries reads the items of an atom, rss or json feed, the format is told by the content
+func parseFeedEntries(data []byte) ([]*feedEntry, error) {
+	var entries []*feedEntry
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var doc jsonFeedDoc
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		for _, i := range doc.Items {
+			e := &feedEntry{ID: i.ID}
+			e.set("title", i.Title)
+			e.set("link", i.URL)
+			e.set("date", i.DatePublished)
+			e.set("updated", i.DateModified)
+			e.set("summary", i.Summary)
+			e.set("content", i.ContentHTML)
+			if i.Author != nil {
+				e.set("author", i.Author.Name)
+			}
+			e.set("categories", strings.Join(i.Tags, ", "))
+			entries = append(entries, e)
+		}
+
+		return entries, nil
+	}
+
+	// the root element tells atom and rss apart
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	switch root.XMLName.Local {
+	case "feed":
+		var doc atomDoc
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		for _, a := range doc.Entries {
+			e := &feedEntry{ID: a.ID}
+			e.set("title", a.Title)
+			for _, l := range a.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					e.set("link", l.Href)
+				}
+			}
+			e.set("updated", a.Updated)
+			e.set("summary", a.Summary)
+			e.set("content", a.Content)
+			e.set("author", a.Author)
+			var terms []string
+			for _, c := range a.Categories {
+				terms = append(terms, c.Term)
+			}
+			e.set("categories", strings.Join(terms, ", "))
+			entries = append(entries, e)
+		}
+	case "rss":
+		var doc rssDoc
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		for _, i := range doc.Items {
+			e := &feedEntry{ID: i.GUID}
+			e.set("title", i.Title)
+			e.set("link", i.Link)
+			e.set("date", i.PubDate)
+			e.set("summary", i.Description)
+			e.set("content", i.Content)
+			e.set("author", i.Creator)
+			e.set("categories", strings.Join(i.Categories, ", "))
+			entries = append(entries, e)
+		}
+	default:
+		return nil, fmt.Errorf("unknown feed format with root element %s", root.XMLName.Local)
+	}
+
+	return entries, nil
+}
+
+// runDiffFeeds compares the items of two versions of a feed; like diff it exits with 0
+// without differences, 1 with differences and 2 on trouble
+func runDiffFeeds(args []string) {
+	var userAgent, contactEmail string
+
+	fs := flag.NewFlagSet("diff-feeds", flag.ExitOnError)
+	fs.StringVar(&userAgent, "user-agent", feedgen.DefaultUserAgent(), "user agent for all outbound http requests")
+	fs.StringVar(&contactEmail, "contact-email", "", "contact address sent as from header with all outbound http requests")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s: %s [flags] <old file or url> <new file or url>\n", fs.Name(), fs.Name())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	client := feedgen.NewHTTPClient(userAgent, contactEmail)
+	var versions [2][]*feedEntry
+	for n, src := range fs.Args() {
+		data, err := readSource(src, client)
+		if err != nil {
+			log.Printf("failed to read feed: %s: %v", src, err)
+			os.Exit(2)
+		}
+		if versions[n], err = parseFeedEntries(data); err != nil {
+			log.Printf("failed to parse feed: %s: %v", src, err)
+			os.Exit(2)
+		}
+	}
+
+	if diffFeedEntries(versions[0], versions[1]) {
+		os.Exit(1)
+	}
+}
+
+// diffFeedEntries prints added, removed and modified items in the order of the new feed,
+// removed ones last, and reports whether there were any
+func diffFeedEntries(old []*feedEntry, new []*feedEntry) bool {
+	byKey := make(map[string]*feedEntry)
+	for _, e := range old {
+		byKey[e.key()] = e
+	}
+
+	found := false
+	seen := make(map[string]bool)
+	for _, e := range new {
+		k := e.key()
+		seen[k] = true
+
+		o := byKey[k]
+		if o == nil {
+			fmt.Printf("+ %s (%s)\n", e.Fields["title"], k)
+			found = true
+			continue
+		}
+
+		var changed []string
+		for _, name := range e.ordered {
+			if o.Fields[name] != e.Fields[name] {
+				changed = append(changed, name)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		fmt.Printf("~ %s (%s)\n", e.Fields["title"], k)
+		for _, name := range changed {
+			fmt.Printf("    %s: %q -> %q\n", name, o.Fields[name], e.Fields[name])
+		}
+		found = true
+	}
+
+	for _, e := range old {
+		if k := e.key(); !seen[k] {
+			fmt.Printf("- %s (%s)\n", e.Fields["title"], k)
+			found = true
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,102 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// annotation is a warning about a line of a list file, reported at that line in the head commit
+type annotation struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Class   string `json:"class"`
+	Message string `json:"message"`
+
+	// the line as changed, looked up in the head commit
+	text string
+}
+
+// printAnnotations reports the warnings about lines still present in the head commit at their
+// line there, as github workflow commands or as a json array; warnings about lines gone since
+// are left out, the same line is only reported once per class
+func printAnnotations(w io.Writer, format string, annotations []*annotation, head *object.Commit) error {
+	lines := make(map[string]map[string]int)
+	seen := make(map[string]bool)
+
+	list := []*annotation{}
+	for _, a := range annotations {
+		if lines[a.File] == nil {
+			numbers, err := lineNumbers(head, a.File)
+			if err != nil {
+				return err
+			}
+			lines[a.File] = numbers
+		}
+
+		n := lines[a.File][a.text]
+		key := fmt.Sprintf("%s:%d:%s", a.File, n, a.Class)
+		if n == 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		a.Line = n
+		list = append(list, a)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(list)
+	}
+
+	for _, a := range list {
+		if _, err := fmt.Fprintf(w, "::warning file=%s,line=%d,title=%s::%s\n", escapeProperty(a.File), a.Line, escapeProperty(a.Class), escapeData(a.Message)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lineNumbers maps every line of a file to the number of its first occurrence, counting from 1;
+// a file missing from the commit has no lines
+func lineNumbers(c *object.Commit, file string) (map[string]int, error) {
+	numbers := make(map[string]int)
+
+	f, err := c.File(file)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return numbers, nil
+	} else if err != nil {
+		return nil, err
+	}
+	contents, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	// split the way lines of patches are, carriage returns stay part of the line
+	for n, line := range strings.Split(contents, "\n") {
+		if _, found := numbers[line]; !found {
+			numbers[line] = n + 1
+		}
+	}
+
+	return numbers, nil
+}
+
+// escapeData escapes the message of a workflow command
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,71 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// badge is a shields.io endpoint description
+type badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// newThisMonth counts the additions in the feed dated within the calendar month of now, in the location of now
+func newThisMonth(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, now time.Time) int {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 1, 0)
+
+	n := 0
+	for _, item := range feed.Items {
+		if m := meta[item]; m == nil || m.Kind != "Addition" {
+			continue
+		}
+
+		t := item.Created.In(now.Location())
+		if !t.Before(start) && t.Before(end) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func newBadge(count int) *badge {
+	b := &badge{SchemaVersion: 1, Label: "new this month", Message: strconv.Itoa(count), Color: "green"}
+	if count == 0 {
+		b.Color = "lightgrey"
+	}
+
+	return b
+}
+
+func (b *badge) marshal() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+// svg renders the badge in the flat style, text widths are estimated at 7 pixels per character
+func (b *badge) svg() []byte {
+	colors := map[string]string{"green": "#97ca00", "lightgrey": "#9f9f9f"}
+
+	lw := 7*len(b.Label) + 10
+	mw := 7*len(b.Message) + 10
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">
+  <title>%s: %s</title>
+  <rect width="%d" height="20" fill="#555"/>
+  <rect x="%d" width="%d" height="20" fill="%s"/>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%d" y="14">%s</text>
+    <text x="%d" y="14">%s</text>
+  </g>
+</svg>
+`, lw+mw, xmlEscape(b.Label), xmlEscape(b.Message), xmlEscape(b.Label), xmlEscape(b.Message),
+		lw, lw, mw, colors[b.Color], lw/2, xmlEscape(b.Label), lw+mw/2, xmlEscape(b.Message)))
+}
@@ -0,0 +1,47 @@
+package feedgen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestNewThisMonthAtTheMonthBoundary(t *testing.T) {
+	o := parseArgs(t, []string{"-destdir", t.TempDir(), "-timezone", "Europe/Berlin"})
+	loc := o.location
+
+	// a minute apart in berlin, both on the 31st of march in utc
+	late := time.Date(2024, 3, 31, 23, 59, 0, 0, loc)
+	early := time.Date(2024, 4, 1, 0, 0, 0, 0, loc)
+	if late.UTC().Day() != 31 || early.UTC().Day() != 31 {
+		t.Fatalf("both commits should be on the 31st in utc: %s, %s", late.UTC(), early.UTC())
+	}
+
+	feed := &feeds.Feed{}
+	meta := make(map[*feeds.Item]*ItemMeta)
+	for _, created := range []time.Time{late.UTC(), early.UTC()} {
+		item := &feeds.Item{Title: "Addition of Tofu Town", Created: created}
+		feed.Items = append(feed.Items, item)
+		meta[item] = &ItemMeta{Kind: "Addition"}
+	}
+
+	tests := []struct {
+		now  time.Time
+		want int
+	}{
+		{time.Date(2024, 3, 15, 12, 0, 0, 0, loc), 1},
+		{time.Date(2024, 3, 31, 23, 59, 30, 0, loc), 1},
+		{time.Date(2024, 4, 1, 0, 0, 0, 0, loc), 1},
+		{time.Date(2024, 4, 30, 23, 59, 0, 0, loc), 1},
+		{time.Date(2024, 5, 1, 0, 0, 0, 0, loc), 0},
+		// counted in utc both fall into march
+		{time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC), 2},
+		{time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC), 0},
+	}
+	for _, tt := range tests {
+		if got := newThisMonth(feed, meta, tt.now); got != tt.want {
+			t.Errorf("at %s: got %d, want %d", tt.now, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package feedgen
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// checkDefaultBranch compares the checked out HEAD against the default branch of the remote
+// as last fetched, returning a description of the problem or an empty string when there is none
+// or the repository has no remote to compare with
+func checkDefaultBranch(r *git.Repository, head *plumbing.Reference) (string, error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return "", err
+	}
+	if len(remotes) == 0 {
+		return "", nil
+	}
+
+	// prefer origin, otherwise the first remote by name
+	var names []string
+	for _, rm := range remotes {
+		names = append(names, rm.Config().Name)
+	}
+	sort.Strings(names)
+	remote := names[0]
+	for _, n := range names {
+		if n == "origin" {
+			remote = n
+		}
+	}
+
+	sym, err := r.Reference(plumbing.NewRemoteHEADReferenceName(remote), false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	def, err := r.Reference(plumbing.NewRemoteHEADReferenceName(remote), true)
+	if err != nil {
+		return "", err
+	}
+
+	branch := sym.Target().Short()
+	if sym.Type() != plumbing.SymbolicReference {
+		branch = remote + "/HEAD"
+	}
+
+	// ci systems commonly check out the default branch head detached
+	if !head.Name().IsBranch() {
+		if def.Hash() == head.Hash() {
+			return "", nil
+		}
+		return fmt.Sprintf("HEAD is detached at %s instead of on the default branch %s", head.Hash(), branch), nil
+	}
+
+	if remote+"/"+head.Name().Short() != branch {
+		return fmt.Sprintf("HEAD is on %s instead of the default branch %s", head.Name().Short(), branch), nil
+	}
+
+	if def.Hash() == head.Hash() {
+		return "", nil
+	}
+
+	hc, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return "", err
+	}
+	dc, err := r.CommitObject(def.Hash())
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	behind, err := hc.IsAncestor(dc)
+	if err != nil {
+		return "", err
+	}
+	if behind {
+		return fmt.Sprintf("HEAD on %s is behind the default branch %s", head.Name().Short(), branch), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,137 @@
+package feedgen
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// cache control of every class of artifacts: feeds are polled and change with every new
+// commit, pages and data files change as often but are fetched less, private files like the
+// state are never meant to be served
+var defaultCachePolicy = map[string]string{
+	"feed":    "public, max-age=300, must-revalidate",
+	"page":    "public, max-age=3600, must-revalidate",
+	"data":    "public, max-age=3600, must-revalidate",
+	"private": "no-store",
+}
+
+// cachePolicy decides the Cache-Control header an artifact is to be served with
+type cachePolicy struct {
+	classes map[string]string
+	// name=value overrides for artifacts whose name matches a glob, the first match wins
+	files   [][2]string
+	private map[string]bool
+}
+
+// newCachePolicy sets up the default policy with class=value or glob=value overrides, the
+// given private files are classified as such
+func newCachePolicy(overrides []string, private []string) (*cachePolicy, error) {
+	p := &cachePolicy{classes: make(map[string]string), private: make(map[string]bool)}
+	for class, value := range defaultCachePolicy {
+		p.classes[class] = value
+	}
+	for _, name := range private {
+		if name != "" {
+			p.private[name] = true
+		}
+	}
+
+	for _, s := range overrides {
+		key, value, found := strings.Cut(s, "=")
+		value = strings.TrimSpace(value)
+		if !found || key == "" || value == "" || strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("invalid cache policy, expected class=value or glob=value: %s", s)
+		}
+		if _, found := p.classes[key]; found {
+			p.classes[key] = value
+			continue
+		}
+		if _, err := path.Match(key, ""); err != nil {
+			return nil, fmt.Errorf("invalid cache policy glob: %s: %v", key, err)
+		}
+		p.files = append(p.files, [2]string{key, value})
+	}
+
+	return p, nil
+}
+
+// class tells feeds, html pages and private files apart from all other data
+func (p *cachePolicy) class(name string, contentType string) string {
+	if p.private[name] {
+		return "private"
+	}
+	if _, found := documentFormats[contentType]; found {
+		return "feed"
+	}
+	if contentType == "text/html" {
+		return "page"
+	}
+
+	return "data"
+}
+
+// cacheControl is the Cache-Control header value of an artifact
+func (p *cachePolicy) cacheControl(name string, contentType string) string {
+	for _, f := range p.files {
+		if ok, _ := path.Match(f[0], name); ok {
+			return f[1]
+		}
+	}
+
+	return p.classes[p.class(name, contentType)]
+}
+
+// headerFiles are the names of the snippets for web servers and hosts setting headers per file
+var headerFiles = map[string]string{"netlify": "_headers", "apache": ".htaccess"}
+
+// headersSink records the cache control of every artifact passing through and writes a
+// snippet setting it for a web server or host before finalizing
+type headersSink struct {
+	OutputSink
+	format  string
+	policy  *cachePolicy
+	headers map[string]string
+	// artifacts written after the snippet, like the manifest
+	later map[string]string
+}
+
+func newHeadersSink(sink OutputSink, format string, policy *cachePolicy, later map[string]string) (*headersSink, error) {
+	if headerFiles[format] == "" {
+		return nil, fmt.Errorf("unknown headers file format: %s", format)
+	}
+
+	return &headersSink{OutputSink: sink, format: format, policy: policy, headers: make(map[string]string), later: later}, nil
+}
+
+func (s *headersSink) Write(name string, contentType string, data []byte) error {
+	if err := s.OutputSink.Write(name, contentType, data); err != nil {
+		return err
+	}
+	s.headers[name] = s.policy.cacheControl(name, contentType)
+
+	return nil
+}
+
+func (s *headersSink) Finalize() error {
+	for name, contentType := range s.later {
+		s.headers[name] = s.policy.cacheControl(name, contentType)
+	}
+
+	var b strings.Builder
+	for _, name := range sortedKeys(s.headers) {
+		switch s.format {
+		case "netlify":
+			fmt.Fprintf(&b, "/%s\n  Cache-Control: %s\n", name, s.headers[name])
+		case "apache":
+			// files sections match base names, which are unique among the generated files
+			fmt.Fprintf(&b, "<Files %q>\n  Header set Cache-Control %q\n</Files>\n", path.Base(name), s.headers[name])
+		}
+	}
+
+	if err := s.OutputSink.Write(headerFiles[s.format], "text/plain", []byte(b.String())); err != nil {
+		return err
+	}
+
+	return s.OutputSink.Finalize()
+}
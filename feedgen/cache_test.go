@@ -0,0 +1,139 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCacheControlPerClass(t *testing.T) {
+	p, err := newCachePolicy([]string{
+		"page=public, max-age=86400, immutable",
+		"archive/*.xml=public, max-age=31536000, immutable",
+		"registry.json=no-cache",
+	}, []string{"warnings.xml", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		class       string
+		want        string
+	}{
+		{"feed.xml", "application/atom+xml", "feed", "public, max-age=300, must-revalidate"},
+		{"feed.rss", "application/rss+xml", "feed", "public, max-age=300, must-revalidate"},
+		{"feed.json", "application/feed+json", "feed", "public, max-age=300, must-revalidate"},
+		{"tags/tag-tofu.xml", "application/atom+xml", "feed", "public, max-age=300, must-revalidate"},
+		{"archive/2024.xml", "application/atom+xml", "feed", "public, max-age=31536000, immutable"},
+		{"removed.html", "text/html", "page", "public, max-age=86400, immutable"},
+		{"timeseries.json", "application/json", "data", "public, max-age=3600, must-revalidate"},
+		{"badge.svg", "image/svg+xml", "data", "public, max-age=3600, must-revalidate"},
+		{"registry.json", "application/json", "data", "no-cache"},
+		{"warnings.xml", "application/atom+xml", "private", "no-store"},
+	}
+
+	for _, tt := range tests {
+		if got := p.class(tt.name, tt.contentType); got != tt.class {
+			t.Errorf("%s: class %q, want %q", tt.name, got, tt.class)
+		}
+		if got := p.cacheControl(tt.name, tt.contentType); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// the defaults are left alone by overrides
+	if got := defaultCachePolicy["page"]; got != "public, max-age=3600, must-revalidate" {
+		t.Errorf("default page policy changed to %q", got)
+	}
+}
+
+func TestCachePolicyRejectsInvalidOverrides(t *testing.T) {
+	for _, s := range []string{"feed", "=no-store", "feed=", "feed=  ", "[.xml=no-store", "feed=no-store\r\nX-Injected: 1"} {
+		if _, err := newCachePolicy([]string{s}, nil); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
+
+func TestHeaderSnippetsAndManifest(t *testing.T) {
+	args := []string{
+		"-removed-page", "removed.html", "-registry", "registry.json", "-maintainer-feed", "warnings.xml",
+		"-cache-policy", "page=public, max-age=86400, immutable", "-cache-policy", "registry.json=no-cache",
+	}
+
+	files := generate(t, append([]string{"-manifest", "-headers-file", "netlify"}, args...), goldenHistory()...)
+	want := `/feed.json
+  Cache-Control: public, max-age=300, must-revalidate
+/feed.rss
+  Cache-Control: public, max-age=300, must-revalidate
+/feed.xml
+  Cache-Control: public, max-age=300, must-revalidate
+/manifest.json
+  Cache-Control: public, max-age=3600, must-revalidate
+/registry.json
+  Cache-Control: no-cache
+/removed.html
+  Cache-Control: public, max-age=86400, immutable
+/warnings.xml
+  Cache-Control: no-store
+`
+	if got := string(files["_headers"]); got != want {
+		t.Errorf("netlify:\n%s\nwant:\n%s", got, want)
+	}
+
+	var manifest struct {
+		Files []struct {
+			Name         string `json:"name"`
+			CacheControl string `json:"cache_control"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, f := range manifest.Files {
+		got[f.Name] = f.CacheControl
+	}
+	wantManifest := map[string]string{
+		"feed.xml":      "public, max-age=300, must-revalidate",
+		"feed.json":     "public, max-age=300, must-revalidate",
+		"feed.rss":      "public, max-age=300, must-revalidate",
+		"warnings.xml":  "no-store",
+		"registry.json": "no-cache",
+		"removed.html":  "public, max-age=86400, immutable",
+		"_headers":      "public, max-age=3600, must-revalidate",
+	}
+	if len(got) != len(wantManifest) {
+		t.Errorf("manifest lists %v", got)
+	}
+	for name, value := range wantManifest {
+		if got[name] != value {
+			t.Errorf("manifest: %s: got %q, want %q", name, got[name], value)
+		}
+	}
+
+	files = generate(t, append([]string{"-headers-file", "apache"}, args...), goldenHistory()...)
+	want = `<Files "feed.json">
+  Header set Cache-Control "public, max-age=300, must-revalidate"
+</Files>
+<Files "feed.rss">
+  Header set Cache-Control "public, max-age=300, must-revalidate"
+</Files>
+<Files "feed.xml">
+  Header set Cache-Control "public, max-age=300, must-revalidate"
+</Files>
+<Files "registry.json">
+  Header set Cache-Control "no-cache"
+</Files>
+<Files "removed.html">
+  Header set Cache-Control "public, max-age=86400, immutable"
+</Files>
+<Files "warnings.xml">
+  Header set Cache-Control "no-store"
+</Files>
+`
+	if got := string(files[".htaccess"]); got != want {
+		t.Errorf("apache:\n%s\nwant:\n%s", got, want)
+	}
+}
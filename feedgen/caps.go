@@ -0,0 +1,97 @@
+package feedgen
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// categoryConfig holds the limits of one section of the list
+type categoryConfig struct {
+	MaxPerWeek int `yaml:"max-per-week"`
+}
+
+// capCategories returns a copy of the feed where items of a capped section beyond its
+// weekly maximum are rolled up into one digest item per section and week, placed where
+// the last of them was; sections are matched by their slug
+func capCategories(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, caps map[string]categoryConfig, authority string) *feeds.Feed {
+	limits := make(map[string]int)
+	for name, c := range caps {
+		if c.MaxPerWeek > 0 {
+			limits[slug(name)] = c.MaxPerWeek
+		}
+	}
+
+	capped := *feed
+	capped.Items = nil
+
+	type window struct {
+		category string
+		year     int
+		week     int
+	}
+	seen := make(map[window]int)
+	excess := make(map[window][]*feeds.Item)
+	last := make(map[*feeds.Item]window)
+
+	for _, item := range feed.Items {
+		m := meta[item]
+		if m == nil || m.Section == "" || limits[slug(m.Section)] == 0 {
+			continue
+		}
+
+		year, week := item.Created.ISOWeek()
+		w := window{slug(m.Section), year, week}
+		seen[w]++
+		if seen[w] > limits[w.category] {
+			excess[w] = append(excess[w], item)
+		}
+	}
+	for w, items := range excess {
+		last[items[len(items)-1]] = w
+	}
+
+	rolled := make(map[*feeds.Item]bool)
+	for _, items := range excess {
+		for _, item := range items {
+			rolled[item] = true
+		}
+	}
+
+	for _, item := range feed.Items {
+		if !rolled[item] {
+			capped.Items = append(capped.Items, item)
+			continue
+		}
+
+		w, found := last[item]
+		if !found {
+			continue
+		}
+
+		items := excess[w]
+		section := meta[items[0]].Section
+
+		var b strings.Builder
+		b.WriteString("<ul>\n")
+		for _, x := range items {
+			fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a>: %s</li>\n", html.EscapeString(x.Link.Href), html.EscapeString(x.Title), html.EscapeString(x.Description))
+		}
+		b.WriteString("</ul>")
+
+		digest := &feeds.Item{
+			Id:          fmt.Sprintf("tag:%s,%d:digest/%s/%d-W%02d", authority, w.year, w.category, w.year, w.week),
+			Title:       fmt.Sprintf("%d more %s in %s", len(items), plural(len(items), "change", "changes"), section),
+			Link:        &feeds.Link{Href: feed.Link.Href},
+			Description: fmt.Sprintf("%d more %s in %s in week %d of %d.", len(items), plural(len(items), "change", "changes"), section, w.week, w.year),
+			Content:     b.String(),
+			Created:     item.Created,
+		}
+		meta[digest] = &ItemMeta{Kind: "Digest", Section: section}
+		capped.Items = append(capped.Items, digest)
+	}
+
+	return &capped
+}
@@ -0,0 +1,145 @@
+package feedgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// checkCompat rejects options that change the main feeds in ways the selected compatibility mode cannot represent,
+// or that rely on the item ids the first releases did not have, like the event log
+func checkCompat(o *options) error {
+	switch o.Compat {
+	case "":
+		return nil
+	case "v1":
+	default:
+		return fmt.Errorf("unknown compatibility mode: %s", o.Compat)
+	}
+
+	conflicts := map[string]bool{
+		"-context":            o.Context,
+		"-include-diff":       o.IncludeDiff,
+		"-tag-feeds":          o.TagFeeds,
+		"-suffix-pattern":     len(o.SuffixPatterns) > 0,
+		"-url-prefix":         o.URLPrefix != "",
+		"-group-by":           o.GroupBy != "entry",
+		"-section-categories": o.SectionCategories,
+		"-repo-url":           o.RepoURL != "",
+		"-positions":          o.Positions,
+		"-files":              o.Files != "",
+		"-squash-window":      o.SquashWindow > 0,
+		"-commit-body":        o.CommitBody,
+		"-prefer-commit-body": o.PreferCommitBody,
+		"-merges":             o.Merges != "combined",
+		"-github-meta":        o.GithubMeta,
+		"-atom-self-url":      o.AtomSelfURL != "",
+		"-json-feed-url":      o.JSONFeedURL != "",
+		"-rss-self-url":       o.RSSSelfURL != "",
+		"-meta-file":          len(o.MetaFiles) > 0,
+		"-eventlog":           o.EventLog != "",
+		"-tiered":             o.Tiered,
+	}
+	for _, name := range []string{"-context", "-include-diff", "-tag-feeds", "-suffix-pattern", "-url-prefix", "-group-by", "-section-categories", "-repo-url", "-positions", "-files", "-squash-window", "-commit-body", "-prefer-commit-body", "-merges", "-github-meta", "-atom-self-url", "-json-feed-url", "-rss-self-url", "-meta-file", "-eventlog", "-tiered"} {
+		if conflicts[name] {
+			return fmt.Errorf("%s is not supported with -compat %s", name, o.Compat)
+		}
+	}
+
+	return nil
+}
+
+// publishV1 writes the main feeds exactly the way the first releases did, leaving out
+// everything added since: icons, thumbnails, categories, the generator and the json extension
+func publishV1(o *options, feed *feeds.Feed, sink OutputSink, t *timings) error {
+	done := t.start("render")
+	atom, err := feed.ToAtom()
+	if err != nil {
+		return fmt.Errorf("failed to generate atom feed: %v", err)
+	}
+	done(1)
+
+	done = t.start("postprocess")
+	if o.Stylesheet != "" {
+		atom = injectAtomStylesheetV1(atom, o.Stylesheet)
+	}
+	atom = adjustAtomLinksV1(atom, o.AtomFile)
+	done(1)
+	if err := sink.Write(o.AtomFile, "application/atom+xml", []byte(atom)); err != nil {
+		return fmt.Errorf("failed to write atom feed: %v", err)
+	}
+
+	done = t.start("render")
+	json, err := feed.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to generate json feed: %v", err)
+	}
+	done(1)
+	if err := sink.Write(o.JSONFile, "application/feed+json", []byte(json)); err != nil {
+		return fmt.Errorf("failed to write json feed: %v", err)
+	}
+
+	done = t.start("render")
+	rss, err := feed.ToRss()
+	if err != nil {
+		return fmt.Errorf("failed to generate rss feed: %v", err)
+	}
+	done(1)
+
+	done = t.start("postprocess")
+	rss = adjustRssAuthorsV1(rss)
+	rss = addRssAtomLinkV1(rss, o.RSSFile)
+	done(1)
+	if err := sink.Write(o.RSSFile, "application/rss+xml", []byte(rss)); err != nil {
+		return fmt.Errorf("failed to write rss feed: %v", err)
+	}
+
+	return nil
+}
+
+// adjustAtomLinksV1 rewrites every link of the document, entry links included
+func adjustAtomLinksV1(atom string, file string) string {
+	re := regexp.MustCompile(`(?m)^(\s*<link href="[^"]+)"></link>`)
+
+	return re.ReplaceAllString(atom, `${1}`+file+`" rel="self"/>`+"\n"+`${1}" rel="alternate"/>`)
+}
+
+// addRssAtomLinkV1 appends the file name to the escaped channel link as is
+func addRssAtomLinkV1(rss string, file string) string {
+	// inject atom namespace
+	atomre := regexp.MustCompile(`(<rss [^>]+)>`)
+	rss = atomre.ReplaceAllString(rss, `$1 xmlns:atom="http://www.w3.org/2005/Atom">`)
+
+	re := regexp.MustCompile(`(?m)^(\s+)<link>([^<]+)</link>`)
+	subst := "$1<link>$2</link>\n" + `$1<atom:link href="${2}` + file + `" rel="self" type="application/rss+xml" />`
+
+	done := false
+	return re.ReplaceAllStringFunc(rss, func(a string) string {
+		if done {
+			return a
+		}
+		done = true
+
+		return re.ReplaceAllString(a, subst)
+	})
+}
+
+// injectAtomStylesheetV1 puts the stylesheet processing instruction after the xml declaration
+func injectAtomStylesheetV1(atom string, style string) string {
+	preamble := `<?xml version="1.0" encoding="UTF-8"?>`
+	stylesheet := fmt.Sprintf(`<?xml-stylesheet href="%s" type="text/xsl"?>`, xmlEscape(style))
+
+	return strings.Replace(atom, preamble, fmt.Sprintf("%s\n%s\n", preamble, stylesheet), 1)
+}
+
+// adjustRssAuthorsV1 turns all author elements into dublin core creators
+func adjustRssAuthorsV1(rss string) string {
+	dcre := regexp.MustCompile(`(<rss [^>]+)>`)
+	re := regexp.MustCompile(`<author>(.*?)</author>`)
+
+	rss = dcre.ReplaceAllString(rss, "\n"+`$1 xmlns:dc="http://purl.org/dc/elements/1.1/">`)
+
+	return re.ReplaceAllString(rss, `<dc:creator>$1</dc:creator>`)
+}
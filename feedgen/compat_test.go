@@ -0,0 +1,42 @@
+package feedgen
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestCheckCompatRefusesNewFeatures(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-compat", "v1"}, ""},
+		{[]string{"-compat", "v1", "-stylesheet", "feed.xsl"}, ""},
+		{[]string{"-compat", "v2"}, "unknown compatibility mode: v2"},
+		{[]string{"-compat", "v1", "-include-diff"}, "-include-diff is not supported with -compat v1"},
+		{[]string{"-compat", "v1", "-section-categories"}, "-section-categories is not supported with -compat v1"},
+		{[]string{"-compat", "v1", "-merges", "first-parent"}, "-merges is not supported with -compat v1"},
+		{[]string{"-compat", "v1", "-atom-self-url", "https://example.org/feed.xml"}, "-atom-self-url is not supported with -compat v1"},
+		{[]string{"-compat", "v1", "-meta-file", "meta.md"}, "-meta-file is not supported with -compat v1"},
+		{[]string{"-compat", "v1", "-eventlog", "events.jsonl"}, "-eventlog is not supported with -compat v1"},
+		{[]string{"-compat", "v1", "-tiered"}, "-tiered is not supported with -compat v1"},
+	}
+
+	for _, tt := range tests {
+		var o options
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		o.RegisterFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+
+		err := checkCompat(&o)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package feedgen
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Config holds all settings of a run, by the names of their command line flags; NewConfig
+// returns the defaults of the command line
+type Config struct {
+	// repository to walk instead of the one checked out in Workdir, it does not need a worktree;
+	// the work file is then taken as it is, without following submodules
+	Repository *git.Repository
+	// commit the history is walked back from instead of HEAD, never checked against the
+	// default branch
+	Head plumbing.Hash
+	// called after every processed commit, processing stops when it returns false
+	Step func(s *Step) bool
+
+	Destdir           string
+	Workdir           string
+	Stylesheet        string
+	Theme             string
+	ThemeDir          string
+	Verbose           bool
+	NoAvatars         bool
+	StaleAfter        time.Duration
+	StaleFile         string
+	Context           bool
+	Sink              SinkOptions
+	TimeseriesFile    string
+	EventLog          string
+	RegistryFile      string
+	RemovedPage       string
+	RemovedJSON       string
+	BadgeFile         string
+	MinimalFile       string
+	LegacyFile        string
+	MinimalTitle      string
+	BadgeSVGFile      string
+	Timezone          string
+	MaintainerFile    string
+	IncludeDiff       bool
+	DiffLimit         int
+	UserAgent         string
+	ContactEmail      string
+	MaxLineLength     int
+	MaxPatchSize      int
+	Strict            bool
+	PostprocessCmd    string
+	SquashWindow      time.Duration
+	Merges            string
+	Tiered            bool
+	FullWindow        Window
+	GithubMeta        bool
+	Annotations       string
+	CommitBody        bool
+	PreferCommitBody  bool
+	PostprocessTime   time.Duration
+	AllowedSchemes    string
+	InvalidURL        string
+	URLIdentity       string
+	ExcludeEntries    []string
+	AnnounceAuthors   []string
+	MuteAuthors       []string
+	CategoryMoves     bool
+	Restructure       string
+	GroupBy           string
+	PRURLTemplate     string
+	TagFeeds          bool
+	FilterFeeds       []string
+	FilterKeywords    []string
+	FilterRegexps     []string
+	SuffixPatterns    []string
+	MetaFiles         []string
+	CachePolicy       []string
+	URLPrefix         string
+	PathPrefix        string
+	FollowSubmodule   bool
+	Compat            string
+	RequireDefault    bool
+	GitExcludeOutputs bool
+	Title             string
+	Link              string
+	Description       string
+	Author            string
+	Workfile          string
+	Files             string
+	AtomFile          string
+	JSONFile          string
+	RSSFile           string
+	AtomSelfURL       string
+	JSONFeedURL       string
+	RSSSelfURL        string
+	StateFile         string
+	Dates             string
+	IDAuthority       string
+	SectionCategories bool
+	Sections          string
+	FutureDates       string
+	FutureSkew        time.Duration
+	RepoURL           string
+	Positions         bool
+	MaxItems          int
+	Since             string
+	Full              bool
+}
+
+// options is a configuration with the settings derived from it
+type options struct {
+	Config
+
+	// derived settings
+	schemes      []string
+	sinceAge     time.Duration
+	sinceDate    time.Time
+	location     *time.Location
+	client       *http.Client
+	cache        *cachePolicy
+	sections     bool
+	minimalTitle *template.Template
+	authorFilter *authorFilter
+	theme        *theme
+	fingerprint  string
+	// fingerprint of the flags a state has to be produced with to be continued
+	collectFingerprint string
+
+	// run over every rendered feed document in order before it is written
+	postProcessors []postProcessor
+	// files announced next to the list
+	metaFiles []*metaFile
+
+	// settings changed from their default
+	explicit map[string]bool
+	// categories of the feeds themselves
+	topics []string
+	// github usernames by lowercased commit email, from the repository configuration
+	authors map[string]string
+}
+
+// NewConfig returns a configuration with the defaults of the command line
+func NewConfig() Config {
+	var c Config
+	c.RegisterFlags(flag.NewFlagSet("defaults", flag.ContinueOnError))
+
+	return c
+}
+
+// RegisterFlags defines a flag for every setting, with its default
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Destdir, "destdir", ".", "destination directory for feed files")
+	fs.StringVar(&c.Workdir, "workdir", ".", "working directory with a git repository")
+	fs.StringVar(&c.Stylesheet, "stylesheet", "", "xslt stylesheet to inject into atom feed, generated into -destdir with -theme")
+	fs.StringVar(&c.Theme, "theme", "", "look of the removed entries page and the generated stylesheet: light, dark or plain")
+	fs.StringVar(&c.ThemeDir, "theme-dir", "", "directory overriding the theme with theme.css, removed.html and feed.xsl templates, its other files are copied into -destdir")
+	fs.BoolVar(&c.Verbose, "verbose", false, "turn on verbose mode")
+	fs.StringVar(&c.Dates, "dates", "absolute", "dates in verbose output: absolute, relative to the latest commit, or both")
+	fs.StringVar(&c.Title, "title", defaultTitle, "title of the feeds")
+	fs.StringVar(&c.Link, "link", defaultLink, "site url the feeds link to")
+	fs.StringVar(&c.Description, "description", defaultDescription, "description of the feeds")
+	fs.StringVar(&c.Author, "author", "", "author of the feeds, a name optionally followed by <email>")
+	fs.StringVar(&c.Workfile, "workfile", defaultWorkfile, "markdown file of the list, relative to -path-prefix")
+	fs.StringVar(&c.Files, "files", "", "comma separated markdown files or globs of a list spread over several files, relative to -path-prefix, instead of -workfile")
+	fs.StringVar(&c.IDAuthority, "id-authority", "", "domain item ids are minted under as tag uris, the host of -link by default")
+	fs.StringVar(&c.AtomFile, "atom-file", "feed.xml", "file name of the atom feed")
+	fs.StringVar(&c.JSONFile, "json-file", "feed.json", "file name of the json feed")
+	fs.StringVar(&c.RSSFile, "rss-file", "feed.rss", "file name of the rss feed")
+	fs.StringVar(&c.AtomSelfURL, "atom-self-url", "", "absolute url the atom feed advertises as its self link, the site url and -atom-file by default")
+	fs.StringVar(&c.JSONFeedURL, "json-feed-url", "", "absolute url the json feed advertises as its feed_url, none by default")
+	fs.StringVar(&c.RSSSelfURL, "rss-self-url", "", "absolute url the rss feed advertises as its self link, the site url and -rss-file by default")
+	fs.BoolVar(&c.NoAvatars, "no-avatars", false, "do not reference contributor avatars in feed items")
+	fs.Var((*ageValue)(&c.StaleAfter), "stale-after", "age after which an unchanged entry is a review candidate, e.g. 3y")
+	fs.StringVar(&c.StaleFile, "stale-feed", "", "atom feed file listing entries to review")
+	fs.BoolVar(&c.Context, "context", false, "describe the surrounding entries of added and removed entries")
+	fs.StringVar(&c.StateFile, "state", "", "local json file in -destdir keeping the processed history, so the next run only processes newer commits; it is never published")
+	fs.BoolVar(&c.Full, "full", false, "ignore the -state file and process the full history")
+	fs.StringVar(&c.TimeseriesFile, "timeseries", "", "json file with the number of entries at every commit")
+	fs.StringVar(&c.EventLog, "eventlog", "", "local json lines file every run appends the changes it found first to, before any output is written")
+	fs.StringVar(&c.MaintainerFile, "maintainer-feed", "", "atom feed file listing the warnings of the run, never part of the public feeds")
+	fs.StringVar(&c.MinimalFile, "minimal-feed", "", "atom feed file with titles and links only, for notification services")
+	fs.Var((*listValue)(&c.AnnounceAuthors), "announce-authors", "glob or /regex/ against commit emails of the authors whose items go into the minimal feed, all by default; repeatable")
+	fs.Var((*listValue)(&c.MuteAuthors), "mute-authors", "glob or /regex/ against commit emails of the authors whose items are left out of the minimal feed, the other feeds keep them; repeatable")
+	fs.StringVar(&c.LegacyFile, "compat-feed", "", "additional atom feed file with required elements only and ascii text, for old readers")
+	fs.StringVar(&c.MinimalTitle, "minimal-title-template", defaultMinimalTitle, "item title template of the minimal feed, with .Kind, .Name, .Title and .Section")
+	fs.StringVar(&c.BadgeFile, "badge", "", "shields.io endpoint json file with the number of additions this month")
+	fs.StringVar(&c.BadgeSVGFile, "badge-svg", "", "svg file rendering the additions this month badge")
+	fs.StringVar(&c.Timezone, "timezone", "Local", "time zone the calendar month of the badge is counted in, like Europe/Berlin")
+	fs.StringVar(&c.RegistryFile, "registry", "", "json file listing every entry with its stable id, names and urls")
+	fs.StringVar(&c.RemovedPage, "removed-page", "", "html file listing every entry ever removed from the list, latest first")
+	fs.StringVar(&c.RemovedJSON, "removed-json", "", "json file listing every entry ever removed from the list, latest first")
+	fs.BoolVar(&c.IncludeDiff, "include-diff", false, "include the changed lines of an entry in the item content")
+	fs.IntVar(&c.DiffLimit, "diff-limit", 1024, "maximum size in bytes of an included diff")
+	fs.StringVar(&c.UserAgent, "user-agent", DefaultUserAgent(), "user agent for all outbound http requests")
+	fs.StringVar(&c.ContactEmail, "contact-email", "", "contact address sent as from header with all outbound http requests")
+	fs.IntVar(&c.MaxLineLength, "max-line-length", 4096, "skip diff lines longer than this many bytes")
+	fs.IntVar(&c.MaxPatchSize, "max-patch-size", 32<<20, "skip commits changing more than this many bytes, 0 for no limit")
+	fs.BoolVar(&c.Strict, "strict", false, "abort on the first commit without a usable patch instead of skipping it")
+	fs.StringVar(&c.PostprocessCmd, "postprocess-cmd", "", "command every feed document is piped through before it is written, with the format in AVFEED_FORMAT")
+	fs.DurationVar(&c.PostprocessTime, "postprocess-timeout", 30*time.Second, "how long -postprocess-cmd may take per document")
+	fs.StringVar(&c.AllowedSchemes, "allowed-schemes", "http,https", "comma separated url schemes allowed for entries, e.g. add mailto")
+	fs.StringVar(&c.URLIdentity, "url-identity", "full", "url parts that tell entries apart when following renames: full, no-fragment or host-path")
+	fs.StringVar(&c.FutureDates, "future-dates", "clamp", "what to do with items dated after the newest commit: clamp, skip or keep")
+	fs.BoolVar(&c.CommitBody, "commit-body", false, "describe entries without a description by the first paragraph of the commit message body")
+	fs.BoolVar(&c.PreferCommitBody, "prefer-commit-body", false, "describe entries by the first paragraph of the commit message body whenever there is one")
+	fs.StringVar(&c.Annotations, "annotations", "", "print warnings about lines of the list at the end of the run, as github workflow commands or json: github or json")
+	fs.BoolVar(&c.GithubMeta, "github-meta", false, "default -description and -link to the description and homepage of the github repository of the origin remote and add its topics as feed categories, GITHUB_TOKEN is used when set")
+	fs.BoolVar(&c.Tiered, "tiered", false, "walk history below -full-window in monthly chunks of the first parent chain instead of commit by commit, for long histories whose older items only need to be roughly right")
+	c.FullWindow = Window{Commits: 1000}
+	fs.Var(&c.FullWindow, "full-window", "number of newest commits, or an age before the newest commit like 2y, the tiered mode processes commit by commit")
+	fs.StringVar(&c.Merges, "merges", "combined", "how merges are compared: combined with all parents, reporting only what the merge itself changed, or first-parent, following the first parent chain only and reporting the changes of merged branches at the merge")
+	fs.DurationVar(&c.SquashWindow, "squash-window", 0, "merge changes to an entry by the same author within this long after the first one into one item, e.g. 15m")
+	fs.DurationVar(&c.FutureSkew, "future-skew", 48*time.Hour, "how far items may be dated after the newest commit before -future-dates applies")
+	fs.StringVar(&c.InvalidURL, "invalid-url", "drop", "what to do with entries with other url schemes: drop or flag")
+	fs.IntVar(&c.MaxItems, "max-items", 0, "keep only this many of the newest items in the feeds, all by default")
+	fs.StringVar(&c.Since, "since", "", "leave out items older than a date like 2023-01-01 or an age before the newest commit like 90d")
+	fs.StringVar(&c.GroupBy, "group-by", "entry", "one item per changed entry, or per pull request with pr")
+	fs.StringVar(&c.RepoURL, "repo-url", "", "forge url of the list repository, items then link to their commit and mention the entry url in the content; a url with {hash} is used as template")
+	fs.StringVar(&c.PRURLTemplate, "pr-url-template", "", "pull request url with %d for the number, derived from the origin remote by default")
+	fs.BoolVar(&c.SectionCategories, "section-categories", false, "add the section of the changed entry as category to every item")
+	fs.StringVar(&c.Sections, "sections", "", "comma separated sections to restrict the feeds to, items of other sections are left out")
+	fs.BoolVar(&c.Positions, "positions", false, "record the place of added, updated and removed entries within their section in the json feed")
+	fs.BoolVar(&c.CategoryMoves, "category-move-items", false, "announce entries moved to another section as items")
+	fs.StringVar(&c.Restructure, "restructure", "item", "how a list file leaving or coming back is announced: as a single item, or suppress")
+	fs.Var((*listValue)(&c.ExcludeEntries), "exclude-entry", "glob or /regex/ against entry title or url to leave out of all feeds, repeatable")
+	fs.BoolVar(&c.GitExcludeOutputs, "git-exclude-outputs", false, "add generated files inside the repository to its .git/info/exclude")
+	fs.BoolVar(&c.RequireDefault, "require-default-branch", false, "fail instead of warn when HEAD is not the default branch of the remote")
+	fs.StringVar(&c.Compat, "compat", "", "reproduce the main feeds of an earlier release byte for byte: v1")
+	fs.BoolVar(&c.FollowSubmodule, "follow-submodule", false, "use the history of the submodule repository when the work file is inside one")
+	fs.StringVar(&c.PathPrefix, "path-prefix", "", "directory of the repository the work file is in, e.g. lists/")
+	fs.StringVar(&c.URLPrefix, "url-prefix", "", "path the generated files are published under, joined onto the site url for self links")
+	fs.Var((*listValue)(&c.MetaFiles), "meta-file", "path:pattern:template of another file whose added lines matching the pattern become meta items titled by the template, with the named groups and .line, repeatable")
+	fs.Var((*listValue)(&c.SuffixPatterns), "suffix-pattern", "name=regex matched against the end of descriptions, the first group is split off as a category, repeatable")
+	fs.Var((*listValue)(&c.FilterFeeds), "filter-feed", "name=path of an additional atom feed with the items matching the filter of that name, repeatable")
+	fs.Var((*listValue)(&c.FilterKeywords), "filter-keyword", "name=keyword matched case-insensitively against title, description and categories, repeatable")
+	fs.Var((*listValue)(&c.FilterRegexps), "filter-regexp", "name=regex matched case-insensitively like -filter-keyword, repeatable")
+	fs.BoolVar(&c.TagFeeds, "tag-feeds", false, "write an additional atom feed tag-<name>.xml per hashtag")
+	fs.StringVar(&c.Sink.Output, "output", "fs", "where to publish generated files: fs, s3, sftp, git or stdout")
+	fs.StringVar(&c.Sink.GitBranch, "git-branch", "gh-pages", "branch to commit generated files to with -output git")
+	fs.StringVar(&c.Sink.S3Bucket, "s3-bucket", "", "bucket to upload generated files to with -output s3")
+	fs.StringVar(&c.Sink.S3Prefix, "s3-prefix", "", "object key prefix for uploaded files")
+	fs.StringVar(&c.Sink.S3Region, "s3-region", "us-east-1", "region of the s3 bucket")
+	fs.StringVar(&c.Sink.S3Endpoint, "s3-endpoint", "", "endpoint of an s3 compatible service instead of aws")
+	fs.StringVar(&c.Sink.SFTPTarget, "sftp", "", "user@host:/dir or sftp://user@host:port/dir to upload generated files to with -output sftp")
+	fs.StringVar(&c.Sink.SFTPKey, "sftp-key", "", "private key file for -output sftp, the ssh agent is used as well when running")
+	fs.StringVar(&c.Sink.SFTPKnownHosts, "sftp-known-hosts", "", "known_hosts file verifying the sftp host key, ~/.ssh/known_hosts by default")
+	fs.BoolVar(&c.Sink.SFTPInsecure, "sftp-insecure", false, "skip verifying the sftp host key")
+	fs.BoolVar(&c.Sink.Manifest, "manifest", false, "write manifest.json listing size, checksum, change state and cache control of every file last")
+	fs.Var((*listValue)(&c.CachePolicy), "cache-policy", "class=value or glob=value overriding the cache control of the feed, page, data or private class or of matching files, repeatable")
+	fs.StringVar(&c.Sink.HeadersFile, "headers-file", "", "write a snippet setting the cache control of every file: netlify for _headers or apache for .htaccess")
+}
+
+// flagSet defines the flags of a copy of the configuration, so settings are told apart from
+// their defaults the same way however the configuration was filled in
+func (c Config) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	copied := new(Config)
+	copied.RegisterFlags(fs)
+	*copied = c
+
+	return fs
+}
+
+// newOptions checks a configuration and derives the remaining settings
+func newOptions(c Config) (*options, error) {
+	o := &options{Config: c, explicit: make(map[string]bool)}
+
+	fs := c.flagSet()
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Value.String() != f.DefValue {
+			o.explicit[f.Name] = true
+		}
+	})
+
+	// identify ourselves on every outbound request, including git remotes
+	o.client = NewHTTPClient(o.UserAgent, o.ContactEmail)
+	InstallGitTransport(o.client)
+
+	if o.InvalidURL != "drop" && o.InvalidURL != "flag" {
+		return nil, fmt.Errorf("invalid -invalid-url policy: %s", o.InvalidURL)
+	}
+	if o.GroupBy != "entry" && o.GroupBy != "pr" {
+		return nil, fmt.Errorf("invalid -group-by: %s", o.GroupBy)
+	}
+	if o.FutureDates != "clamp" && o.FutureDates != "skip" && o.FutureDates != "keep" {
+		return nil, fmt.Errorf("invalid -future-dates: %s", o.FutureDates)
+	}
+	if o.Dates != "absolute" && o.Dates != "relative" && o.Dates != "both" {
+		return nil, fmt.Errorf("invalid -dates: %s", o.Dates)
+	}
+	if o.URLIdentity != "full" && o.URLIdentity != "no-fragment" && o.URLIdentity != "host-path" {
+		return nil, fmt.Errorf("invalid -url-identity: %s", o.URLIdentity)
+	}
+	loc, err := time.LoadLocation(o.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -timezone: %v", err)
+	}
+	o.location = loc
+	for _, f := range []string{"atom-self-url", "json-feed-url", "rss-self-url"} {
+		if u := fs.Lookup(f).Value.String(); u != "" && !isAbsoluteURL(u) {
+			return nil, fmt.Errorf("invalid -%s, expected an absolute url: %s", f, u)
+		}
+	}
+	if o.PRURLTemplate != "" && !validPRURLTemplate(o.PRURLTemplate) {
+		return nil, fmt.Errorf("invalid -pr-url-template, expected exactly one %%d: %s", o.PRURLTemplate)
+	}
+	if o.Annotations != "" && o.Annotations != "github" && o.Annotations != "json" {
+		return nil, fmt.Errorf("invalid -annotations: %s", o.Annotations)
+	}
+	if o.Annotations != "" && o.Sink.Output == "stdout" {
+		return nil, fmt.Errorf("-annotations cannot be combined with -output stdout")
+	}
+	if o.explicit["full-window"] && !o.Tiered {
+		return nil, fmt.Errorf("-full-window needs -tiered")
+	}
+	if o.Merges != "combined" && o.Merges != "first-parent" {
+		return nil, fmt.Errorf("invalid -merges: %s", o.Merges)
+	}
+	if o.Restructure != "item" && o.Restructure != "suppress" {
+		return nil, fmt.Errorf("invalid -restructure: %s", o.Restructure)
+	}
+	if o.MaxItems < 0 {
+		return nil, fmt.Errorf("invalid -max-items: %d", o.MaxItems)
+	}
+	if o.Since != "" {
+		if o.sinceAge, o.sinceDate, err = parseSince(o.Since); err != nil {
+			return nil, err
+		}
+	}
+	if o.PostprocessCmd != "" {
+		args, err := SplitColumns(o.PostprocessCmd)
+		if err != nil || len(args) == 0 {
+			return nil, fmt.Errorf("invalid -postprocess-cmd: %q", o.PostprocessCmd)
+		}
+		o.postProcessors = append(o.postProcessors, commandPostProcessor(args, o.PostprocessTime))
+	}
+	if o.Files != "" && o.FollowSubmodule {
+		return nil, fmt.Errorf("-files cannot be combined with -follow-submodule")
+	}
+	for _, s := range o.MetaFiles {
+		mf, err := parseMetaFile(s)
+		if err != nil {
+			return nil, err
+		}
+		o.metaFiles = append(o.metaFiles, mf)
+	}
+	if len(o.metaFiles) > 0 && o.FollowSubmodule {
+		return nil, fmt.Errorf("-meta-file cannot be combined with -follow-submodule")
+	}
+	if err := checkCompat(o); err != nil {
+		return nil, err
+	}
+
+	o.fingerprint = optionsFingerprint(fs)
+	o.collectFingerprint = collectionFingerprint(fs)
+
+	if o.minimalTitle, err = template.New("minimal").Parse(o.MinimalTitle); err != nil {
+		return nil, fmt.Errorf("invalid -minimal-title-template: %v", err)
+	}
+	if o.authorFilter, err = newAuthorFilter(o.AnnounceAuthors, o.MuteAuthors); err != nil {
+		return nil, err
+	}
+	if o.Theme != "" {
+		if themeCSS[o.Theme] == "" {
+			return nil, fmt.Errorf("invalid -theme: %s", o.Theme)
+		}
+		// the stylesheet is generated, so it has to be a file the feeds reference relatively
+		if s := path.Clean(filepath.ToSlash(o.Stylesheet)); o.Stylesheet != "" && (strings.Contains(o.Stylesheet, ":") || path.IsAbs(s) || s == ".." || strings.HasPrefix(s, "../")) {
+			return nil, fmt.Errorf("invalid -stylesheet, expected a file in -destdir with -theme: %s", o.Stylesheet)
+		}
+		if o.theme, err = loadTheme(o.Theme, o.ThemeDir); err != nil {
+			return nil, fmt.Errorf("invalid -theme-dir: %v", err)
+		}
+		outputs := make(map[string]bool)
+		for _, name := range outputNames(o) {
+			outputs[name] = true
+		}
+		for _, name := range o.theme.files(o.Stylesheet) {
+			if outputs[path.Clean(filepath.ToSlash(name))] {
+				return nil, fmt.Errorf("invalid -theme-dir, %s would overwrite an output of the same name", name)
+			}
+		}
+	} else if o.ThemeDir != "" {
+		return nil, fmt.Errorf("-theme-dir needs -theme")
+	}
+
+	// filters match against the section of an entry as well
+	if len(o.FilterFeeds) > 0 || o.SectionCategories || o.Sections != "" {
+		o.sections = true
+	}
+
+	o.schemes = splitList(o.AllowedSchemes)
+
+	if o.IDAuthority == "" {
+		if u, err := url.Parse(o.Link); err == nil {
+			o.IDAuthority = u.Hostname()
+		}
+	}
+	if o.IDAuthority == "" {
+		return nil, fmt.Errorf("missing -id-authority, -link has no host to derive it from: %s", o.Link)
+	}
+
+	if o.Sink.HeadersFile != "" && headerFiles[o.Sink.HeadersFile] == "" {
+		return nil, fmt.Errorf("invalid -headers-file: %s", o.Sink.HeadersFile)
+	}
+	// the maintainer feed is for the maintainers only
+	if o.cache, err = newCachePolicy(o.CachePolicy, []string{o.MaintainerFile}); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// workfile is the repository path of the file to work with, git paths always use forward slashes
+func (o *options) workfile() string {
+	return path.Join(filepath.ToSlash(o.PathPrefix), filepath.ToSlash(o.Workfile))
+}
+
+// publicPath is the path a generated file is published under relative to the site url
+func (o *options) publicPath(name string) string {
+	return path.Join("/", filepath.ToSlash(o.URLPrefix), name)
+}
+
+// selfURL is what a feed advertises as its own url: the override when given, which replaces
+// site url and -url-prefix alike, otherwise the public path of the file
+func (o *options) selfURL(name string, override string) string {
+	if override != "" {
+		return override
+	}
+
+	return o.publicPath(name)
+}
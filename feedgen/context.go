@@ -0,0 +1,80 @@
+package feedgen
+
+import (
+	"fmt"
+)
+
+// neighbors finds the entries listed directly before and after the named entry within its section
+func neighbors(entries []entry, name string) (section string, before *entry, after *entry, found bool) {
+	for n, e := range entries {
+		if e.Name != name {
+			continue
+		}
+
+		if n > 0 && sameSection(entries[n-1], e) {
+			before = &entries[n-1]
+		}
+		if n < len(entries)-1 && sameSection(entries[n+1], e) {
+			after = &entries[n+1]
+		}
+
+		return e.Section, before, after, true
+	}
+
+	return "", nil, nil, false
+}
+
+// position finds the place of the named entry within its section counting from 1 and the
+// number of entries in that section, zero when the entry is not listed
+func position(entries []entry, name string) (int, int) {
+	for n, e := range entries {
+		if e.Name != name {
+			continue
+		}
+
+		pos, total := 0, 0
+		for i, o := range entries {
+			if !sameSection(o, e) {
+				continue
+			}
+			total++
+			if i <= n {
+				pos++
+			}
+		}
+
+		return pos, total
+	}
+
+	return 0, 0
+}
+
+// sameSection reports whether two entries are listed under the same heading of the same file
+func sameSection(a entry, b entry) bool {
+	return a.Section == b.Section && a.File == b.File
+}
+
+// contextSentence describes where in the list an entry was added or removed
+func contextSentence(verb string, section string, before *entry, after *entry) string {
+	where := "the list"
+	if section != "" {
+		where = section
+	}
+
+	// the top and bottom of a section take another preposition than the section itself
+	edge := verb
+	if verb == "Updated in" {
+		edge = "Updated at"
+	}
+
+	switch {
+	case before != nil && after != nil:
+		return fmt.Sprintf("%s %s between %s and %s.", verb, where, before.Name, after.Name)
+	case after != nil:
+		return fmt.Sprintf("%s the top of %s, before %s.", edge, where, after.Name)
+	case before != nil:
+		return fmt.Sprintf("%s the bottom of %s, after %s.", edge, where, before.Name)
+	}
+
+	return fmt.Sprintf("%s %s as its only entry.", verb, where)
+}
@@ -0,0 +1,152 @@
+package feedgen
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// formatDate renders a date for humans: absolute, relative to the anchor, or both; anchoring
+// on a commit instead of the clock keeps the text the same for the same history
+func formatDate(t time.Time, anchor time.Time, mode string) string {
+	switch mode {
+	case "relative":
+		return relativeDate(t, anchor)
+	case "both":
+		return fmt.Sprintf("%s (%s)", t.Format(time.RFC3339), relativeDate(t, anchor))
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// relativeDate describes how long before the anchor a date is, in its largest whole unit
+func relativeDate(t time.Time, anchor time.Time) string {
+	d := anchor.Sub(t)
+	if d < 0 {
+		return "after the latest commit"
+	}
+
+	units := []struct {
+		size      time.Duration
+		one, many string
+	}{
+		{365 * 24 * time.Hour, "year", "years"},
+		{30 * 24 * time.Hour, "month", "months"},
+		{7 * 24 * time.Hour, "week", "weeks"},
+		{24 * time.Hour, "day", "days"},
+		{time.Hour, "hour", "hours"},
+		{time.Minute, "minute", "minutes"},
+	}
+	for _, u := range units {
+		if n := int(d / u.size); n > 0 {
+			return fmt.Sprintf("%d %s ago", n, plural(n, u.one, u.many))
+		}
+	}
+
+	return "at the latest commit"
+}
+
+// clampFutureDates handles items dated more than skew after the newest commit, which only
+// happens with a wrong clock: clamp moves them to the newest commit, skip leaves them out and
+// keep does nothing; the feed is then never updated later than its newest item
+func clampFutureDates(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, newest time.Time, skew time.Duration, mode string, w *warnings) {
+	limit := newest.Add(skew)
+
+	var items []*feeds.Item
+	for _, item := range feed.Items {
+		if mode != "keep" && item.Created.After(limit) {
+			w.warn("future-date", "%s is dated %s, after the newest commit at %s", item.Title, item.Created.Format(time.RFC3339), newest.Format(time.RFC3339))
+			if mode == "skip" {
+				continue
+			}
+
+			if m := meta[item]; m != nil {
+				m.ClampedFrom = item.Created
+			}
+			item.Created = newest
+		}
+
+		items = append(items, item)
+	}
+	feed.Items = items
+
+	if mode == "keep" || !feed.Updated.After(limit) {
+		return
+	}
+
+	feed.Updated = time.Time{}
+	for _, item := range feed.Items {
+		if item.Created.After(feed.Updated) {
+			feed.Updated = item.Created
+		}
+	}
+}
+
+// parseSince reads a cutoff that is either a date like 2023-01-01 or an age like 90d, ages
+// count back from the newest commit later on
+func parseSince(s string) (time.Duration, time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return 0, t, nil
+	}
+
+	d, err := parseAge(s)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid -since, neither a date nor an age: %s", s)
+	}
+
+	return d, time.Time{}, nil
+}
+
+// limitItems keeps at most max of the newest items and only those created at or after since,
+// whichever leaves fewer; zero values disable either limit, the order of the items stays as is
+// and the feed is updated as of its newest remaining item
+func limitItems(feed *feeds.Feed, max int, since time.Time) int {
+	keep := make(map[*feeds.Item]bool)
+	for _, item := range feed.Items {
+		if !item.Created.Before(since) {
+			keep[item] = true
+		}
+	}
+
+	if max > 0 && len(keep) > max {
+		// later items win ties, they come from newer commits
+		newest := make([]*feeds.Item, 0, len(keep))
+		for i := len(feed.Items) - 1; i >= 0; i-- {
+			if keep[feed.Items[i]] {
+				newest = append(newest, feed.Items[i])
+			}
+		}
+		sort.SliceStable(newest, func(i, j int) bool {
+			return newest[i].Created.After(newest[j].Created)
+		})
+		for i := len(newest) - 1; i >= 0 && len(keep) > max; i-- {
+			delete(keep, newest[i])
+		}
+	}
+
+	if len(keep) == len(feed.Items) {
+		return 0
+	}
+
+	pruned := len(feed.Items) - len(keep)
+	var items []*feeds.Item
+	for _, item := range feed.Items {
+		if keep[item] {
+			items = append(items, item)
+		}
+	}
+	feed.Items = items
+
+	if len(items) > 0 {
+		feed.Updated = time.Time{}
+		for _, item := range items {
+			if item.Created.After(feed.Updated) {
+				feed.Updated = item.Created
+			}
+		}
+	}
+
+	return pruned
+}
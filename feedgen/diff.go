@@ -0,0 +1,241 @@
+package feedgen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// workfileLines returns the lines of the work file's patch prefixed with +, - or a space
+func workfileLines(patch *object.Patch, workfile string) []string {
+	var lines []string
+	for _, fp := range patch.FilePatches() {
+		from, to := fp.Files()
+		if (to == nil || to.Path() != workfile) && (from == nil || from.Path() != workfile) {
+			continue
+		}
+
+		for _, chunk := range fp.Chunks() {
+			op := " "
+			switch chunk.Type() {
+			case diff.Add:
+				op = "+"
+			case diff.Delete:
+				op = "-"
+			}
+
+			for _, line := range strings.Split(strings.TrimSuffix(chunk.Content(), "\n"), "\n") {
+				lines = append(lines, op+line)
+			}
+		}
+	}
+
+	return lines
+}
+
+// entryDiff returns the changed line of an entry with one line of context around it,
+// truncated to limit bytes
+func entryDiff(lines []string, sign string, name string, limit int) string {
+	for n, line := range lines {
+		if !strings.HasPrefix(line, sign) || !strings.Contains(line, "["+name+"](") {
+			continue
+		}
+
+		from, to := n-1, n+2
+		if from < 0 {
+			from = 0
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		d := strings.Join(lines[from:to], "\n")
+		if limit > 0 && len(d) > limit {
+			d = strings.ToValidUTF8(d[:limit], "") + "\n[...]"
+		}
+
+		return d
+	}
+
+	return ""
+}
+
+// patchSize is the number of bytes of all changed and context lines of a patch
+func patchSize(patch *object.Patch) int {
+	size := 0
+	for _, fp := range patch.FilePatches() {
+		for _, chunk := range fp.Chunks() {
+			size += len(chunk.Content())
+		}
+	}
+
+	return size
+}
+
+// patchStats tells what a commit offered to extraction and what was dropped on the way, for
+// verbose logging
+type patchStats struct {
+	files     int
+	listed    int
+	added     int
+	removed   int
+	matches   int
+	merged    int
+	excluded  int
+	moves     int
+	workfiles int
+}
+
+// countPatch counts the files of a patch, the list files among them and their added and
+// removed lines, of all files when no list files are given
+func countPatch(patch *object.Patch, workfiles []string) patchStats {
+	s := patchStats{workfiles: len(workfiles)}
+	for _, fp := range patch.FilePatches() {
+		s.files++
+
+		from, to := fp.Files()
+		listed := len(workfiles) == 0
+		for _, f := range workfiles {
+			if (to != nil && to.Path() == f) || (from != nil && from.Path() == f) {
+				listed = true
+			}
+		}
+		if !listed {
+			continue
+		}
+		if len(workfiles) > 0 {
+			s.listed++
+		}
+
+		for _, chunk := range fp.Chunks() {
+			n := strings.Count(strings.TrimSuffix(chunk.Content(), "\n"), "\n") + 1
+			switch chunk.Type() {
+			case diff.Add:
+				s.added += n
+			case diff.Delete:
+				s.removed += n
+			}
+		}
+	}
+
+	return s
+}
+
+func (s patchStats) String() string {
+	listed := ""
+	if s.workfiles > 0 {
+		listed = fmt.Sprintf(" (%d of %d list files)", s.listed, s.workfiles)
+	}
+
+	return fmt.Sprintf("%d files%s, +%d -%d lines, %d matches, %d seen in a parent, %d excluded, %d moves",
+		s.files, listed, s.added, s.removed, s.matches, s.merged, s.excluded, s.moves)
+}
+
+// diffPatches computes the patches from every base to a commit, refusing patches over limit
+// bytes unless limit is zero
+func diffPatches(bases []*object.Commit, c *object.Commit, limit int) ([]*object.Patch, error) {
+	var patches []*object.Patch
+	for _, b := range bases {
+		patch, err := b.Patch(c)
+		if err != nil {
+			return nil, err
+		}
+		if size := patchSize(patch); limit > 0 && size > limit {
+			return nil, fmt.Errorf("patch of %d bytes exceeds %d bytes", size, limit)
+		}
+		patches = append(patches, patch)
+	}
+
+	return patches, nil
+}
+
+// commonMatches keeps the matches whose line is changed in the patch as well, so only what
+// a merge changed compared to all of its parents remains
+func commonMatches(matches [][]string, patch *object.Patch, workfiles []string) [][]string {
+	lines := make(map[string]int)
+	for _, f := range workfiles {
+		for _, line := range workfileLines(patch, f) {
+			lines[f+"\x00"+line]++
+		}
+	}
+
+	var kept [][]string
+	for _, m := range matches {
+		f := matchFile(m)
+		if f == "" {
+			f = workfiles[0]
+		}
+		if key := f + "\x00" + m[0]; lines[key] > 0 {
+			lines[key]--
+			kept = append(kept, m)
+		}
+	}
+
+	return kept
+}
+
+// parents returns the parents of a commit, the first one only when first is set
+func parents(c *object.Commit, first bool) ([]*object.Commit, error) {
+	var list []*object.Commit
+	err := c.Parents().ForEach(func(p *object.Commit) error {
+		list = append(list, p)
+		if first {
+			return storer.ErrStop
+		}
+		return nil
+	})
+
+	return list, err
+}
+
+// firstParents collects the commits of the first parent chain starting at c
+func firstParents(c *object.Commit) (map[plumbing.Hash]bool, error) {
+	chain := make(map[plumbing.Hash]bool)
+	for {
+		chain[c.Hash] = true
+		if c.NumParents() == 0 {
+			return chain, nil
+		}
+
+		var err error
+		if c, err = c.Parent(0); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sameAsAny reports whether the files of a commit are the same as in one of the bases
+func sameAsAny(bases []*object.Commit, c *object.Commit, files []string) bool {
+	for _, b := range bases {
+		if sameFiles(b, c, files) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameFiles reports whether the files have the same content in both commits, a file missing
+// from both counts as the same; when in doubt they are not
+func sameFiles(a *object.Commit, b *object.Commit, files []string) bool {
+	for _, name := range files {
+		fa, errA := a.File(name)
+		fb, errB := b.File(name)
+		switch {
+		case errA == nil && errB == nil:
+			if fa.Hash != fb.Hash {
+				return false
+			}
+		case errors.Is(errA, object.ErrFileNotFound) && errors.Is(errB, object.ErrFileNotFound):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,150 @@
+package feedgen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// event is a change as appended to the event log, every line names the hash of the line
+// before it so consumers can tell the log was never rewritten
+type event struct {
+	Seq    int       `json:"seq"`
+	Prev   string    `json:"prev"`
+	Head   string    `json:"head"`
+	ID     string    `json:"id"`
+	Entry  string    `json:"entry,omitempty"`
+	Kind   string    `json:"kind"`
+	Name   string    `json:"name"`
+	Link   string    `json:"link"`
+	Commit string    `json:"commit"`
+	Date   time.Time `json:"date"`
+}
+
+// eventTail is what appending to a log needs to know of it
+type eventTail struct {
+	ids  map[string]bool
+	seq  int
+	hash string
+}
+
+// lineHash chains a line of the log to the next one
+func lineHash(line []byte) string {
+	sum := sha256.Sum256(bytes.TrimSuffix(line, []byte("\n")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// readEvents checks an event log line by line: sequence numbers count up from 1 and every
+// line names the hash of the one before; a missing file is an empty log
+func readEvents(path string) (*eventTail, error) {
+	t := &eventTail{ids: make(map[string]bool)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for n, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		// a line without newline is a write that never completed
+		if line[len(line)-1] != '\n' {
+			return nil, fmt.Errorf("line %d: incomplete", n+1)
+		}
+
+		var e event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		if e.Seq != t.seq+1 {
+			return nil, fmt.Errorf("line %d: sequence %d after %d", n+1, e.Seq, t.seq)
+		}
+		if e.Prev != t.hash {
+			return nil, fmt.Errorf("line %d: hash of the previous line does not match", n+1)
+		}
+
+		t.ids[e.ID] = true
+		t.seq = e.Seq
+		t.hash = lineHash(line)
+	}
+
+	return t, nil
+}
+
+// VerifyEventLog checks the sequence numbers and the hash chain of an event log and returns
+// the number of its events
+func VerifyEventLog(path string) (int, error) {
+	t, err := readEvents(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.seq, nil
+}
+
+// appendEvents adds the items not logged yet to the end of the event log and syncs it to
+// disk, existing lines are never touched; it returns the number of events added
+func appendEvents(path string, head string, items []*feeds.Item, meta map[*feeds.Item]*ItemMeta) (int, error) {
+	t, err := readEvents(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var b bytes.Buffer
+	added := 0
+	for _, item := range items {
+		if t.ids[item.Id] {
+			continue
+		}
+
+		t.seq++
+		e := event{Seq: t.seq, Prev: t.hash, Head: head, ID: item.Id, Date: item.Created}
+		if item.Link != nil {
+			e.Link = item.Link.Href
+		}
+		if m := meta[item]; m != nil {
+			e.Entry, e.Kind, e.Name, e.Commit = m.EntryID, m.Kind, m.Name, m.Commit
+		}
+
+		line, err := json.Marshal(e)
+		if err != nil {
+			return 0, err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+
+		t.ids[item.Id] = true
+		t.hash = lineHash(line)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	return added, f.Close()
+}
@@ -0,0 +1,134 @@
+package feedgen
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"gopkg.in/yaml.v3"
+)
+
+// name of the optional configuration file maintained in the list repository itself
+const repoConfigFile = ".feedgen.yml"
+
+// repoConfig holds the settings list maintainers can make in their repository
+type repoConfig struct {
+	Exclude    []string                  `yaml:"exclude"`
+	Suffixes   []suffixPattern           `yaml:"suffixes"`
+	Categories map[string]categoryConfig `yaml:"categories"`
+	// github usernames of contributors by commit email, for avatars of those committing with
+	// another address than their noreply one; left out of the state hash while empty
+	Authors map[string]string `yaml:"authors" json:",omitempty"`
+}
+
+// loadRepoConfig reads the repository configuration at the given commit, a missing file yields an empty one
+func loadRepoConfig(c *object.Commit) (*repoConfig, error) {
+	cfg := &repoConfig{}
+
+	f, err := c.File(repoConfigFile)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal([]byte(contents), cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", repoConfigFile, err)
+	}
+
+	return cfg, nil
+}
+
+// listValue is a repeatable string flag
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	*l = append(*l, s)
+
+	return nil
+}
+
+// compilePattern turns /regex/ into a regular expression and anything else into a glob
+// where * matches any text and ? a single character, both case-insensitive
+func compilePattern(p string) (*regexp.Regexp, error) {
+	if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		return regexp.Compile("(?i)" + p[1:len(p)-1])
+	}
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range p {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// normalizeURL lowercases scheme and host and drops a trailing slash so equivalent links compare equal
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	return u.String()
+}
+
+// excluder decides which entries are never announced
+type excluder struct {
+	patterns []*regexp.Regexp
+}
+
+func newExcluder(patterns []string) (*excluder, error) {
+	x := &excluder{}
+	for _, p := range patterns {
+		re, err := compilePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %s: %v", p, err)
+		}
+		x.patterns = append(x.patterns, re)
+	}
+
+	return x, nil
+}
+
+// excluded reports whether an entry matches any pattern by title or normalized url
+func (x *excluder) excluded(name string, link string) bool {
+	u := normalizeURL(link)
+	for _, re := range x.patterns {
+		if re.MatchString(name) || re.MatchString(u) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,214 @@
+// Package feedgen generates feeds of the changes to an awesome list from its git history.
+// GenerateFeed walks the history of the list and publishes all outputs selected in a Config;
+// the command is a thin wrapper parsing its flags into one.
+package feedgen
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// ParserVersion is the version of the extraction rules, to be raised whenever the items
+// found in a history or their identity change
+const ParserVersion = "1.8.1"
+
+// regular expression of the first release, run over the whole text of a patch: the \s*
+// after the sign crosses line ends, so an unchanged entry right below an added or removed
+// blank line counts as added or removed as well
+var legacyRe = regexp.MustCompile(`\n([+-])\s*[-] \[([^\]]+)\]\(([^\)]+)\) [-] ([^\n]+)`)
+
+// the same expression for a single added or removed diff line, as used up to parser
+// version 1.3.0
+var lineRe = regexp.MustCompile(`^([+-])\s*[-] \[([^\]]+)\]\(([^\)]+)\) [-] (.+)$`)
+
+// lines starting like an entry that the full expression does not match are malformed
+var entryStartRe = regexp.MustCompile(`^[+-]\s*[-*+] \[`)
+
+// html list items with a single link and a plain text description, as in sections kept as raw html
+var htmlEntryRe = regexp.MustCompile(`^\s*<li>\s*<a href="([^"<>]+)">([^<]+)</a>\s*[-–]\s*([^<]+?)\s*(?:</li>)?\s*$`)
+
+// html list items that the expression above does not match are nested or have several links
+var htmlStartRe = regexp.MustCompile(`^\s*<li[\s>]`)
+
+// Kind tells whether an entry was found on an added or a removed line
+type Kind string
+
+const (
+	Addition Kind = "Addition"
+	Removal  Kind = "Removal"
+)
+
+// Change is an entry found on an added or removed line of a patch
+type Change struct {
+	Kind        Kind
+	Name        string
+	URL         string
+	Description string
+	// the diff line including its sign
+	Line string
+}
+
+// Warning is a line of a patch that looks like it was meant to be an entry but is skipped
+type Warning struct {
+	// short name of the kind of problem: oversized-line, malformed-entry or html-entry
+	Class   string
+	Line    string
+	Message string
+}
+
+// ParseOptions are the rules ParsePatch applies
+type ParseOptions struct {
+	// lines longer than this many bytes are skipped with a warning, 0 for no limit
+	MaxLineLength int
+	// use the single expression of the first release over the whole patch instead of the
+	// markdown link parser, exactly as it was: lines of any length are taken
+	Legacy bool
+}
+
+// ParsePatch finds entries in the added and removed lines of a patch, one line at a time
+// so the work per line is bounded; lines skipped on the way are returned as warnings
+func ParsePatch(patch string, opts ParseOptions) ([]Change, []Warning) {
+	if opts.Legacy {
+		return parseLegacy(patch)
+	}
+
+	var changes []Change
+	var warnings []Warning
+	warn := func(line string, class string, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Class: class, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, line := range strings.Split(patch, "\n") {
+		if !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-") {
+			continue
+		}
+
+		if opts.MaxLineLength > 0 && len(line) > opts.MaxLineLength {
+			warn(line, "oversized-line", "skipping diff line of %d bytes exceeding %d bytes: %.40q...", len(line), opts.MaxLineLength, line)
+			continue
+		}
+
+		if name, url, desc, ok := ParseEntry(line[1:]); ok {
+			changes = append(changes, newChange(line, name, url, desc))
+		} else if entryStartRe.MatchString(line) {
+			warn(line, "malformed-entry", "skipping line that looks like a malformed entry: %.80q", line)
+		} else if htmlStartRe.MatchString(line[1:]) {
+			if name, url, desc, ok := ParseHTMLEntry(line[1:]); ok {
+				changes = append(changes, newChange(line, name, url, desc))
+			} else {
+				warn(line, "html-entry", "skipping html list item with nested html or several links: %.80q", line)
+			}
+		}
+	}
+
+	return changes, warnings
+}
+
+// parseLegacy finds entries the way the first release did; the line of a change is the text
+// the expression matched from the sign on, which spans several lines when it crossed line ends
+func parseLegacy(patch string) ([]Change, []Warning) {
+	var changes []Change
+	for _, idx := range legacyRe.FindAllStringSubmatchIndex(patch, -1) {
+		changes = append(changes, newChange(patch[idx[2]:idx[1]], patch[idx[4]:idx[5]], patch[idx[6]:idx[7]], patch[idx[8]:idx[9]]))
+	}
+
+	var warnings []Warning
+	for _, line := range strings.Split(patch, "\n") {
+		if entryStartRe.MatchString(line) && !lineRe.MatchString(line) {
+			warnings = append(warnings, Warning{Class: "malformed-entry", Line: line, Message: fmt.Sprintf("skipping line that looks like a malformed entry: %.80q", line)})
+		}
+	}
+
+	return changes, warnings
+}
+
+func newChange(line string, name string, url string, desc string) Change {
+	kind := Addition
+	if line[0] == '-' {
+		kind = Removal
+	}
+
+	return Change{Kind: kind, Name: name, URL: url, Description: desc, Line: line}
+}
+
+// ParseHTMLEntry reads an html list item with a single link and a plain text description
+func ParseHTMLEntry(line string) (name string, url string, desc string, ok bool) {
+	m := htmlEntryRe.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", "", false
+	}
+	// a link without text names nothing
+	name = html.UnescapeString(strings.TrimSpace(m[2]))
+	if name == "" {
+		return "", "", "", false
+	}
+
+	return name, html.UnescapeString(m[1]), html.UnescapeString(m[3]), true
+}
+
+// IsHTMLItem reports whether a line starts an html list item
+func IsHTMLItem(line string) bool {
+	return htmlStartRe.MatchString(line)
+}
+
+// FoldDefinitions turns definition style entries in the lines of a file's patch, a title line
+// followed by indented description lines, into single line entries. Both versions of the file
+// are walked, and an entry with any changed line becomes a removed entry of the old version and
+// an added one of the new version, so a changed description is an update of the title above.
+func FoldDefinitions(lines []string) []string {
+	folded := make(map[int][]string)
+	consumed := make(map[int]bool)
+
+	for _, sign := range []string{"-", "+"} {
+		// the lines of one version of the file, unchanged ones included
+		var side []int
+		for n, line := range lines {
+			if strings.HasPrefix(line, " ") || strings.HasPrefix(line, sign) {
+				side = append(side, n)
+			}
+		}
+
+		for i := 0; i < len(side); i++ {
+			title := lines[side[i]][1:]
+			if _, _, ok := ParseTitle(title); !ok {
+				continue
+			}
+
+			var desc []string
+			changed := lines[side[i]][:1] == sign
+			j := i + 1
+			for ; j < len(side) && IsDescription(lines[side[j]][1:]); j++ {
+				desc = append(desc, strings.TrimSpace(lines[side[j]][1:]))
+				changed = changed || lines[side[j]][:1] == sign
+			}
+			if len(desc) == 0 {
+				continue
+			}
+
+			if changed {
+				for _, n := range side[i:j] {
+					if lines[n][:1] == sign {
+						consumed[n] = true
+					}
+				}
+				folded[side[i]] = append(folded[side[i]], sign+strings.TrimRight(title, " \t")+" - "+strings.Join(desc, " "))
+			}
+			i = j - 1
+		}
+	}
+	if len(folded) == 0 {
+		return lines
+	}
+
+	var out []string
+	for n, line := range lines {
+		out = append(out, folded[n]...)
+		if !consumed[n] {
+			out = append(out, line)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,134 @@
+package feedgen
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		legacy   bool
+		fold     bool
+		changes  []Change
+		warnings []string
+	}{
+		{
+			fixture: "entries.patch",
+			changes: []Change{
+				{Kind: Removal, Name: "Tofu Co", URL: "https://tofu.example", Description: "Firm tofu from local soy beans."},
+				{Kind: Addition, Name: "Tofu Co", URL: "https://tofu.example/shop", Description: "Firm tofu from local soy beans."},
+				{Kind: Addition, Name: "Seitan Co", URL: "https://seitan.example", Description: "Wheat based meats."},
+				{Kind: Addition, Name: "Tempeh (fresh)", URL: "https://tempeh.example/a_(b)", Description: `Fermented \[soy\] cakes.`},
+				{Kind: Removal, Name: "Vegan Shoes", URL: "https://shoes.example/a b", Description: "Shoes without leather."},
+			},
+		},
+		{
+			// the first release only knew dashes and took the destination as written
+			fixture: "entries.patch",
+			legacy:  true,
+			changes: []Change{
+				{Kind: Removal, Name: "Tofu Co", URL: "https://tofu.example", Description: "Firm tofu from local soy beans."},
+				{Kind: Addition, Name: "Tofu Co", URL: "https://tofu.example/shop", Description: "Firm tofu from local soy beans."},
+				{Kind: Removal, Name: "Vegan Shoes", URL: `<https://shoes.example/a b> "Shoes"`, Description: "Shoes without leather."},
+			},
+			warnings: []string{"malformed-entry", "malformed-entry"},
+		},
+		{
+			fixture:  "malformed.patch",
+			warnings: []string{"malformed-entry", "malformed-entry", "malformed-entry"},
+		},
+		{
+			fixture: "html.patch",
+			changes: []Change{
+				{Kind: Addition, Name: "Soy & Co", URL: "https://soy.example/?a=1&b=2", Description: "Soy milk and tofu."},
+				{Kind: Removal, Name: "Old Shop", URL: "https://old.example", Description: "Closed."},
+			},
+			warnings: []string{"html-entry"},
+		},
+		{
+			fixture: "html.patch",
+			legacy:  true,
+		},
+		{
+			// a changed description line updates the title above it
+			fixture: "definitions.patch",
+			fold:    true,
+			changes: []Change{
+				{Kind: Removal, Name: "Tofu Co", URL: "https://tofu.example", Description: "Firm tofu."},
+				{Kind: Addition, Name: "Tofu Co", URL: "https://tofu.example", Description: "Firm tofu from local soy beans."},
+				{Kind: Addition, Name: "Tempeh Co", URL: "https://tempeh.example", Description: "Fermented soy cakes, made by hand."},
+			},
+		},
+		{
+			// unfolded, an added title line is an entry without description
+			fixture:  "definitions.patch",
+			warnings: []string{"malformed-entry"},
+		},
+	}
+
+	for _, tt := range tests {
+		data, err := os.ReadFile("testdata/" + tt.fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patch := string(data)
+		if tt.fold {
+			patch = strings.Join(FoldDefinitions(strings.Split(patch, "\n")), "\n")
+		}
+
+		changes, warnings := ParsePatch(patch, ParseOptions{Legacy: tt.legacy})
+
+		for i := range changes {
+			if changes[i].Line == "" || changes[i].Line[0] != map[Kind]byte{Addition: '+', Removal: '-'}[changes[i].Kind] {
+				t.Errorf("%s: line of %s does not match its kind: %q", tt.fixture, changes[i].Name, changes[i].Line)
+			}
+			changes[i].Line = ""
+		}
+		if !reflect.DeepEqual(changes, tt.changes) {
+			t.Errorf("%s, legacy %v: got changes\n%+v\nwant\n%+v", tt.fixture, tt.legacy, changes, tt.changes)
+		}
+
+		var classes []string
+		for _, w := range warnings {
+			classes = append(classes, w.Class)
+		}
+		if !reflect.DeepEqual(classes, tt.warnings) {
+			t.Errorf("%s, legacy %v: got warnings %v, want %v", tt.fixture, tt.legacy, classes, tt.warnings)
+		}
+	}
+}
+
+func TestParsePatchSkipsOversizedLines(t *testing.T) {
+	long := "+- [Long](https://long.example) - " + strings.Repeat("very ", 100) + "long."
+	patch := "+- [Short](https://short.example) - Short.\n" + long
+
+	changes, warnings := ParsePatch(patch, ParseOptions{MaxLineLength: 100})
+	if len(changes) != 1 || changes[0].Name != "Short" {
+		t.Errorf("got changes %+v", changes)
+	}
+	if len(warnings) != 1 || warnings[0].Class != "oversized-line" || warnings[0].Line != long {
+		t.Errorf("got warnings %+v", warnings)
+	}
+
+	if changes, _ := ParsePatch(patch, ParseOptions{}); len(changes) != 2 {
+		t.Errorf("without limit: got changes %+v", changes)
+	}
+}
+
+func TestParsePatchLegacyCrossesLines(t *testing.T) {
+	patch := "@@ -4,6 +4,7 @@\n \n ## Food\n \n+\n - [Oat Dream](https://oat.example/) - Oat milk.\n - [Tofu Town](https://tofu.example/) - All things tofu.\n"
+
+	// the whitespace after the sign of the added blank line runs into the next line
+	changes, _ := ParsePatch(patch, ParseOptions{Legacy: true})
+	want := []Change{{Kind: Addition, Name: "Oat Dream", URL: "https://oat.example/", Description: "Oat milk.", Line: "+\n - [Oat Dream](https://oat.example/) - Oat milk."}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("got %+v, want %+v", changes, want)
+	}
+
+	if changes, _ := ParsePatch(patch, ParseOptions{}); len(changes) != 0 {
+		t.Errorf("unchanged entries found: %+v", changes)
+	}
+}
@@ -0,0 +1,170 @@
+package feedgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// Result is the outcome of a run
+type Result struct {
+	// all items found, before the caps of the combined feeds
+	Feed *feeds.Feed
+	// additional per item data not covered by the feeds package
+	Meta map[*feeds.Item]*ItemMeta
+	// commits left out because their patch failed or was too large, with the reason
+	Skipped []string
+
+	o       *options
+	col     *collection
+	preview bool
+}
+
+func newResult(o *options, col *collection) *Result {
+	return &Result{Feed: col.feed, Meta: col.meta, Skipped: col.skipped, o: o, col: col}
+}
+
+// WriteAnnotations writes the warnings about lines of the list, as github workflow commands
+// or json
+func (r *Result) WriteAnnotations(w io.Writer, format string) error {
+	return printAnnotations(w, format, r.col.warnings.annotations, r.col.head)
+}
+
+// Atom renders the items as an atom feed advertising self as its url
+func (r *Result) Atom(self string) (string, error) {
+	doc := atomDocument(r.Feed, r.Meta, self, r.o.topics)
+	if r.preview {
+		doc.Id += "#preview"
+	}
+
+	return marshalFeed(doc, r.col.provenance, r.o.Stylesheet)
+}
+
+// Collect walks the history of the list and finds its items without writing anything
+func Collect(ctx context.Context, cfg Config) (*Result, error) {
+	o, err := newOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := collect(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return newResult(o, col), nil
+}
+
+// GenerateFeed walks the history of the list and publishes all outputs of the configuration;
+// runs sharing a destination directory have to be kept apart with LockDir by the caller
+func GenerateFeed(ctx context.Context, cfg Config) (*Result, error) {
+	o, err := newOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := collect(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	// nothing is published once the run is given up
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := publish(o, col); err != nil {
+		return nil, err
+	}
+
+	return newResult(o, col), nil
+}
+
+// Preview collects the items the commits of head after its merge base with base would add to
+// the feeds; nothing is published and no state is read or written. The items are labeled and
+// get ids of their own so a preview is never taken for the real thing.
+func Preview(ctx context.Context, cfg Config, head string, base string) (*Result, error) {
+	if cfg.FollowSubmodule {
+		return nil, errors.New("-follow-submodule is not supported by preview")
+	}
+
+	r := cfg.Repository
+	if r == nil {
+		var err error
+		if r, err = git.PlainOpen(cfg.Workdir); err != nil {
+			return nil, fmt.Errorf("failed to open repository: %s: %v", cfg.Workdir, err)
+		}
+	}
+	hc, err := resolveCommit(r, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", head, err)
+	}
+	bc, err := resolveCommit(r, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", base, err)
+	}
+
+	bases, err := hc.MergeBase(bc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge base: %v", err)
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("%s and %s have no common history", head, base)
+	}
+
+	// everything reachable from the merge base is part of the published feeds already
+	merged := make(map[string]bool)
+	for _, c := range bases {
+		err := object.NewCommitPreorderIter(c, nil, nil).ForEach(func(c *object.Commit) error {
+			merged[c.Hash.String()] = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk history of merge base: %v", err)
+		}
+	}
+
+	cfg.Head = hc.Hash
+	cfg.StateFile = ""
+	res, err := Collect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := res.Feed
+	var items []*feeds.Item
+	for _, item := range feed.Items {
+		if m := res.Meta[item]; m == nil || merged[m.Commit] {
+			continue
+		}
+		if item.Id != "" {
+			item.Id += "/preview"
+		}
+		item.Title = "Preview: " + item.Title
+		items = append(items, item)
+	}
+	feed.Items = items
+	feed.Title = "Preview of " + feed.Title
+	res.preview = true
+
+	if cfg.Verbose {
+		log.Printf("preview of %s from merge base %s: %d items", hc.Hash, bases[0].Hash, len(items))
+	}
+
+	return res, nil
+}
+
+// resolveCommit finds the commit of a branch, tag, other ref or hash
+func resolveCommit(r *git.Repository, rev string) (*object.Commit, error) {
+	h, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, err
+	}
+
+	return r.CommitObject(*h)
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package feedgen
+
+import (
+	"os"
+)
+
+// renameFile replaces newpath atomically
+func renameFile(oldpath string, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// chmodFile sets the permission of an open file regardless of the umask
+func chmodFile(f *os.File, mode os.FileMode) error {
+	return f.Chmod(mode)
+}
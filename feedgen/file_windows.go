@@ -0,0 +1,39 @@
+//go:build windows
+
+package feedgen
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// errors returned while another process, e.g. an editor or a virus scanner, has the file open
+const (
+	errSharingViolation syscall.Errno = 32
+	errLockViolation    syscall.Errno = 33
+)
+
+// renameFile replaces newpath, retrying for a while as long as the file is held open elsewhere
+func renameFile(oldpath string, newpath string) error {
+	var err error
+	for wait := 50 * time.Millisecond; wait <= 3200*time.Millisecond; wait *= 2 {
+		if err = os.Rename(oldpath, newpath); err == nil || !busy(err) {
+			return err
+		}
+		time.Sleep(wait)
+	}
+
+	return err
+}
+
+// busy reports whether an error only means the file is in use by another process
+func busy(err error) bool {
+	return errors.Is(err, errSharingViolation) || errors.Is(err, errLockViolation) || errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+}
+
+// chmodFile does nothing, permission bits beyond read-only do not exist on windows
+func chmodFile(f *os.File, mode os.FileMode) error {
+	return nil
+}
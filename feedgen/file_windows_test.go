@@ -0,0 +1,120 @@
+//go:build windows
+
+package feedgen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGitPathsUseSlashes(t *testing.T) {
+	o := &options{Config: Config{PathPrefix: `lists\food`, Workfile: "README.md", URLPrefix: `feeds\v1`}}
+	if got, want := o.workfile(), "lists/food/README.md"; got != want {
+		t.Errorf("workfile: got %q, want %q", got, want)
+	}
+	if got, want := o.publicPath("feed.xml"), "/feeds/v1/feed.xml"; got != want {
+		t.Errorf("public path: got %q, want %q", got, want)
+	}
+
+	o = &options{Config: Config{Workfile: `docs\list.md`}}
+	if got, want := o.workfile(), "docs/list.md"; got != want {
+		t.Errorf("workfile: got %q, want %q", got, want)
+	}
+
+	workdir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workdir, "public", "feeds"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	o = &options{Config: Config{Workdir: workdir, Destdir: workdir + `\public\feeds`}}
+	o.Sink.Output = "fs"
+	if got, want := generatedPaths(o), []string{"public/feeds"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("generated paths: got %v, want %v", got, want)
+	}
+
+	o.Destdir = workdir
+	o.AtomFile = `feeds\feed.xml`
+	if got, want := generatedPaths(o), []string{"feeds/feed.xml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("generated paths: got %v, want %v", got, want)
+	}
+}
+
+func TestRenameFileWaitsForOpenTarget(t *testing.T) {
+	dir := t.TempDir()
+	oldpath := filepath.Join(dir, "feed.xml.tmp")
+	newpath := filepath.Join(dir, "feed.xml")
+	if err := os.WriteFile(oldpath, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newpath, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// an editor keeps the previous feed open for a moment
+	f, err := os.Open(newpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		f.Close()
+		close(released)
+	}()
+
+	if err := renameFile(oldpath, newpath); err != nil {
+		t.Fatal(err)
+	}
+	<-released
+
+	if data, err := os.ReadFile(newpath); err != nil || string(data) != "new" {
+		t.Errorf("got %q, %v", data, err)
+	}
+}
+
+func TestRenameFileFailsFast(t *testing.T) {
+	dir := t.TempDir()
+
+	start := time.Now()
+	err := renameFile(filepath.Join(dir, "missing"), filepath.Join(dir, "feed.xml"))
+	if err == nil {
+		t.Fatal("renaming a missing file succeeded")
+	}
+	if busy(err) || time.Since(start) > time.Second {
+		t.Errorf("missing file retried: %v after %v", err, time.Since(start))
+	}
+}
+
+func TestBusy(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&os.LinkError{Op: "rename", Err: errSharingViolation}, true},
+		{&os.LinkError{Op: "rename", Err: errLockViolation}, true},
+		{&os.LinkError{Op: "rename", Err: syscall.ERROR_ACCESS_DENIED}, true},
+		{&os.LinkError{Op: "rename", Err: syscall.ERROR_FILE_NOT_FOUND}, false},
+		{os.ErrNotExist, false},
+	}
+
+	for _, tt := range tests {
+		if got := busy(tt.err); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestChmodFileIsNoop(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "feed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := chmodFile(f, 0600); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,137 @@
+package feedgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// feedFilter selects the items of an additional feed by keywords and patterns
+type feedFilter struct {
+	name     string
+	file     string
+	keywords []string
+	patterns []*regexp.Regexp
+	count    int
+}
+
+// newFeedFilters sets up the filters named in name=value flags, each needs a feed file
+func newFeedFilters(files, keywords, patterns []string) ([]*feedFilter, error) {
+	var filters []*feedFilter
+	byName := make(map[string]*feedFilter)
+
+	for _, s := range files {
+		name, file, found := strings.Cut(s, "=")
+		if !found || name == "" || file == "" {
+			return nil, fmt.Errorf("invalid filter feed, expected name=path: %s", s)
+		}
+		if byName[name] != nil {
+			return nil, fmt.Errorf("duplicate filter feed: %s", name)
+		}
+
+		f := &feedFilter{name: name, file: file}
+		filters = append(filters, f)
+		byName[name] = f
+	}
+
+	for _, s := range keywords {
+		name, kw, found := strings.Cut(s, "=")
+		if !found || byName[name] == nil || kw == "" {
+			return nil, fmt.Errorf("invalid filter keyword, expected name=keyword of a filter feed: %s", s)
+		}
+		byName[name].keywords = append(byName[name].keywords, strings.ToLower(kw))
+	}
+
+	for _, s := range patterns {
+		name, expr, found := strings.Cut(s, "=")
+		if !found || byName[name] == nil || expr == "" {
+			return nil, fmt.Errorf("invalid filter pattern, expected name=regex of a filter feed: %s", s)
+		}
+		re, err := regexp.Compile("(?i)" + expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter pattern: %s: %v", name, err)
+		}
+		byName[name].patterns = append(byName[name].patterns, re)
+	}
+
+	for _, f := range filters {
+		if len(f.keywords) == 0 && len(f.patterns) == 0 {
+			return nil, fmt.Errorf("filter feed without keywords or patterns: %s", f.name)
+		}
+	}
+
+	return filters, nil
+}
+
+// matches checks the plain text of an item, i.e. title, description and categories, never the html
+// content; announcements from meta files and of list files leaving or coming back are no
+// entries and never match
+func (f *feedFilter) matches(item *feeds.Item, m *ItemMeta) bool {
+	if m != nil && (m.Kind == "Meta" || m.Kind == "Restructure") {
+		return false
+	}
+
+	parts := []string{item.Title, item.Description}
+	if m != nil {
+		parts = append(parts, m.Section)
+		parts = append(parts, m.categories()...)
+	}
+	text := strings.Join(parts, "\n")
+	lower := strings.ToLower(text)
+
+	for _, kw := range f.keywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	for _, re := range f.patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply returns a feed of the matching items, which are shared with the main feed and therefore keep their ids
+func (f *feedFilter) apply(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta) *feeds.Feed {
+	filtered := &feeds.Feed{
+		Title:       feed.Title + ": " + f.name,
+		Link:        feed.Link,
+		Description: feed.Description,
+		Created:     feed.Created,
+	}
+
+	for _, item := range feed.Items {
+		if f.matches(item, meta[item]) {
+			filtered.Items = append(filtered.Items, item)
+			filtered.Updated = item.Created
+		}
+	}
+	f.count = len(filtered.Items)
+
+	return filtered
+}
+
+// restrictSections keeps the items of entries listed under one of the given sections, items
+// whose section is unknown are left out as well
+func restrictSections(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, sections []string) {
+	var items []*feeds.Item
+	for _, item := range feed.Items {
+		m := meta[item]
+		if m == nil {
+			continue
+		}
+
+		for _, s := range sections {
+			if strings.EqualFold(m.Section, s) {
+				items = append(items, item)
+				break
+			}
+		}
+	}
+
+	feed.Items = items
+}
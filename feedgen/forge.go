@@ -0,0 +1,119 @@
+package feedgen
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// forge knows the merge messages of a hosting platform and where its pull requests live
+type forge interface {
+	// parse finds the pull request a squash or merge commit message refers to, nil when the
+	// message is in no format of the forge
+	parse(subject string, body []string) *pullRequest
+	// prURL is the url of pull requests of host/repo with %d for the number
+	prURL(host string, repo string) string
+}
+
+// forges in the order their message formats are tried, the first one to recognize a message
+// wins: the formats overlap, a subject ending in (#12) is a squash merge on github as well as
+// on gitea, and a squash merge on github keeps a gitlab merge message it was made from
+var forges = []forge{githubForge{}, gitlabForge{}, giteaForge{}}
+
+// forgeOf picks the forge of a host, nil for unknown hosts
+func forgeOf(host string) forge {
+	switch {
+	case host == "github.com":
+		return githubForge{}
+	case host == "gitlab.com" || strings.HasPrefix(host, "gitlab."):
+		return gitlabForge{}
+	case host == "codeberg.org" || strings.HasPrefix(host, "gitea.") || strings.HasPrefix(host, "forgejo."):
+		return giteaForge{}
+	}
+
+	return nil
+}
+
+// firstLine is the first non-empty line of a message body, or else the fallback
+func firstLine(body []string, fallback string) string {
+	for _, l := range body {
+		if l = strings.TrimSpace(l); l != "" {
+			return l
+		}
+	}
+
+	return fallback
+}
+
+var (
+	// squash merges of github and gitea end their subject with the number, e.g. "Add tofu (#12)"
+	squashRe = regexp.MustCompile(`\s*\(#(\d+)\)\s*$`)
+	// merge commits of github name it first, the title follows in the body
+	mergeRe = regexp.MustCompile(`^Merge pull request #(\d+) from \S+`)
+	// merge commits of gitlab end with a reference to the merge request, the title is in the body
+	mergeRequestRe = regexp.MustCompile(`^See merge request \S+!(\d+)$`)
+	// merge commits of gitea and forgejo quote the title before the number
+	giteaMergeRe = regexp.MustCompile(`^Merge pull request '(.*)' \(#(\d+)\) from \S+`)
+	// forgejo links the pull request in a trailer of squash and merge commits
+	reviewedOnRe = regexp.MustCompile(`^Reviewed-on: \S+/pulls/(\d+)$`)
+)
+
+type githubForge struct{}
+
+func (githubForge) parse(subject string, body []string) *pullRequest {
+	if m := mergeRe.FindStringSubmatch(subject); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return &pullRequest{Number: n, Title: firstLine(body, subject)}
+	}
+
+	if m := squashRe.FindStringSubmatch(subject); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return &pullRequest{Number: n, Title: squashRe.ReplaceAllString(subject, "")}
+	}
+
+	return nil
+}
+
+func (githubForge) prURL(host string, repo string) string {
+	return "https://" + host + "/" + repo + "/pull/%d"
+}
+
+type gitlabForge struct{}
+
+func (gitlabForge) parse(subject string, body []string) *pullRequest {
+	for n := len(body) - 1; n >= 0; n-- {
+		if m := mergeRequestRe.FindStringSubmatch(strings.TrimSpace(body[n])); m != nil {
+			num, _ := strconv.Atoi(m[1])
+			return &pullRequest{Number: num, Title: firstLine(body[:n], subject)}
+		}
+	}
+
+	return nil
+}
+
+func (gitlabForge) prURL(host string, repo string) string {
+	return "https://" + host + "/" + repo + "/-/merge_requests/%d"
+}
+
+type giteaForge struct{}
+
+func (giteaForge) parse(subject string, body []string) *pullRequest {
+	if m := giteaMergeRe.FindStringSubmatch(subject); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return &pullRequest{Number: n, Title: m[1]}
+	}
+
+	// rebased pull requests only tell in the trailer
+	for _, l := range body {
+		if m := reviewedOnRe.FindStringSubmatch(strings.TrimSpace(l)); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			return &pullRequest{Number: n, Title: squashRe.ReplaceAllString(subject, "")}
+		}
+	}
+
+	return nil
+}
+
+func (giteaForge) prURL(host string, repo string) string {
+	return "https://" + host + "/" + repo + "/pulls/%d"
+}
@@ -0,0 +1,125 @@
+package feedgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addPatchSeeds adds the patches in testdata to the corpus of a fuzz test
+func addPatchSeeds(f *testing.F) {
+	paths, err := filepath.Glob("testdata/*.patch")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+	f.Add("+- [" + strings.Repeat("[", 1000) + "](https://x.example) - x")
+	f.Add("+- [a](" + strings.Repeat("(", 1000) + ") - x")
+	f.Add("+<li><a href=\"https://x.example\">" + strings.Repeat("<", 1000))
+}
+
+func FuzzParsePatch(f *testing.F) {
+	addPatchSeeds(f)
+
+	f.Fuzz(func(t *testing.T, patch string) {
+		for _, legacy := range []bool{false, true} {
+			changes, warnings := ParsePatch(patch, ParseOptions{MaxLineLength: 4096, Legacy: legacy})
+
+			for _, c := range changes {
+				// the first release had no limit on the length of lines
+				if !strings.Contains(patch, c.Line) || !legacy && len(c.Line) > 4096 {
+					t.Fatalf("change from a line not in the patch: %q", c.Line)
+				}
+				if c.Name == "" || c.URL == "" || c.Description == "" {
+					t.Fatalf("incomplete change %+v", c)
+				}
+				if (c.Kind == Addition) != (c.Line[0] == '+') {
+					t.Fatalf("kind %s of line %q", c.Kind, c.Line)
+				}
+			}
+			for _, w := range warnings {
+				if !strings.Contains(patch, w.Line) {
+					t.Fatalf("warning about a line not in the patch: %q", w.Line)
+				}
+			}
+		}
+	})
+}
+
+func FuzzFoldDefinitions(f *testing.F) {
+	addPatchSeeds(f)
+
+	f.Fuzz(func(t *testing.T, patch string) {
+		lines := strings.Split(patch, "\n")
+		folded := FoldDefinitions(lines)
+
+		// folding only ever replaces changed lines, unchanged ones are kept in order
+		var kept, was []string
+		for _, l := range folded {
+			if strings.HasPrefix(l, " ") {
+				kept = append(kept, l)
+			}
+		}
+		for _, l := range lines {
+			if strings.HasPrefix(l, " ") {
+				was = append(was, l)
+			}
+		}
+		if strings.Join(kept, "\n") != strings.Join(was, "\n") {
+			t.Fatalf("unchanged lines differ after folding:\n%q\n%q", was, kept)
+		}
+	})
+}
+
+func FuzzParseLink(f *testing.F) {
+	f.Add("[Tofu](https://tofu.example) - rest")
+	f.Add(`[a \] b](<c d> "e") f`)
+	f.Add("[" + strings.Repeat("[", 1000) + "](x)")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		text, dest, rest, ok := ParseLink(s)
+		if !ok {
+			if text != "" || dest != "" || rest != s {
+				t.Fatalf("failed parse returned %q %q %q", text, dest, rest)
+			}
+			return
+		}
+		if text == "" || dest == "" || !strings.HasSuffix(s, rest) {
+			t.Fatalf("parse of %q returned %q %q %q", s, text, dest, rest)
+		}
+	})
+}
+
+// a long line of opening brackets must neither hang the parser nor be matched
+func TestParsePatchPathologicalLines(t *testing.T) {
+	for _, line := range []string{
+		"+- " + strings.Repeat("[", 100000),
+		"+- [" + strings.Repeat("[[[[[", 20000) + "](https://x.example) - x",
+		"+- [a](" + strings.Repeat("((((((", 20000),
+		"+<li>" + strings.Repeat("<a href=\"x\">", 10000),
+	} {
+		for _, limit := range []int{0, 4096} {
+			start := time.Now()
+			changes, warnings := ParsePatch(line, ParseOptions{MaxLineLength: limit})
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("%.20q..., limit %d: took %s", line, limit, d)
+			}
+			if len(changes) != 0 {
+				t.Errorf("%.20q..., limit %d: matched %+v", line, limit, changes[0].Name)
+			}
+			if len(warnings) != 1 {
+				t.Errorf("%.20q..., limit %d: got %d warnings", line, limit, len(warnings))
+			} else if limit > 0 && warnings[0].Class != "oversized-line" {
+				t.Errorf("%.20q..., limit %d: got %s warning", line, limit, warnings[0].Class)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package feedgen
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// gap is a list file leaving or coming back in a commit, e.g. when the list is moved to
+// another directory for a while
+type gap struct {
+	file string
+	// path the file was moved to or from in the same commit, empty when it was deleted or created
+	other string
+	back  bool
+}
+
+// title describes the transition in the words of an item title
+func (g *gap) title() string {
+	switch {
+	case g.back && g.other != "":
+		return g.file + " moved back from " + g.other
+	case g.back:
+		return g.file + " restored"
+	case g.other != "":
+		return g.file + " moved to " + g.other
+	}
+
+	return g.file + " removed"
+}
+
+// hasFile reports whether a commit has a file, when in doubt it has not
+func hasFile(c *object.Commit, name string) bool {
+	_, err := c.File(name)
+
+	return err == nil
+}
+
+// findGap tells whether a list file leaves or comes back between a base and a commit, nil when
+// it is in both or in neither; renames in the patch tell where it went or came from
+func findGap(base *object.Commit, c *object.Commit, patch *object.Patch, file string) *gap {
+	inBase, inCommit := hasFile(base, file), hasFile(c, file)
+	if inBase == inCommit {
+		return nil
+	}
+
+	g := &gap{file: file, back: inCommit}
+	for _, fp := range patch.FilePatches() {
+		from, to := fp.Files()
+		if from == nil || to == nil || from.Path() == to.Path() {
+			continue
+		}
+		if g.back && to.Path() == file {
+			g.other = from.Path()
+		}
+		if !g.back && from.Path() == file {
+			g.other = to.Path()
+		}
+	}
+
+	return g
+}
+
+// gapLines returns the patch lines of a list file between the commit it was last seen in and
+// the commit it came back in, so everything changed in between is announced once
+func gapLines(since *object.Commit, c *object.Commit, file string) ([]string, error) {
+	a, err := since.Tree()
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	// without rename detection, the file is compared with itself only
+	changes, err := object.DiffTree(a, b)
+	if err != nil {
+		return nil, err
+	}
+	var own object.Changes
+	for _, ch := range changes {
+		if ch.From.Name == file || ch.To.Name == file {
+			own = append(own, ch)
+		}
+	}
+
+	patch, err := own.Patch()
+	if err != nil {
+		return nil, err
+	}
+
+	return workfileLines(patch, file), nil
+}
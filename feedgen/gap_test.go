@@ -0,0 +1,74 @@
+package feedgen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+// TestListMovedOutAndBack moves a long list to docs/ for a while, with changes made there,
+// and back again: each move is a single item and the changes are announced once on return
+func TestListMovedOutAndBack(t *testing.T) {
+	var entries []string
+	for n := 1; n <= 200; n++ {
+		entries = append(entries, fmt.Sprintf("- [Shop %03d](https://shop%03d.example/) - Vegan shop number %d.", n, n, n))
+	}
+	list := func(lines ...string) []string {
+		return append(append([]string{"Shops"}, entries...), lines...)
+	}
+	moved := func(s feedgentest.Snapshot) feedgentest.Snapshot {
+		s.Files = map[string][]byte{"docs/README.md": s.Files["README.md"]}
+		return s
+	}
+
+	const (
+		tofu  = "- [Tofu Town](https://tofu.example/) - All things tofu."
+		oat   = "- [Oat Dream](https://oat.example/) - Oat milk for coffee."
+		oat2  = "- [Oat Dream](https://oat.example/) - Oat milk for coffee and tea."
+		bags  = "- [Bags](https://bags.example/) - Bags without leather."
+		shoes = "- [Vegan Shoes](https://shoes.example/) - Shoes without leather."
+	)
+	history := []feedgentest.Snapshot{
+		listSnapshot("Alice", 1, "Start the list", list(tofu, oat)...),
+		moved(listSnapshot("Alice", 2, "Move the list to docs", list(tofu, oat)...)),
+		moved(listSnapshot("Bob", 3, "Add Bags", list(tofu, oat, bags)...)),
+		moved(listSnapshot("Chloé", 4, "Mention tea", list(tofu, oat2, bags)...)),
+		listSnapshot("Alice", 5, "Move the list back", list(tofu, oat2, bags)...),
+		listSnapshot("Bob", 6, "Add Vegan Shoes", list(tofu, oat2, bags, shoes)...),
+	}
+
+	files := generate(t, nil, history...)
+	got := feedEntries(t, files["feed.xml"])
+	want := []string{
+		"2024-03-02T09:30:00Z README.md moved to docs/README.md by Alice: https://awesome-veganism.com/ Move the list to docs",
+		// what changed while the list was gone is dated by its return
+		"2024-03-05T09:30:00Z Update of Oat Dream by Alice: https://oat.example/ Oat milk for coffee and tea.",
+		"2024-03-05T09:30:00Z Addition of Bags by Alice: https://bags.example/ Bags without leather.",
+		"2024-03-05T09:30:00Z README.md moved back from docs/README.md by Alice: https://awesome-veganism.com/ Move the list back",
+		"2024-03-06T09:30:00Z Addition of Vegan Shoes by Bob: https://shoes.example/ Shoes without leather.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	// a run while the list is away remembers where it was last seen for the next one
+	dir := t.TempDir()
+	generateInto(t, dir, []string{"-state", "state.json"}, history[:3]...)
+	if continued := generateInto(t, dir, []string{"-state", "state.json"}, history...); !reflect.DeepEqual(feedEntries(t, continued["feed.xml"]), want) {
+		t.Errorf("continued from a state: got\n%q", feedEntries(t, continued["feed.xml"]))
+	}
+
+	got = feedEntries(t, generate(t, []string{"-restructure", "suppress"}, history...)["feed.xml"])
+	var kept []string
+	for _, e := range want {
+		if !strings.Contains(e, "README.md") {
+			kept = append(kept, e)
+		}
+	}
+	if !reflect.DeepEqual(got, kept) {
+		t.Errorf("suppressed: got\n%q\nwant\n%q", got, kept)
+	}
+}
@@ -0,0 +1,945 @@
+package feedgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// entryContent is the html content of an item linking to the entry, with the changed lines when given
+func entryContent(desc string, name string, link string, diff string) string {
+	content := fmt.Sprintf("<p>%s</p>\n<p><a href=\"%s\">%s</a></p>", html.EscapeString(desc), html.EscapeString(link), html.EscapeString(name))
+	if diff != "" {
+		content += fmt.Sprintf("\n<pre>%s</pre>", html.EscapeString(diff))
+	}
+
+	return content
+}
+
+// feedAuthor parses a name optionally followed by an address in angle brackets, nil when empty
+func feedAuthor(s string) *feeds.Author {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	if a, err := mail.ParseAddress(s); err == nil {
+		return &feeds.Author{Name: a.Name, Email: a.Address}
+	}
+
+	return &feeds.Author{Name: s}
+}
+
+// collection is the outcome of walking the history of the work file
+type collection struct {
+	repo      *git.Repository
+	workfiles []string
+	commits   []*object.Commit
+	feed      *feeds.Feed
+
+	// additional per item data not covered by the feeds package
+	meta map[*feeds.Item]*ItemMeta
+
+	// first addition and last change of every entry
+	history  map[string]*entryHistory
+	registry *registry
+	head     *object.Commit
+	warnings *warnings
+	timings  *timings
+
+	// list files gone for a while, with the commit they were last seen in
+	gaps map[string]plumbing.Hash
+
+	// what the feeds are generated from
+	provenance *Provenance
+
+	// weekly limits of sections in the combined feed
+	caps map[string]categoryConfig
+
+	// additional feeds of items matching keywords
+	filters []*feedFilter
+
+	// entries left out of all feeds
+	exclude  *excluder
+	excluded int
+
+	// entries removed from all outputs and history, with the number of their items
+	scrub    *scrubList
+	scrubbed int
+
+	// items of muted authors left out of the minimal feed
+	muted int
+
+	// commits left out because their patch failed or was too large, with the reason
+	skipped []string
+
+	// what the next run needs to continue from here
+	state []byte
+
+	// items in the order they were found, before grouping and pruning
+	changes []*feeds.Item
+}
+
+// defaults of the list this tool was written for
+const (
+	defaultWorkfile    = "README.md"
+	defaultTitle       = "Awesome Veganism Feed"
+	defaultLink        = "https://awesome-veganism.com/"
+	defaultDescription = "A curated list of awesome resources, pointers, and tips to make veganism easy and accessible to everyone."
+)
+
+// collect opens the checked out repository in the work directory, unless a repository is
+// given, and collects its history
+func collect(ctx context.Context, o *options) (*collection, error) {
+	r := o.Repository
+	if r == nil {
+		// open checked out repository
+		var err error
+		if r, err = git.PlainOpen(o.Workdir); err != nil {
+			return nil, fmt.Errorf("failed to open repository: %s: %v", o.Workdir, err)
+		}
+	}
+
+	// the files of -files are found in the history itself, as is the work file of a
+	// repository given without checkout
+	if o.Files != "" {
+		return collectRepo(ctx, o, r, "")
+	} else if o.Repository != nil {
+		return collectRepo(ctx, o, r, o.workfile())
+	}
+
+	// make sure file exists and find the history it has
+	r, workfile, err := resolveWorkfile(r, o.Workdir, o.workfile(), o.FollowSubmodule)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectRepo(ctx, o, r, workfile)
+}
+
+// collectRepo walks the history of the work file, or of the files of -files when no work file
+// is given, and turns changed entries into feed items; the repository does not need a worktree
+// on disk
+func collectRepo(ctx context.Context, o *options, r *git.Repository, workfile string) (*collection, error) {
+	timer := &timings{}
+	done := timer.start("open")
+
+	// get HEAD reference
+	ref, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD reference: %v", err)
+	}
+	if !o.Head.IsZero() {
+		ref = plumbing.NewHashReference(plumbing.HEAD, o.Head)
+	}
+
+	workfiles := []string{workfile}
+	if workfile == "" {
+		c, err := r.CommitObject(ref.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get HEAD commit: %v", err)
+		}
+		if workfiles, err = resolveFiles(c, filepath.ToSlash(o.PathPrefix), o.Files, generatedPaths(o)); err != nil {
+			return nil, err
+		}
+	}
+	done(1)
+
+	if o.Verbose {
+		log.Printf("path filter: %s", strings.Join(workfiles, ", "))
+	}
+
+	done = timer.start("log")
+	logopts := &git.LogOptions{
+		From:  ref.Hash(),
+		Order: git.LogOrderCommitterTime,
+	}
+	// meta files share the walk, a commit changing only them is visited as well
+	watched := append([]string{}, workfiles...)
+	for _, mf := range o.metaFiles {
+		watched = append(watched, mf.path)
+	}
+
+	// a single file keeps following the history the way it always did
+	if len(watched) == 1 {
+		logopts.FileName = &workfiles[0]
+	} else {
+		logopts.PathFilter = func(name string) bool {
+			for _, f := range watched {
+				if name == f {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	// the state of an earlier run limits the walk to the commits added since
+	var state *collectState
+	if o.StateFile != "" && !o.Full {
+		state, err = loadState(filepath.Join(o.Destdir, o.StateFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state: %v", err)
+		}
+		if state != nil && state.Parser != parserVersion {
+			// items found by other extraction rules may differ in identity, always worth a note
+			log.Printf("state of parser version %q, processing the full history with parser version %s", state.Parser, parserVersion)
+			state = nil
+		} else if state != nil && !state.usable(o, workfiles) {
+			if o.Verbose {
+				log.Printf("state of a different version or settings, processing the full history")
+			}
+			state = nil
+		}
+	}
+
+	stop := ""
+	if state != nil {
+		stop = state.Commits[0]
+	}
+
+	// get commit history
+	commits, found, err := logCommits(r, logopts, stop)
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate commit log: %v", err)
+	}
+	if state != nil && !found {
+		if o.Verbose {
+			log.Printf("last processed commit %s is gone, processing the full history", stop)
+		}
+		state = nil
+	}
+	if state != nil {
+		ok, err := state.continues(r, ref.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare the history with the state: %v", err)
+		}
+		if !ok {
+			if o.Verbose {
+				log.Printf("history merged below the last processed commit %s, processing the full history", stop)
+			}
+			state = nil
+			if commits, _, err = logCommits(r, logopts, ""); err != nil {
+				return nil, fmt.Errorf("failed to iterate commit log: %v", err)
+			}
+		}
+	}
+
+	if len(commits) == 0 {
+		return nil, errors.New("failed to find commits")
+	}
+	done(len(commits))
+
+	// exclusions and suffix patterns come from the command line and the repository itself
+	head, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %v", err)
+	}
+	rcfg, err := loadRepoConfig(head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load repository configuration: %v", err)
+	}
+	exclude, err := newExcluder(append(append([]string{}, o.ExcludeEntries...), rcfg.Exclude...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup exclusions: %v", err)
+	}
+
+	// suffix patterns from the command line come before those of the repository
+	var patterns []suffixPattern
+	for _, s := range o.SuffixPatterns {
+		p, err := parseSuffixFlag(s)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	o.authors = make(map[string]string)
+	for email, user := range rcfg.Authors {
+		if !githubUserRe.MatchString(user) {
+			return nil, fmt.Errorf("invalid github username of %s in %s: %q", email, repoConfigFile, user)
+		}
+		o.authors[strings.ToLower(email)] = user
+	}
+	// capped categories are told apart by the section of each entry
+	if len(rcfg.Categories) > 0 {
+		if o.Compat != "" {
+			return nil, fmt.Errorf("categories of %s are not supported with -compat %s", repoConfigFile, o.Compat)
+		}
+		o.sections = true
+	}
+
+	suffixes, err := newSuffixRules(append(patterns, rcfg.Suffixes...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup suffix patterns: %v", err)
+	}
+	if o.Compat != "" && len(suffixes) > 0 {
+		return nil, fmt.Errorf("suffix patterns of %s are not supported with -compat %s", repoConfigFile, o.Compat)
+	}
+
+	// only the commits newer than the last processed one are walked, the older ones come from the state
+	start := len(commits) - 1
+	if state != nil && state.Config != configHash(rcfg) {
+		if o.Verbose {
+			log.Printf("repository configuration changed, processing the full history")
+		}
+		state = nil
+		if commits, _, err = logCommits(r, logopts, ""); err != nil {
+			return nil, fmt.Errorf("failed to iterate commit log: %v", err)
+		}
+		start = len(commits) - 1
+	} else if state != nil {
+		older, err := state.olderCommits(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load processed commits: %v", err)
+		}
+		commits = append(commits, older...)
+		if o.Verbose {
+			log.Printf("continuing from state: %d new commits", start)
+		}
+	}
+
+	// setup feed
+	// lists hosted on github describe themselves there already
+	if o.GithubMeta {
+		applyGithubMeta(o, r)
+	}
+
+	feed := &feeds.Feed{
+		Title:       o.Title,
+		Link:        &feeds.Link{Href: o.Link},
+		Description: o.Description,
+		Author:      feedAuthor(o.Author),
+		Created:     commits[len(commits)-1].Author.When,
+	}
+
+	col := &collection{
+		repo:      r,
+		workfiles: workfiles,
+		commits:   commits,
+		feed:      feed,
+		meta:      make(map[*feeds.Item]*ItemMeta),
+		history:   make(map[string]*entryHistory),
+		registry:  newRegistry(o.URLIdentity),
+		gaps:      make(map[string]plumbing.Hash),
+		head:      head,
+		warnings:  &warnings{},
+		timings:   timer,
+		exclude:   exclude,
+
+		caps:       rcfg.Categories,
+		provenance: &Provenance{Head: head.Hash.String(), Version: Version, Parser: parserVersion, Options: o.fingerprint},
+	}
+
+	col.filters, err = newFeedFilters(o.FilterFeeds, o.FilterKeywords, o.FilterRegexps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup filter feeds: %v", err)
+	}
+
+	// a checkout left on an old branch silently yields a stale feed
+	if o.Head.IsZero() {
+		if msg, err := checkDefaultBranch(r, ref); err != nil {
+			return nil, fmt.Errorf("failed to compare with default branch: %v", err)
+		} else if msg != "" {
+			if o.RequireDefault {
+				return nil, errors.New(msg)
+			}
+			col.warnings.warn("default-branch", "%s", msg)
+		}
+	}
+
+	if state != nil {
+		state.restore(col)
+	}
+
+	// ids only depend on the commit, the entry and the kind of change, so they are the same on
+	// every run; earlier releases left them to the feeds package
+	ids := make(map[string]int)
+	newID := func(p *object.Commit, name string, kind string) string {
+		if o.Compat != "" {
+			return ""
+		}
+
+		id := itemID(o.IDAuthority, p.Author.When.UTC().Year(), p.Hash.String(), name, idKinds[kind])
+		if ids[id]++; ids[id] > 1 {
+			id = fmt.Sprintf("%s/%d", id, ids[id])
+		}
+
+		return id
+	}
+
+	if o.Verbose && o.Dates != "absolute" {
+		log.Printf("relative dates are as of the latest commit at %s", head.Committer.When.Format(time.RFC3339))
+	}
+
+	// the first parent chain of the head, only walked when only it is followed
+	var mainline map[plumbing.Hash]bool
+	if o.Merges == "first-parent" && o.Compat == "" {
+		if mainline, err = firstParents(head); err != nil {
+			return nil, fmt.Errorf("failed to follow first parents: %v", err)
+		}
+	}
+
+	// the tiered mode walks the history below the full window in monthly chunks, the window
+	// itself the same way as every other run
+	var chunked *tiers
+	if o.Tiered {
+		if chunked, err = splitTiers(commits[:start+1], start, head, o.FullWindow, mainline != nil); err != nil {
+			return nil, fmt.Errorf("failed to split the history into tiers: %v", err)
+		}
+		if chunked != nil {
+			if o.Verbose {
+				log.Printf("tiered: commits down to %s one by one, %d monthly chunks below", chunked.window.Hash, len(chunked.bases))
+			}
+			commits, start = append(chunked.commits, commits[start+1:]...), chunked.start
+			col.provenance.Tiered = chunked.window.Hash.String()
+		}
+	}
+
+	for n := start; n >= 0; n-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c := commits[n]
+
+		// skip initial commit in this project as it happens to have no relevant content
+		if n == 0 {
+			break
+		}
+
+		p := commits[n-1]
+
+		// a commit is compared to its parents, a merge to all of them unless only the first parent
+		// chain is followed; the first releases compared neighbours in the log, which mixes up
+		// branches worked on at the same time
+		bases := []*object.Commit{c}
+		if o.Compat == "" {
+			if mainline != nil && !mainline[p.Hash] {
+				if o.Verbose {
+					log.Printf("===> commit: %s skipped: not on the first parent chain", p.Hash)
+				}
+				continue
+			}
+			if bases, err = parents(p, mainline != nil); err != nil {
+				return nil, fmt.Errorf("failed to get parents: %s: %v", p.Hash, err)
+			}
+			// a chunk of the tiered mode is compared with the end of the chunk before
+			if base := chunked.base(p.Hash); base != nil {
+				bases = []*object.Commit{base}
+			}
+			// another root commit starts its own history, like the initial one
+			if len(bases) == 0 {
+				if o.Verbose {
+					log.Printf("===> commit: %s skipped: root commit", p.Hash)
+				}
+				continue
+			}
+			c = bases[0]
+		}
+
+		// a commit leaving the list files as they are in one of its parents, like an empty commit
+		// or a merge bringing in changes seen before, has nothing to offer and is skipped before
+		// the costly patch
+		if o.Compat == "" && sameAsAny(bases, p, watched) {
+			if o.Verbose {
+				log.Printf("===> commit: %s skipped: list files as in a parent", p.Hash)
+			}
+			if o.Step != nil && !o.Step(&Step{Parent: c, Commit: p, Meta: col.meta}) {
+				break
+			}
+			continue
+		}
+
+		if o.Verbose {
+			log.Printf("===> commit: %s by %s at %s: %s", p.Hash, p.Author.Name, formatDate(p.Author.When, head.Committer.When, o.Dates), p.Message)
+		}
+
+		done := timer.start("extract")
+		patches, err := diffPatches(bases, p, o.MaxPatchSize)
+		if err != nil {
+			if o.Strict {
+				return nil, fmt.Errorf("failed to get patch: %s: %v", p.Hash, err)
+			}
+			// one broken commit must not keep the feeds from being refreshed
+			col.warnings.warn("skipped-commit", "skipping commit %s: %v", p.Hash, err)
+			col.skipped = append(col.skipped, fmt.Sprintf("%s: %v", p.Hash, err))
+			done(1)
+			continue
+		}
+		patch := patches[0]
+
+		var stats patchStats
+		if o.Verbose {
+			if o.Compat != "" {
+				stats = countPatch(patch, nil)
+			} else {
+				stats = countPatch(patch, workfiles)
+			}
+		}
+
+		// entries are looked for in the changed lines of the list files only, never of other
+		// files in the commit; the first releases looked at the whole patch
+		var matches [][]string
+		var difflines []string
+		var gaps []*gap
+		if o.Compat != "" {
+			matches = extractMatches(patch.String(), o.MaxLineLength, false, "", col.warnings)
+			stats.matches = len(matches)
+		} else {
+			for _, f := range workfiles {
+				lines := workfileLines(patch, f)
+
+				// a list file leaving is no removal of all of its entries, and coming back it is
+				// compared with the version last seen, as if it had never been gone
+				if g := findGap(c, p, patch, f); g != nil {
+					gaps = append(gaps, g)
+					if !g.back {
+						col.gaps[f] = c.Hash
+						lines = nil
+					} else if h, found := col.gaps[f]; found {
+						since, err := r.CommitObject(h)
+						if err == nil {
+							lines, err = gapLines(since, p, f)
+						}
+						if err != nil {
+							return nil, fmt.Errorf("failed to compare with last seen version: %s: %v", f, err)
+						}
+						delete(col.gaps, f)
+					}
+				}
+
+				for _, m := range extractMatches(strings.Join(FoldDefinitions(lines), "\n"), o.MaxLineLength, true, f, col.warnings) {
+					if len(workfiles) > 1 {
+						m = append(m, f)
+					}
+					matches = append(matches, m)
+				}
+				if o.IncludeDiff {
+					difflines = append(difflines, redactURLs(lines, o.schemes)...)
+				}
+			}
+
+			stats.matches = len(matches)
+
+			// a merge only brings news where it differs from all of its parents
+			for _, other := range patches[1:] {
+				matches = commonMatches(matches, other, workfiles)
+			}
+			stats.merged = stats.matches - len(matches)
+		}
+		done(1)
+
+		done = timer.start("group")
+		items := len(feed.Items)
+
+		// drop excluded entries before anything else looks at them
+		kept := matches[:0]
+		for _, m := range matches {
+			if exclude.excluded(m[2], m[3]) {
+				col.excluded++
+				stats.excluded++
+				continue
+			}
+			kept = append(kept, m)
+		}
+		matches = kept
+
+		// filter out moving items around: a plus and a minus cancel each other out
+		changes := make(map[string]int)
+		for _, m := range matches {
+			x := 1
+			if m[1] == "-" {
+				x = -1
+			}
+
+			v, found := changes[m[2]]
+			if !found {
+				v = x
+			} else {
+				v += x
+			}
+
+			changes[m[2]] = v
+		}
+
+		registered := len(col.registry.entries)
+		col.registry.observe(matches, changes, p)
+		if chunked.base(p.Hash) != nil {
+			if err := chunked.refine(p.Hash, col.registry.entries[registered:], matches, workfiles); err != nil {
+				return nil, fmt.Errorf("failed to date entries of a chunk: %s: %v", p.Hash, err)
+			}
+		}
+		recordHistory(col.history, col.registry, matches, changes, p)
+
+		// entries of the file before and after the commit, only loaded when needed
+		var before, after []entry
+
+		// a new url or description of an entry is an update, earlier releases had no updates
+		var updates map[string]update
+		if o.Compat == "" {
+			updates = updatedEntries(matches, changes)
+		}
+
+		if o.Verbose {
+			for name, v := range changes {
+				if _, found := updates[name]; v == 0 && !found {
+					stats.moves++
+				}
+			}
+			log.Printf("patch: %v", stats)
+			log.Printf("changes: %v", changes)
+		}
+
+		for n, m := range matches {
+			t := "Addition"
+			if m[1] == "-" {
+				t = "Removal"
+			}
+
+			// skip when there was only a move of an entry
+			// safe to access without check due to full iteration in previous loop
+			if changes[m[2]] == 0 {
+				u, found := updates[m[2]]
+				if !found {
+					if o.Verbose && m[1] == "+" {
+						log.Printf("=====>> Move: %s -- unchanged", m[2])
+					}
+					continue
+				}
+				if u.index != n {
+					continue
+				}
+				t = "Update"
+			}
+
+			if o.Verbose {
+				log.Printf("=====>> %s: %s -- %s -- %s", t, m[2], m[3], m[4])
+				if u, found := updates[m[2]]; found && t == "Update" {
+					log.Printf("previously: %s -- %s", u.old[3], u.old[4])
+				}
+				if isHTMLEntry(m) {
+					log.Printf("found as html list item: %s", m[2])
+				}
+			}
+
+			// never let links with unexpected schemes into any output
+			link := m[3]
+			flagged := !schemeAllowed(link, o.schemes)
+			if flagged {
+				if o.Compat == "" {
+					file := matchFile(m)
+					if file == "" {
+						file = workfiles[0]
+					}
+					col.warnings.warnAt("disallowed-url", file, m[0][1:], "%s of %s in %s has a disallowed url scheme: %q", t, m[2], p.Hash, link)
+				} else {
+					col.warnings.warn("disallowed-url", "%s of %s in %s has a disallowed url scheme: %q", t, m[2], p.Hash, link)
+				}
+				if o.InvalidURL == "drop" {
+					continue
+				}
+				link = feed.Link.Href
+			}
+
+			// earlier releases kept descriptions as they are
+			desc, tags := m[4], []string(nil)
+			if o.Compat == "" {
+				desc, tags = splitTags(desc)
+			}
+			desc, fields := splitSuffixes(desc, suffixes)
+
+			// the commit message may say what the entry does not, attributed as such
+			source := ""
+			if (o.CommitBody && desc == "") || o.PreferCommitBody {
+				if body := commitBody(p.Message); body != "" {
+					desc, source = "maintainer's note: "+body, "commit"
+				}
+			}
+
+			item := &feeds.Item{
+				Id:          newID(p, m[2], t),
+				Title:       fmt.Sprintf("%s of %s", t, m[2]),
+				Link:        &feeds.Link{Href: link},
+				Description: desc,
+				Author:      &feeds.Author{Name: p.Author.Name},
+				Created:     p.Author.When,
+			}
+			if flagged {
+				item.Description += " (link removed: disallowed url scheme)"
+			}
+			feed.Items = append(feed.Items, item)
+
+			im := newItemMeta(o, p, t, m[2])
+			im.EntryID, im.Tags, im.Fields, im.File, im.DescriptionSource = col.registry.id(m[2]), tags, fields, matchFile(m), source
+
+			if o.Context || o.sections || o.Positions {
+				// additions and updates are found in the new file, removals in the old one
+				entries, verb := &after, "Added to"
+				from := p
+				if t == "Update" {
+					verb = "Updated in"
+				} else if m[1] == "-" {
+					entries, verb = &before, "Removed from"
+					from = c
+				}
+				if *entries == nil {
+					*entries, err = currentEntries(from, workfiles)
+					if err != nil {
+						return nil, fmt.Errorf("failed to parse entries: %s: %v", from.Hash, err)
+					}
+				}
+
+				if section, prev, next, found := neighbors(*entries, m[2]); found {
+					im.Section = section
+					if o.SectionCategories {
+						im.Category = section
+					}
+					if o.Positions {
+						im.Position, im.SectionTotal = position(*entries, m[2])
+					}
+
+					if o.Context {
+						item.Description += " " + contextSentence(verb, section, prev, next)
+						for _, e := range []*entry{prev, next} {
+							if e != nil {
+								im.Neighbors = append(im.Neighbors, e.Name)
+							}
+						}
+					}
+				}
+			}
+
+			if o.IncludeDiff && !flagged {
+				if d := entryDiff(difflines, m[1], m[2], o.DiffLimit); d != "" {
+					item.Content = fmt.Sprintf("<p>%s</p>\n<pre>%s</pre>", html.EscapeString(item.Description), html.EscapeString(d))
+					im.Diff = d
+				}
+			}
+			// the change itself is what the item links to, the entry is linked from the content
+			if o.RepoURL != "" {
+				if !flagged {
+					item.Content = entryContent(item.Description, m[2], link, im.Diff)
+				}
+				item.Link = &feeds.Link{Href: commitURL(o.RepoURL, p.Hash.String())}
+			}
+			col.meta[item] = im
+
+			feed.Updated = p.Author.When
+		}
+
+		// moves within a section stay silent, moves between sections are news to readers following one
+		if o.CategoryMoves {
+			for _, m := range movedEntries(matches, changes) {
+				if before == nil {
+					if before, err = currentEntries(c, workfiles); err != nil {
+						return nil, fmt.Errorf("failed to parse entries: %s: %v", c.Hash, err)
+					}
+				}
+				if after == nil {
+					if after, err = currentEntries(p, workfiles); err != nil {
+						return nil, fmt.Errorf("failed to parse entries: %s: %v", p.Hash, err)
+					}
+				}
+
+				from, to := sectionOf(before, m[2]), sectionOf(after, m[2])
+				if from == "" || to == "" || from == to || !schemeAllowed(m[3], o.schemes) {
+					continue
+				}
+
+				if o.Verbose {
+					log.Printf("=====>> Move: %s -- %s -> %s", m[2], from, to)
+				}
+
+				desc, tags := splitTags(m[4])
+				desc, fields := splitSuffixes(desc, suffixes)
+
+				item := &feeds.Item{
+					Id:          newID(p, m[2], "Move"),
+					Title:       fmt.Sprintf("Moved %s from %s to %s", m[2], from, to),
+					Link:        &feeds.Link{Href: m[3]},
+					Description: desc,
+					Author:      &feeds.Author{Name: p.Author.Name},
+					Created:     p.Author.When,
+				}
+				if o.RepoURL != "" {
+					item.Content = entryContent(item.Description, m[2], m[3], "")
+					item.Link = &feeds.Link{Href: commitURL(o.RepoURL, p.Hash.String())}
+				}
+				feed.Items = append(feed.Items, item)
+
+				im := newItemMeta(o, p, "Move", m[2])
+				im.EntryID, im.Section, im.Tags, im.File = col.registry.id(m[2]), to, tags, matchFile(m)
+				im.Fields = append([]field{{Name: "from_category", Value: from}, {Name: "to_category", Value: to}}, fields...)
+				if o.SectionCategories {
+					im.Category = to
+				}
+				col.meta[item] = im
+
+				feed.Updated = p.Author.When
+			}
+		}
+
+		// announcements from meta files have their own lines and never mix with the entries
+		for _, mf := range o.metaFiles {
+			mms, err := mf.matches(patches)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate meta item: %s: %v", p.Hash, err)
+			}
+			for _, mm := range mms {
+				if o.Verbose {
+					log.Printf("=====>> Meta: %s -- %s", mm.title, mm.line)
+				}
+
+				link := o.Link
+				if o.RepoURL != "" {
+					link = commitURL(o.RepoURL, p.Hash.String())
+				}
+				item := &feeds.Item{
+					Id:          newID(p, mm.title, "Meta"),
+					Title:       mm.title,
+					Link:        &feeds.Link{Href: link},
+					Description: mm.line,
+					Author:      &feeds.Author{Name: p.Author.Name},
+					Created:     p.Author.When,
+				}
+				feed.Items = append(feed.Items, item)
+
+				im := newItemMeta(o, p, "Meta", mm.title)
+				im.Category = mf.path
+				col.meta[item] = im
+
+				feed.Updated = p.Author.When
+			}
+		}
+		// a list file leaving or coming back is one item at most, however many entries it has
+		for _, g := range gaps {
+			// a move between list files is told by the file it left
+			between := false
+			for _, f := range workfiles {
+				if g.back && f == g.other {
+					between = true
+				}
+			}
+			if between {
+				continue
+			}
+			if o.Verbose {
+				log.Printf("=====>> Restructure: %s", g.title())
+			}
+			if o.Restructure == "suppress" {
+				continue
+			}
+
+			link := o.Link
+			if o.RepoURL != "" {
+				link = commitURL(o.RepoURL, p.Hash.String())
+			}
+			item := &feeds.Item{
+				Id:          newID(p, g.file, "Restructure"),
+				Title:       g.title(),
+				Link:        &feeds.Link{Href: link},
+				Description: strings.TrimSpace(strings.SplitN(p.Message, "\n", 2)[0]),
+				Author:      &feeds.Author{Name: p.Author.Name},
+				Created:     p.Author.When,
+			}
+			feed.Items = append(feed.Items, item)
+
+			im := newItemMeta(o, p, "Restructure", g.file)
+			im.Category = g.file
+			col.meta[item] = im
+
+			feed.Updated = p.Author.When
+		}
+		done(len(feed.Items) - items)
+
+		if o.Step != nil && !o.Step(&Step{Parent: c, Commit: p, Matches: matches, Changes: changes, Items: feed.Items[items:], Meta: col.meta}) {
+			break
+		}
+	}
+
+	// scrubbed entries are gone from everything, the state of earlier runs included
+	if col.scrub, err = loadScrubList(head, o.URLIdentity); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", scrubFile, err)
+	}
+	gone, scrubbed := scrub(col, col.scrub)
+	col.scrubbed = scrubbed
+	if o.Verbose && len(gone) > 0 {
+		log.Printf("scrubbed entries: %s", strings.Join(gone, ", "))
+	}
+
+	if o.EventLog != "" {
+		col.changes = append([]*feeds.Item{}, col.feed.Items...)
+	}
+
+	if o.StateFile != "" {
+		if col.state, err = snapshot(o, col, rcfg); err != nil {
+			return nil, fmt.Errorf("failed to generate state: %v", err)
+		}
+	}
+
+	// a wrong clock of a contributor must not push the feed into the future; the newest commit
+	// is only trusted as long as it is not in the future itself, earlier releases kept all dates
+	newest := head.Committer.When
+	for _, c := range commits {
+		if c.Committer.When.After(newest) {
+			newest = c.Committer.When
+		}
+	}
+	if now := time.Now(); newest.After(now) {
+		newest = now
+	}
+	if o.Compat == "" {
+		clampFutureDates(feed, col.meta, newest, o.FutureSkew, o.FutureDates, col.warnings)
+	}
+
+	// fix-ups landed right after a change are one change to readers
+	if o.SquashWindow > 0 {
+		squashed := squashChanges(feed, col.meta, o.SquashWindow, o.Context)
+		if o.Verbose {
+			log.Printf("squash window dropped %d items", squashed)
+		}
+	}
+
+	if o.Sections != "" {
+		restrictSections(feed, col.meta, splitList(o.Sections))
+	}
+
+	if o.GroupBy == "pr" {
+		tmpl := o.PRURLTemplate
+		if tmpl == "" {
+			tmpl = prURLTemplate(r)
+		}
+		prs, err := pullRequests(r, ref.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to find pull requests: %v", err)
+		}
+		groupByPullRequest(feed, col.meta, prs, tmpl, o.IDAuthority)
+	}
+
+	// the state above still holds everything, so later runs can prune differently
+	if o.MaxItems > 0 || o.Since != "" {
+		since := o.sinceDate
+		if o.sinceAge > 0 {
+			since = newest.Add(-o.sinceAge)
+		}
+		pruned := limitItems(feed, o.MaxItems, since)
+		if o.Verbose {
+			log.Printf("pruned %d items, %d left", pruned, len(feed.Items))
+		}
+	}
+
+	// commit metadata and list content end up in every output as is otherwise
+	sanitizeFeed(feed, col.meta)
+
+	return col, nil
+}
@@ -0,0 +1,104 @@
+package feedgen_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"awesome-veganism-feed/feedgen"
+	"awesome-veganism-feed/feedgentest"
+)
+
+// history adds two entries and removes one of them again
+func history() []feedgentest.Snapshot {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	list := func(lines ...string) map[string][]byte {
+		return map[string][]byte{"README.md": feedgentest.File("# Awesome Veganism\n\n## Food\n\n" + strings.Join(lines, "\n") + "\n")}
+	}
+
+	return []feedgentest.Snapshot{
+		{Files: list(), Author: "Alice", When: start, Message: "Start the list"},
+		{Files: list("- [Tofu Town](https://tofu.example/) - Tofu in every shape."), Author: "Bob", When: start.AddDate(0, 0, 1), Message: "Add Tofu Town"},
+		{Files: list("- [Tofu Town](https://tofu.example/) - Tofu in every shape.", "- [Oat Dream](https://oat.example/) - Oat milk."), Author: "Carol", When: start.AddDate(0, 0, 2), Message: "Add Oat Dream"},
+		{Files: list("- [Oat Dream](https://oat.example/) - Oat milk."), Author: "Alice", When: start.AddDate(0, 0, 3), Message: "Remove Tofu Town"},
+	}
+}
+
+func TestGenerateFeed(t *testing.T) {
+	r, err := feedgentest.NewRepository(history()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := feedgen.NewConfig()
+	cfg.Repository = r
+	cfg.Destdir = t.TempDir()
+	cfg.RegistryFile = "registry.json"
+	cfg.SectionCategories = true
+
+	res, err := feedgen.GenerateFeed(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	for _, item := range res.Feed.Items {
+		titles = append(titles, item.Title)
+		if m := res.Meta[item]; m == nil || m.Category != "Food" {
+			t.Errorf("%s: meta %+v, want category Food", item.Title, m)
+		}
+	}
+	if got, want := strings.Join(titles, ", "), "Addition of Tofu Town, Addition of Oat Dream, Removal of Tofu Town"; got != want {
+		t.Errorf("items %s, want %s", got, want)
+	}
+	if len(res.Skipped) > 0 {
+		t.Errorf("skipped %v", res.Skipped)
+	}
+
+	files := feedgentest.ReadTree(t, cfg.Destdir)
+	for _, name := range []string{"feed.xml", "feed.json", "feed.rss", "registry.json"} {
+		if files[name] == nil {
+			t.Errorf("%s not written", name)
+		}
+	}
+	if !strings.Contains(string(files["feed.xml"]), "<title>Removal of Tofu Town</title>") {
+		t.Errorf("removal missing from the atom feed:\n%s", files["feed.xml"])
+	}
+
+	// the result renders like the published feed
+	atom, err := res.Atom("/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atom != string(files["feed.xml"]) {
+		t.Errorf("atom of the result differs from the published feed: %s", feedgentest.FirstDifference([]byte(atom), files["feed.xml"]))
+	}
+}
+
+func TestGenerateFeedErrors(t *testing.T) {
+	r, err := feedgentest.NewRepository(history()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := feedgen.NewConfig()
+	cfg.Repository = r
+	cfg.Destdir = t.TempDir()
+	cfg.GroupBy = "commit"
+	if _, err := feedgen.GenerateFeed(context.Background(), cfg); err == nil || err.Error() != "invalid -group-by: commit" {
+		t.Errorf("invalid setting: %v", err)
+	}
+
+	// a run given up publishes nothing
+	cfg.GroupBy = "entry"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := feedgen.GenerateFeed(ctx, cfg); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled run: %v", err)
+	}
+	if files := feedgentest.ReadTree(t, cfg.Destdir); len(files) > 0 {
+		t.Errorf("cancelled run wrote %d files", len(files))
+	}
+}
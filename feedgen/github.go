@@ -0,0 +1,128 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// api of github
+const githubAPI = "https://api.github.com"
+
+// githubMeta is the part of the github repository metadata the feeds default to
+type githubMeta struct {
+	Description string   `json:"description"`
+	Homepage    string   `json:"homepage"`
+	Topics      []string `json:"topics"`
+}
+
+// githubCache is a response kept between runs, sent back as etag so unchanged metadata
+// costs no rate limit
+type githubCache struct {
+	ETag string      `json:"etag"`
+	Meta *githubMeta `json:"meta"`
+}
+
+// fetchGithubMeta reads the metadata of a repository given as owner/repo, revalidating the
+// cached response when there is one; GITHUB_TOKEN is sent when set
+func fetchGithubMeta(client *http.Client, repo string) (*githubMeta, error) {
+	cache := &githubCache{}
+	path := ""
+	if dir, err := os.UserCacheDir(); err == nil {
+		path = filepath.Join(dir, "awesome-veganism-feed", "github", strings.ReplaceAll(repo, "/", "_")+".json")
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, cache)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, githubAPI+"/repos/"+repo, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if cache.ETag != "" && cache.Meta != nil {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		if cache.Meta != nil {
+			return cache.Meta, nil
+		}
+	case http.StatusOK:
+		meta := &githubMeta{}
+		if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(meta); err != nil {
+			return nil, err
+		}
+
+		// a cache that cannot be written only costs another full request next time
+		if path != "" && res.Header.Get("ETag") != "" {
+			if data, err := json.Marshal(&githubCache{ETag: res.Header.Get("ETag"), Meta: meta}); err == nil {
+				if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+					os.WriteFile(path, data, 0644)
+				}
+			}
+		}
+
+		return meta, nil
+	}
+
+	return nil, fmt.Errorf("unexpected response: %s", res.Status)
+}
+
+// applyGithubMeta defaults the description and link of the feeds to those of the github
+// repository of the origin remote and takes its topics as categories of the feeds; settings
+// given explicitly win, and without metadata everything stays as it is
+func applyGithubMeta(o *options, r *git.Repository) {
+	host, repo := OriginRepo(r)
+	if host != "github.com" {
+		if o.Verbose {
+			log.Printf("github metadata: origin is not on github")
+		}
+		return
+	}
+
+	meta, err := fetchGithubMeta(o.client, repo)
+	if err != nil {
+		if o.Verbose {
+			log.Printf("github metadata: %s: %v", repo, err)
+		}
+		return
+	}
+
+	if meta.Description != "" && !o.explicit["description"] {
+		o.Description = meta.Description
+	}
+	if meta.Homepage != "" && !o.explicit["link"] {
+		// the homepage becomes the link of the feeds and the authority of their ids, which
+		// needs an absolute web url
+		if u, err := url.Parse(meta.Homepage); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			if o.Verbose {
+				log.Printf("github metadata: %s: ignoring homepage, not an absolute http(s) url: %s", repo, meta.Homepage)
+			}
+		} else {
+			o.Link = meta.Homepage
+			if !o.explicit["id-authority"] {
+				o.IDAuthority = u.Hostname()
+			}
+		}
+	}
+	o.topics = meta.Topics
+}
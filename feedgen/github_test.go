@@ -0,0 +1,106 @@
+package feedgen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"awesome-veganism-feed/feedgentest"
+
+	"github.com/go-git/go-git/v5/config"
+)
+
+// redirectTransport sends every request to the test server instead of the host it names
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// githubServer answers the repository metadata request with the given homepage
+func githubServer(t *testing.T, homepage string) *http.Client {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/veganism/list" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"description":"Vegan things","homepage":"` + homepage + `","topics":["vegan"]}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	target, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &http.Client{Transport: &redirectTransport{target: target}}
+}
+
+func TestGithubHomepageSetsIDAuthority(t *testing.T) {
+	// keep the response cache of the test runs out of the cache of the user
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name     string
+		homepage string
+		args     []string
+		link     string
+		ids      string
+	}{
+		{"homepage", "https://vegan.example.org/list", nil, "https://vegan.example.org/list", "tag:vegan.example.org,"},
+		{"explicit authority", "https://vegan.example.org/list", []string{"-id-authority", "ids.example"}, "https://vegan.example.org/list", "tag:ids.example,"},
+		{"explicit link", "https://vegan.example.org/list", []string{"-link", "https://list.example/"}, "https://list.example/", "tag:list.example,"},
+		{"relative homepage", "/list", nil, defaultLink, "tag:awesome-veganism.com,"},
+		{"other scheme", "ftp://vegan.example.org/list", nil, defaultLink, "tag:awesome-veganism.com,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := feedgentest.NewRepository(goldenHistory()...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/veganism/list.git"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			o := parseArgs(t, append([]string{"-destdir", dir, "-github-meta"}, tt.args...))
+			o.client = githubServer(t, tt.homepage)
+
+			col, err := collectRepo(context.Background(), o, r, o.workfile())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := publish(o, col); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Link != tt.link {
+				t.Errorf("link %q, want %q", o.Link, tt.link)
+			}
+
+			files := feedgentest.ReadTree(t, dir)
+			atom := string(files["feed.xml"])
+			if !strings.Contains(atom, "<id>"+tt.ids) {
+				t.Fatalf("no ids minted under %s:\n%s", tt.ids, atom)
+			}
+			if n := strings.Count(atom, "<id>tag:"); n != strings.Count(atom, "<id>"+tt.ids) {
+				t.Errorf("%d of the ids not minted under %s:\n%s", n-strings.Count(atom, "<id>"+tt.ids), tt.ids, atom)
+			}
+		})
+	}
+}
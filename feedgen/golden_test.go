@@ -0,0 +1,163 @@
+package feedgen
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "write the generated files as the new golden files")
+
+// generate runs the generator with the given flags over a repository built from the
+// snapshots and returns the files it wrote by their path below the destination directory
+func generate(t *testing.T, args []string, snapshots ...feedgentest.Snapshot) map[string][]byte {
+	t.Helper()
+
+	return generateInto(t, t.TempDir(), args, snapshots...)
+}
+
+// generateInto is generate with a given destination directory, for runs continuing an earlier one
+func generateInto(t *testing.T, dir string, args []string, snapshots ...feedgentest.Snapshot) map[string][]byte {
+	t.Helper()
+
+	r, err := feedgentest.NewRepository(snapshots...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := parseArgs(t, append([]string{"-destdir", dir}, args...))
+	workfile := o.workfile()
+	if o.Files != "" {
+		workfile = ""
+	}
+	col, err := collectRepo(context.Background(), o, r, workfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publish(o, col); err != nil {
+		t.Fatal(err)
+	}
+
+	return feedgentest.ReadTree(t, dir)
+}
+
+// parseArgs parses the flags of a run into its options
+func parseArgs(t *testing.T, args []string) *options {
+	t.Helper()
+
+	var cfg Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	o, err := newOptions(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return o
+}
+
+// checkGolden compares the files with the ones in testdata/golden/name, or replaces those
+// when run with -update-golden
+func checkGolden(t *testing.T, name string, files map[string][]byte) {
+	t.Helper()
+
+	feedgentest.CheckGolden(t, filepath.Join("testdata", "golden", name), files, *updateGolden)
+}
+
+// listSnapshot renders a list with the given sections as the README of one commit; sections
+// are given as heading followed by its entries, a heading being any line without bullet
+func listSnapshot(author string, day int, message string, lines ...string) feedgentest.Snapshot {
+	var b strings.Builder
+	b.WriteString("# Awesome Veganism\n\nA curated list.\n")
+	for _, l := range lines {
+		if strings.HasPrefix(l, "- ") {
+			b.WriteString(l + "\n")
+		} else {
+			fmt.Fprintf(&b, "\n## %s\n\n", l)
+		}
+	}
+
+	return feedgentest.Snapshot{
+		Files:   map[string][]byte{"README.md": feedgentest.File(b.String())},
+		Author:  author,
+		Email:   strings.ToLower(strings.Fields(author)[0]) + "@example.org",
+		When:    time.Date(2024, time.March, day, 9, 30, 0, 0, time.UTC),
+		Message: message,
+	}
+}
+
+// goldenHistory has additions, removals, an update, a merge of two branches, moves within
+// and between sections and non-ascii names and descriptions
+func goldenHistory() []feedgentest.Snapshot {
+	const (
+		tofu   = "- [Tofu Town](https://tofu.example/) - All things tofu."
+		oat    = "- [Oat Dream](https://oat.example/) - Oat milk for coffee."
+		oat2   = "- [Oat Dream](https://oatdream.example/) - Oat milk for coffee."
+		shoes  = "- [Vegan Shoes](https://shoes.example/) - Shoes without leather."
+		cafe   = "- [Café Végétal](https://café.example/crème) - Crème brûlée ohne Ei – 100 % pflanzlich."
+		seitan = "- [Seitan Co](https://seitan.example/) - Wheat based meats <3 & more."
+		bags   = "- [Сумки](https://bags.example/) - Рюкзаки без кожи."
+	)
+
+	s := []feedgentest.Snapshot{
+		listSnapshot("Alice", 1, "Start the list", "Food", tofu, oat, "Fashion", shoes),
+		listSnapshot("Bob", 2, "Add Café Végétal", "Food", tofu, cafe, oat, "Fashion", shoes),
+		listSnapshot("Alice", 3, "Update Oat Dream url", "Food", tofu, cafe, oat2, "Fashion", shoes),
+		listSnapshot("Chloé Dupont", 4, "Add Seitan Co", "Food", tofu, cafe, oat2, seitan, "Fashion", shoes),
+		listSnapshot("Bob", 5, "Remove Vegan Shoes", "Food", tofu, cafe, oat2, "Fashion"),
+		listSnapshot("Alice", 6, "Merge branch 'seitan'", "Food", tofu, cafe, oat2, seitan, "Fashion"),
+		listSnapshot("Дмитрий", 7, "Add bags and sort food", "Food", cafe, oat2, seitan, tofu, "Fashion", bags),
+		listSnapshot("Alice", 8, "Café Végétal sells shoes now", "Food", oat2, seitan, tofu, "Fashion", bags, cafe),
+	}
+	// the removal is on a branch off the update, merged together with the addition
+	s[4].Parents = []int{2}
+	s[5].Parents = []int{3, 4}
+
+	return s
+}
+
+// blankLineHistory ends the golden history with a blank line added below a heading, where
+// the expression of the first release takes the entry below it for an addition
+func blankLineHistory() []feedgentest.Snapshot {
+	s := goldenHistory()
+	last := s[len(s)-1]
+	readme := strings.Replace(string(last.Files["README.md"]), "## Food\n\n", "## Food\n\n\n", 1)
+
+	return append(s, feedgentest.Snapshot{
+		Files:   map[string][]byte{"README.md": feedgentest.File(readme)},
+		Author:  "Bob",
+		Email:   "bob@example.org",
+		When:    time.Date(2024, time.March, 9, 9, 30, 0, 0, time.UTC),
+		Message: "Space out the food section",
+	})
+}
+
+func TestGoldenFeeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		history []feedgentest.Snapshot
+	}{
+		{"default", nil, goldenHistory()},
+		{"sections", []string{"-section-categories", "-category-move-items", "-positions"}, goldenHistory()},
+		// captured from the first release, which -compat v1 has to reproduce byte for byte
+		{"compat-v1", []string{"-compat", "v1"}, goldenHistory()},
+		{"compat-v1-stylesheet", []string{"-compat", "v1", "-stylesheet", "feed.xsl"}, goldenHistory()},
+		{"compat-v1-blank-line", []string{"-compat", "v1"}, blankLineHistory()},
+		{"compat-feed", []string{"-compat-feed", "legacy.xml", "-stylesheet", "feed.xsl"}, goldenHistory()},
+	}
+
+	for _, tt := range tests {
+		files := generate(t, tt.args, tt.history...)
+		checkGolden(t, tt.name, files)
+	}
+}
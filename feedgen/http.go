@@ -0,0 +1,56 @@
+package feedgen
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// Version of the tool, set at build time with -ldflags "-X awesome-veganism-feed/feedgen.Version=..."
+var Version = "dev"
+
+// home of the tool, referenced in the user agent
+const repoURL = "https://github.com/sdassow/awesome-veganism-feed"
+
+func DefaultUserAgent() string {
+	return fmt.Sprintf("awesome-veganism-feed/%s (+%s)", Version, repoURL)
+}
+
+// headerTransport adds identifying headers to every outgoing request
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	from      string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// round trippers must not modify the original request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	if t.from != "" {
+		req.Header.Set("From", t.from)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// newHTTPClient returns the client to use for all outbound requests
+func NewHTTPClient(userAgent string, from string) *http.Client {
+	return &http.Client{
+		Timeout: time.Minute,
+		Transport: &headerTransport{
+			base:      http.DefaultTransport,
+			userAgent: userAgent,
+			from:      from,
+		},
+	}
+}
+
+// installGitTransport makes go-git use the given client for http remotes
+func InstallGitTransport(c *http.Client) {
+	client.InstallProtocol("http", githttp.NewClient(c))
+	client.InstallProtocol("https", githttp.NewClient(c))
+}
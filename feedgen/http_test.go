@@ -0,0 +1,60 @@
+package feedgen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+func TestOutboundRequestsIdentify(t *testing.T) {
+	var mu sync.Mutex
+	var seen []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Clone())
+		mu.Unlock()
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	o := parseArgs(t, []string{"-destdir", t.TempDir(), "-contact-email", "feeds@example.org"})
+
+	// requests of the tool itself
+	res, err := o.client.Get(ts.URL + "/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	// and those go-git makes for http remotes
+	r, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm, err := r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{ts.URL + "/list.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rm.List(&git.ListOptions{}); err == nil {
+		t.Fatal("listing a missing remote succeeded")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 {
+		t.Fatalf("got %d requests, want 2", len(seen))
+	}
+	for n, h := range seen {
+		if got, want := h.Get("User-Agent"), DefaultUserAgent(); got != want {
+			t.Errorf("request %d: user agent %q, want %q", n, got, want)
+		}
+		if got, want := h.Get("From"), "feeds@example.org"; got != want {
+			t.Errorf("request %d: from %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package feedgen
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// InsideDir reports whether path is dir or below it, after resolving symlinks
+func InsideDir(dir string, path string) bool {
+	d, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	p, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(d, p)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// generatedPaths lists where the outputs of a run end up inside the repository at workdir,
+// relative to its root: the destination directory as a whole unless it is the root itself,
+// otherwise the files written into it, including the manifest, the headers snippet and the
+// files of a theme
+func generatedPaths(o *options) []string {
+	if o.Sink.Output != "fs" || !InsideDir(o.Workdir, o.Destdir) {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(o.Workdir)
+	if err != nil {
+		return nil
+	}
+	dest, err := filepath.EvalSymlinks(o.Destdir)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(root, dest)
+	if err != nil {
+		return nil
+	}
+	if rel != "." {
+		return []string{filepath.ToSlash(rel)}
+	}
+
+	names := outputNames(o)
+	if o.theme != nil {
+		for _, name := range o.theme.files(o.Stylesheet) {
+			names = append(names, path.Clean(filepath.ToSlash(name)))
+		}
+	}
+
+	return names
+}
+
+// outputNames lists the slash separated names of the files a run writes into destdir, besides
+// those of a theme
+func outputNames(o *options) []string {
+	names := []string{
+		o.AtomFile, o.JSONFile, o.RSSFile, o.StaleFile, o.StateFile, o.TimeseriesFile,
+		o.MaintainerFile, o.MinimalFile, o.LegacyFile, o.BadgeFile, o.BadgeSVGFile,
+		o.RegistryFile, o.RemovedPage, o.RemovedJSON, headerFiles[o.Sink.HeadersFile],
+	}
+	if o.Sink.Manifest {
+		names = append(names, manifestFile)
+	}
+	for _, s := range o.FilterFeeds {
+		_, file, _ := strings.Cut(s, "=")
+		names = append(names, file)
+	}
+
+	var paths []string
+	for _, name := range names {
+		if name != "" {
+			paths = append(paths, path.Clean(filepath.ToSlash(name)))
+		}
+	}
+
+	return paths
+}
+
+// gitExcludeOutputs adds the given files below workdir to the repository's info/exclude,
+// so generated files never show up as untracked changes
+func gitExcludeOutputs(workdir string, files []string) error {
+	gitdir := filepath.Join(workdir, ".git")
+	if fi, err := os.Stat(gitdir); err != nil || !fi.IsDir() {
+		return errors.New("no .git directory to keep excludes in: " + gitdir)
+	}
+
+	path := filepath.Join(gitdir, "info", "exclude")
+
+	known := make(map[string]bool)
+	if f, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			known[strings.TrimSpace(sc.Text())] = true
+		}
+		f.Close()
+		if err := sc.Err(); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	root, err := filepath.EvalSymlinks(workdir)
+	if err != nil {
+		return err
+	}
+
+	var add []string
+	for _, file := range files {
+		p, err := filepath.EvalSymlinks(filepath.Dir(file))
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, filepath.Join(p, filepath.Base(file)))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		pattern := "/" + filepath.ToSlash(rel)
+		if !known[pattern] {
+			known[pattern] = true
+			add = append(add, pattern)
+		}
+	}
+	if len(add) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(strings.Join(add, "\n") + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
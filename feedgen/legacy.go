@@ -0,0 +1,99 @@
+package feedgen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// legacyFeed is an atom 1.0 feed with the required elements only, plus the summary of entries,
+// for readers that choke on anything else: no language, no self link, no generator, no
+// processing instructions and no extension namespaces
+type legacyFeed struct {
+	XMLName xml.Name       `xml:"feed"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	Id      string         `xml:"id"`
+	Title   string         `xml:"title"`
+	Updated string         `xml:"updated"`
+	Link    *legacyLink    `xml:"link,omitempty"`
+	Author  *legacyAuthor  `xml:"author,omitempty"`
+	Entries []*legacyEntry `xml:"entry"`
+}
+
+type legacyEntry struct {
+	Id      string        `xml:"id"`
+	Title   string        `xml:"title"`
+	Updated string        `xml:"updated"`
+	Link    *legacyLink   `xml:"link,omitempty"`
+	Author  *legacyAuthor `xml:"author,omitempty"`
+	Summary string        `xml:"summary,omitempty"`
+}
+
+type legacyLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type legacyAuthor struct {
+	Name string `xml:"name"`
+}
+
+// legacyDocument builds the legacy feed from the items of the main feed; entries name their
+// author only when the feed has none, as atom requires one or the other
+func legacyDocument(feed *feeds.Feed) *legacyFeed {
+	af := (&feeds.Atom{Feed: feed}).AtomFeed()
+
+	doc := &legacyFeed{Xmlns: atomNamespace, Id: af.Id, Title: af.Title, Updated: af.Updated}
+	if af.Link != nil {
+		doc.Link = &legacyLink{Href: af.Link.Href}
+	}
+	if af.Author != nil && af.Author.Name != "" {
+		doc.Author = &legacyAuthor{Name: af.Author.Name}
+	}
+
+	for _, e := range af.Entries {
+		le := &legacyEntry{Id: e.Id, Title: e.Title, Updated: e.Updated}
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				le.Link = &legacyLink{Href: l.Href}
+				break
+			}
+		}
+		if doc.Author == nil {
+			name := "unknown"
+			if e.Author != nil && e.Author.Name != "" {
+				name = e.Author.Name
+			}
+			le.Author = &legacyAuthor{Name: name}
+		}
+		if e.Summary != nil {
+			le.Summary = e.Summary.Content
+		}
+		doc.Entries = append(doc.Entries, le)
+	}
+
+	return doc
+}
+
+// marshalLegacy renders the legacy feed after a bare xml declaration with every character
+// outside of ascii written as a character reference
+func marshalLegacy(doc *legacyFeed) (string, error) {
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	for _, r := range string(data) {
+		if r < 0x80 {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "&#%d;", r)
+		}
+	}
+	b.WriteString("\n")
+
+	return b.String(), nil
+}
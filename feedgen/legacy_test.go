@@ -0,0 +1,59 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+// TestCompatFeedIsMinimal checks what the golden file of the compat feed shows, for any
+// history: plain ascii atom without processing instructions, extensions or link relations
+func TestCompatFeedIsMinimal(t *testing.T) {
+	files := generate(t, []string{"-compat-feed", "legacy.xml", "-stylesheet", "feed.xsl", "-section-categories"}, goldenHistory()...)
+	data := files["legacy.xml"]
+	if data == nil {
+		t.Fatal("legacy.xml was not written")
+	}
+
+	for n, b := range data {
+		if b >= 0x80 {
+			t.Fatalf("non-ascii byte at %d: %q", n, data[n:n+10])
+		}
+	}
+
+	allowed := map[string]bool{
+		"feed": true, "id": true, "title": true, "updated": true, "link": true,
+		"entry": true, "author": true, "name": true, "summary": true,
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.ProcInst:
+			if tok.Target != "xml" {
+				t.Errorf("processing instruction %s", tok.Target)
+			}
+		case xml.StartElement:
+			if tok.Name.Space != "http://www.w3.org/2005/Atom" || !allowed[tok.Name.Local] {
+				t.Errorf("element %s %s", tok.Name.Space, tok.Name.Local)
+			}
+			for _, a := range tok.Attr {
+				if a.Name.Local != "href" && !(tok.Name.Local == "feed" && a.Name.Local == "xmlns") {
+					t.Errorf("attribute %s:%s of %s", a.Name.Space, a.Name.Local, tok.Name.Local)
+				}
+			}
+		}
+	}
+
+	// the main feed keeps everything the compat feed leaves out
+	if !bytes.Contains(files["feed.xml"], []byte(`<?xml-stylesheet href="feed.xsl"`)) {
+		t.Error("stylesheet missing from the main feed")
+	}
+}
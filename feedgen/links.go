@@ -0,0 +1,201 @@
+package feedgen
+
+import (
+	"strings"
+)
+
+// ParseLink reads an inline markdown link [text](destination "title") at the start of s in a
+// single pass and returns the text, the destination and the rest of s after the link.
+//
+// It follows the CommonMark inline link grammar closely enough for lists, with these
+// deliberate deviations:
+//   - the text is taken literally apart from backslash escapes, nested markup is not parsed
+//   - the text must not be empty and may not span lines
+//   - no whitespace is allowed between the text and the destination
+//   - a link title in quotes or parentheses is skipped and never returned
+//   - entity and percent encoding in the destination are kept as written
+func ParseLink(s string) (text string, dest string, rest string, ok bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", "", s, false
+	}
+
+	// the text ends at the bracket balancing the opening one, escaped brackets do not count
+	var b strings.Builder
+	depth := 0
+	i := 1
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			b.WriteByte(s[i+1])
+			i++
+			continue
+		}
+		if c == '[' {
+			depth++
+		} else if c == ']' {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+		b.WriteByte(c)
+	}
+	if i >= len(s) || b.Len() == 0 || i+1 >= len(s) || s[i+1] != '(' {
+		return "", "", s, false
+	}
+	text = b.String()
+	i += 2
+
+	i = skipSpace(s, i)
+
+	// the destination is either in angle brackets or runs up to whitespace or the parenthesis
+	// balancing the opening one
+	start := i
+	if i < len(s) && s[i] == '<' {
+		for i++; i < len(s) && s[i] != '>' && s[i] != '<' && s[i] != '\n'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+		}
+		if i >= len(s) || s[i] != '>' {
+			return "", "", s, false
+		}
+		dest = s[start+1 : i]
+		i++
+	} else {
+		depth = 0
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				i++
+				continue
+			}
+			if c == ' ' || c < 0x20 {
+				break
+			}
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+		dest = s[start:i]
+	}
+	if dest == "" {
+		return "", "", s, false
+	}
+
+	end := i
+	i = skipSpace(s, i)
+
+	// an optional title follows after whitespace
+	if i > end && i < len(s) && (s[i] == '"' || s[i] == '\'' || s[i] == '(') {
+		closing := s[i]
+		if closing == '(' {
+			closing = ')'
+		}
+		for i++; i < len(s) && s[i] != closing; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+		}
+		if i >= len(s) {
+			return "", "", s, false
+		}
+		i = skipSpace(s, i+1)
+	}
+
+	if i >= len(s) || s[i] != ')' {
+		return "", "", s, false
+	}
+
+	return text, dest, s[i+1:], true
+}
+
+// bullets starting a list entry and separators between its link and description, markdown
+// allows all three bullets and lists differ in how they separate the description
+var (
+	entryBullets    = []string{"- ", "* ", "+ "}
+	entrySeparators = []string{" - ", " – ", ": "}
+)
+
+// ParseEntry reads a list entry of the form "- [name](url) - description", leading
+// whitespace allowed, with any of the bullets and separators above
+func ParseEntry(line string) (name string, url string, desc string, ok bool) {
+	s := strings.TrimLeft(line, " \t\v\f\r")
+	if !hasAnyPrefix(s, entryBullets) {
+		return "", "", "", false
+	}
+
+	name, url, rest, ok := ParseLink(s[2:])
+	if !ok || strings.Contains(rest, "\n") {
+		return "", "", "", false
+	}
+	for _, sep := range entrySeparators {
+		if strings.HasPrefix(rest, sep) && len(rest) > len(sep) {
+			return name, url, rest[len(sep):], true
+		}
+	}
+
+	return "", "", "", false
+}
+
+// ParseTitle reads the title line of a definition style entry, "- [name](url)" with nothing
+// after the link, its description is indented on the lines below
+func ParseTitle(line string) (name string, url string, ok bool) {
+	s := strings.TrimLeft(line, " \t\v\f\r")
+	if !hasAnyPrefix(s, entryBullets) {
+		return "", "", false
+	}
+
+	name, url, rest, ok := ParseLink(s[2:])
+	if !ok || strings.TrimSpace(rest) != "" {
+		return "", "", false
+	}
+
+	return name, url, true
+}
+
+// IsDescription reports whether a line continues the description of a definition style entry:
+// indented, not blank and no nested list item
+func IsDescription(line string) bool {
+	if !strings.HasPrefix(line, "  ") && !strings.HasPrefix(line, "\t") {
+		return false
+	}
+	s := strings.TrimSpace(line)
+
+	return s != "" && !hasAnyPrefix(s+" ", entryBullets)
+}
+
+// IsBulleted reports whether s starts with a list bullet and a space
+func IsBulleted(s string) bool {
+	return hasAnyPrefix(s, entryBullets)
+}
+
+// hasAnyPrefix reports whether s starts with one of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipSpace returns the index of the first character at or after i that is no space or tab
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+
+	return i
+}
+
+// isPunct reports whether c is ascii punctuation, the characters markdown lets escape
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
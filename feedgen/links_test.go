@@ -0,0 +1,83 @@
+package feedgen
+
+import (
+	"testing"
+)
+
+func TestParseLink(t *testing.T) {
+	tests := []struct {
+		in   string
+		text string
+		dest string
+		rest string
+		ok   bool
+	}{
+		{"[Tofu](https://tofu.example) - rest", "Tofu", "https://tofu.example", " - rest", true},
+		{"[Tofu [Co]](https://tofu.example)", "Tofu [Co]", "https://tofu.example", "", true},
+		{`[Tofu \]](https://tofu.example)`, "Tofu ]", "https://tofu.example", "", true},
+		{"[Wiki](https://en.example/Tofu_(food))", "Wiki", "https://en.example/Tofu_(food)", "", true},
+		{"[Spaces](<https://x.example/a b>)", "Spaces", "https://x.example/a b", "", true},
+		{`[Title](https://x.example "A title")`, "Title", "https://x.example", "", true},
+		{"[Title](https://x.example 'A title') after", "Title", "https://x.example", " after", true},
+		{"[Title](https://x.example (A title))", "Title", "https://x.example", "", true},
+		{"[](https://x.example)", "", "", "[](https://x.example)", false},
+		{"[Gap] (https://x.example)", "", "", "[Gap] (https://x.example)", false},
+		{"[Open](https://x.example", "", "", "[Open](https://x.example", false},
+		{"[Empty]()", "", "", "[Empty]()", false},
+		{"Tofu", "", "", "Tofu", false},
+	}
+
+	for _, tt := range tests {
+		text, dest, rest, ok := ParseLink(tt.in)
+		if text != tt.text || dest != tt.dest || rest != tt.rest || ok != tt.ok {
+			t.Errorf("%s: got %q %q %q %v, want %q %q %q %v", tt.in, text, dest, rest, ok, tt.text, tt.dest, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		in   string
+		name string
+		url  string
+		desc string
+		ok   bool
+	}{
+		{"- [Tofu](https://tofu.example) - Firm tofu.", "Tofu", "https://tofu.example", "Firm tofu.", true},
+		{"* [Tofu](https://tofu.example): Firm tofu.", "Tofu", "https://tofu.example", "Firm tofu.", true},
+		{"  + [Tofu](https://tofu.example) – Firm tofu.", "Tofu", "https://tofu.example", "Firm tofu.", true},
+		{"- [Tofu](https://tofu.example) - ", "", "", "", false},
+		{"- [Tofu](https://tofu.example)", "", "", "", false},
+		{"- [Tofu](https://tofu.example), Firm tofu.", "", "", "", false},
+		{"1. [Tofu](https://tofu.example) - Firm tofu.", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		name, url, desc, ok := ParseEntry(tt.in)
+		if name != tt.name || url != tt.url || desc != tt.desc || ok != tt.ok {
+			t.Errorf("%s: got %q %q %q %v", tt.in, name, url, desc, ok)
+		}
+	}
+}
+
+func TestDefinitionEntries(t *testing.T) {
+	if name, url, ok := ParseTitle("- [Tofu](https://tofu.example)  "); !ok || name != "Tofu" || url != "https://tofu.example" {
+		t.Errorf("title: got %q %q %v", name, url, ok)
+	}
+	if _, _, ok := ParseTitle("- [Tofu](https://tofu.example) - Firm tofu."); ok {
+		t.Error("entry with description taken as title")
+	}
+
+	for line, want := range map[string]bool{
+		"  Firm tofu.":                    true,
+		"   ":                             false,
+		"  - [Nested](https://x.example)": false,
+		"Firm tofu.":                      false,
+		"\tFirm tofu.":                    true,
+		"    Firm tofu.":                  true,
+	} {
+		if got := IsDescription(line); got != want {
+			t.Errorf("%q: got %v, want %v", line, got, want)
+		}
+	}
+}
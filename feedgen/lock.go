@@ -0,0 +1,30 @@
+package feedgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// name of the lock file runs and prunes keep in the directories they work in
+const lockFileName = ".feedgen.lock"
+
+// LockDir takes the lock of a directory, creating both when missing, and fails right away
+// when another process holds it; closing the returned file or exiting releases the lock,
+// the file itself stays
+func LockDir(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s is in use by another run: %v", dir, err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,12 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package feedgen
+
+import (
+	"os"
+)
+
+// lockFile does nothing where there are no advisory locks, runs are not kept apart there
+func lockFile(f *os.File) error {
+	return nil
+}
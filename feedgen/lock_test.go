@@ -0,0 +1,31 @@
+package feedgen
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLockDir(t *testing.T) {
+	if runtime.GOOS != "windows" && runtime.GOOS != "linux" && runtime.GOOS != "darwin" && !strings.HasSuffix(runtime.GOOS, "bsd") {
+		t.Skip("no advisory locks")
+	}
+
+	dir := filepath.Join(t.TempDir(), "feeds")
+	lock, err := LockDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LockDir(dir); err == nil {
+		t.Fatal("second lock taken")
+	}
+
+	lock.Close()
+	lock, err = LockDir(dir)
+	if err != nil {
+		t.Fatalf("lock not released: %v", err)
+	}
+	lock.Close()
+}
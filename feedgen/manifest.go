@@ -0,0 +1,107 @@
+package feedgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// name of the manifest listing all artifacts of a run
+const manifestFile = "manifest.json"
+
+// manifestEntry describes a single artifact of a run
+type manifestEntry struct {
+	Name        string `json:"name"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+	Changed     bool   `json:"changed"`
+	// header to serve the file with, for scripts setting up a cdn
+	CacheControl string `json:"cache_control,omitempty"`
+}
+
+// manifest lists every artifact written in a run
+type manifest struct {
+	Files []manifestEntry `json:"files"`
+}
+
+// loadManifest reads the manifest of the previous run from wherever the sink publishes, a
+// missing file yields an empty one
+func loadManifest(sink OutputSink) (*manifest, error) {
+	m := &manifest{}
+
+	data, err := sink.Read(manifestFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// manifestSink records every artifact passing through and writes the manifest last
+type manifestSink struct {
+	OutputSink
+	previous map[string]string
+	current  manifest
+	policy   *cachePolicy
+}
+
+func newManifestSink(sink OutputSink, policy *cachePolicy) (*manifestSink, error) {
+	prev, err := loadManifest(sink)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &manifestSink{OutputSink: sink, previous: make(map[string]string), policy: policy}
+	for _, e := range prev.Files {
+		s.previous[e.Name] = e.SHA256
+	}
+
+	return s, nil
+}
+
+func (s *manifestSink) Write(name string, contentType string, data []byte) error {
+	if err := s.OutputSink.Write(name, contentType, data); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	e := manifestEntry{
+		Name:        name,
+		Size:        len(data),
+		SHA256:      hash,
+		ContentType: contentType,
+		Changed:     s.previous[name] != hash,
+	}
+	if s.policy != nil {
+		e.CacheControl = s.policy.cacheControl(name, contentType)
+	}
+	s.current.Files = append(s.current.Files, e)
+
+	return nil
+}
+
+func (s *manifestSink) Finalize() error {
+	// the manifest comes after all artifacts so its presence signals a complete run
+	data, err := json.MarshalIndent(&s.current, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := s.OutputSink.Write(manifestFile, "application/json", append(data, '\n')); err != nil {
+		return err
+	}
+
+	return s.OutputSink.Finalize()
+}
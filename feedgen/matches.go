@@ -0,0 +1,42 @@
+package feedgen
+
+// version of the extraction rules, to be raised whenever the items
+// found in a history or their identity change
+const parserVersion = ParserVersion
+
+// extractMatches finds entries in the added and removed lines of a patch in the shape the
+// walk works with: line, sign, name, url and description; the rules of the first release
+// are used unless current is set; warnings refer to the lines of file when the patch is the
+// one of a single file
+func extractMatches(patch string, maxLen int, current bool, file string, w *warnings) [][]string {
+	changes, skipped := ParsePatch(patch, ParseOptions{MaxLineLength: maxLen, Legacy: !current})
+
+	for _, s := range skipped {
+		if file == "" {
+			w.warn(s.Class, "%s", s.Message)
+		} else {
+			w.warnAt(s.Class, file, s.Line[1:], "%s", s.Message)
+		}
+	}
+
+	var matches [][]string
+	for _, c := range changes {
+		matches = append(matches, []string{c.Line, c.Line[:1], c.Name, c.URL, c.Description})
+	}
+
+	return matches
+}
+
+// isHTMLEntry reports whether a match was found in an html list item
+func isHTMLEntry(m []string) bool {
+	return IsHTMLItem(m[0][len(m[1]):])
+}
+
+// matchFile returns the file a match was found in, only known when the list spans several files
+func matchFile(m []string) string {
+	if len(m) > 5 {
+		return m[5]
+	}
+
+	return ""
+}
@@ -0,0 +1,100 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+// feedEntries lists the entries of an atom feed without their ids, which name the commits
+func feedEntries(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	var doc struct {
+		Entries []struct {
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Link    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Summary string `xml:"summary"`
+			Author  string `xml:"author>name"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []string
+	for _, e := range doc.Entries {
+		entries = append(entries, fmt.Sprintf("%s %s by %s: %s %s", e.Updated, e.Title, e.Author, e.Link.Href, e.Summary))
+	}
+
+	return entries
+}
+
+// TestOctopusMergeAndEmptyCommits has three branches merged at once and empty commits, which
+// have to give the same feed as the same changes made one after the other
+func TestOctopusMergeAndEmptyCommits(t *testing.T) {
+	const (
+		tofu   = "- [Tofu Town](https://tofu.example/) - All things tofu."
+		oat    = "- [Oat Dream](https://oat.example/) - Oat milk for coffee."
+		oat2   = "- [Oat Dream](https://oatdream.example/) - Oat milk for coffee."
+		seitan = "- [Seitan Co](https://seitan.example/) - Wheat based meats."
+		shoes  = "- [Vegan Shoes](https://shoes.example/) - Shoes without leather."
+		bags   = "- [Bags](https://bags.example/) - Bags without leather."
+	)
+
+	octopus := []feedgentest.Snapshot{
+		listSnapshot("Alice", 1, "Start the list", "Food", tofu, oat, "Fashion", shoes),
+		listSnapshot("Bob", 2, "Add Seitan Co", "Food", tofu, oat, seitan, "Fashion", shoes),
+		listSnapshot("Chloé", 3, "Remove Vegan Shoes", "Food", tofu, oat, "Fashion"),
+		listSnapshot("Dmitri", 4, "Add Bags", "Food", tofu, oat, "Fashion", shoes, bags),
+		listSnapshot("Alice", 5, "Merge branches 'seitan', 'shoes' and 'bags'", "Food", tofu, oat, seitan, "Fashion", bags),
+		listSnapshot("Alice", 6, "Empty commit left by a rebase", "Food", tofu, oat, seitan, "Fashion", bags),
+		listSnapshot("Bob", 7, "Update Oat Dream url", "Food", tofu, oat2, seitan, "Fashion", bags),
+		listSnapshot("Bob", 8, "Another empty commit", "Food", tofu, oat2, seitan, "Fashion", bags),
+	}
+	octopus[2].Parents = []int{0}
+	octopus[3].Parents = []int{0}
+	octopus[4].Parents = []int{1, 2, 3}
+
+	linear := []feedgentest.Snapshot{
+		octopus[0],
+		octopus[1],
+		listSnapshot("Chloé", 3, "Remove Vegan Shoes", "Food", tofu, oat, seitan, "Fashion"),
+		listSnapshot("Dmitri", 4, "Add Bags", "Food", tofu, oat, seitan, "Fashion", bags),
+		octopus[6],
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	got := feedEntries(t, generate(t, nil, octopus...)["feed.xml"])
+	log.SetOutput(os.Stderr)
+
+	want := feedEntries(t, generate(t, nil, linear...)["feed.xml"])
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("combined: got\n%q\nwant\n%q", got, want)
+	}
+	if len(want) != 4 {
+		t.Errorf("linear history has %d entries, want 4", len(want))
+	}
+	if logged.Len() > 0 {
+		t.Errorf("empty commits logged:\n%s", logged.String())
+	}
+
+	// following the first parent, the merge brings the other two branches at once
+	got = feedEntries(t, generate(t, []string{"-merges", "first-parent"}, octopus...)["feed.xml"])
+	merged := []feedgentest.Snapshot{octopus[0], octopus[1], octopus[4], octopus[6]}
+	merged[2].Parents = nil
+	want = feedEntries(t, generate(t, nil, merged...)["feed.xml"])
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("first-parent: got\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,238 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// ItemMeta carries item data that feeds.Item has no field for
+type ItemMeta struct {
+	// kind of change, e.g. Addition or Removal
+	Kind string
+	// name of the changed entry
+	Name string
+	// stable id of the entry across renames and url changes
+	EntryID string
+	// hash of the commit the change was made in
+	Commit string
+	// heading the entry is listed under, when known
+	Section string
+	// section offered as category of the item
+	Category string
+	// hashtags found at the end of the entry
+	Tags []string
+	// values split off the description by suffix patterns
+	Fields []field
+	// avatar image url of the contributor
+	Avatar string
+	// commit email of the author, only matched against and never published
+	Email string
+	// names of the entries listed around the changed entry
+	Neighbors []string
+	// changed lines of the entry
+	Diff string
+	// original date of an item dated after the newest commit
+	ClampedFrom time.Time
+	// place of the entry within its section counting from 1, before a removal, and the
+	// number of entries in that section
+	Position     int
+	SectionTotal int
+	// file the entry is listed in, only when the list spans several files
+	File string
+	// where the description was taken from when not from the list, e.g. commit
+	DescriptionSource string
+}
+
+// categories are the section category, the source file, the hashtags and the values of suffix
+// fields, in their canonical order as every format lists them
+func (m *ItemMeta) categories() []string {
+	var cats []string
+	if m.Category != "" {
+		cats = append(cats, m.Category)
+	}
+	if m.File != "" {
+		cats = append(cats, m.File)
+	}
+	cats = append(cats, m.Tags...)
+	for _, f := range m.Fields {
+		cats = append(cats, f.Value)
+	}
+
+	return sortTerms(cats)
+}
+
+// jsonExt is the item extension object of the json feed
+type jsonExt struct {
+	EntryID   string            `json:"entry_id,omitempty"`
+	Neighbors []string          `json:"neighbors,omitempty"`
+	Diff      string            `json:"diff,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	// the item date was moved from this date in the future
+	ClampedFrom *time.Time `json:"clamped_from,omitempty"`
+	// where the entry is listed, for rebuilding the list in its curated order
+	Section      string `json:"section,omitempty"`
+	Position     int    `json:"position,omitempty"`
+	SectionTotal int    `json:"section_total,omitempty"`
+	File         string `json:"file,omitempty"`
+	// the description is not the text of the list when set, e.g. commit for the commit message
+	DescriptionSource string `json:"description_source,omitempty"`
+}
+
+// jsonItem is a json feed item with the extension object attached
+type jsonItem struct {
+	*feeds.JSONItem
+	Ext *jsonExt `json:"_feedgen,omitempty"`
+}
+
+// jsonGenerator is the feed level extension naming the tool and its parser version
+type jsonGenerator struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Version string `json:"version"`
+}
+
+// jsonDoc is a json feed whose items carry the extension object
+type jsonDoc struct {
+	*feeds.JSONFeed
+	Generator  *jsonGenerator `json:"_generator,omitempty"`
+	Provenance *Provenance    `json:"_provenance,omitempty"`
+	Items      []*jsonItem    `json:"items,omitempty"`
+}
+
+func (j *jsonDoc) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// itemID is a tag uri identifying an item by the commit, the entry and the kind of change,
+// e.g. tag:awesome-veganism.com,2023:1a2b3c.../tofu-co/add
+func itemID(authority string, year int, commit string, name string, kind string) string {
+	s := slug(name)
+	if s == "" {
+		s = "entry"
+	}
+
+	return fmt.Sprintf("tag:%s,%d:%s/%s/%s", authority, year, commit, s, kind)
+}
+
+// idKinds are the kinds of change as they appear in item ids
+var idKinds = map[string]string{"Addition": "add", "Removal": "remove", "Update": "update", "Move": "move", "Meta": "meta", "Restructure": "restructure"}
+
+// noreply addresses look like 12345+user@users.noreply.github.com or user@users.noreply.github.com
+var noreplyRe = regexp.MustCompile(`^(?:[0-9]+\+)?([A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)@users\.noreply\.github\.com$`)
+
+// usernames as github allows them, checked before one ends up in an avatar url
+var githubUserRe = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?$`)
+
+// githubUser derives the GitHub username from a commit email, or returns an empty string
+func githubUser(email string) string {
+	m := noreplyRe.FindStringSubmatch(strings.ToLower(email))
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
+
+// avatarURL references the avatar of a GitHub user, the image itself is never downloaded
+func avatarURL(user string) string {
+	return fmt.Sprintf("https://github.com/%s.png?size=64", user)
+}
+
+// newItemMeta starts the metadata of an item found in commit c, with the avatar of its author
+// unless avatars are turned off: a noreply address names the user, any other address needs to
+// be listed in the authors of the repository configuration
+func newItemMeta(o *options, c *object.Commit, kind string, name string) *ItemMeta {
+	im := &ItemMeta{Kind: kind, Name: name, Commit: c.Hash.String(), Email: c.Author.Email}
+	if !o.NoAvatars {
+		user := githubUser(c.Author.Email)
+		if user == "" {
+			user = o.authors[strings.ToLower(c.Author.Email)]
+		}
+		if user != "" {
+			im.Avatar = avatarURL(user)
+		}
+	}
+
+	return im
+}
+
+// atomFeed builds the atom representation including per item data
+func atomFeed(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta) *feeds.AtomFeed {
+	a := (&feeds.Atom{Feed: feed}).AtomFeed()
+
+	for n, e := range a.Entries {
+		m := meta[feed.Items[n]]
+		if m == nil {
+			continue
+		}
+
+		if m.Avatar != "" {
+			e.Links = append(e.Links, feeds.AtomLink{Href: m.Avatar, Rel: "icon"})
+		}
+	}
+
+	return a
+}
+
+// jsonFeed builds the json representation including per item data
+func jsonFeed(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta) *jsonDoc {
+	j := &jsonDoc{
+		JSONFeed:  (&feeds.JSON{Feed: feed}).JSONFeed(),
+		Generator: &jsonGenerator{Name: "awesome-veganism-feed", URL: repoURL, Version: parserVersion},
+	}
+
+	for n, e := range j.JSONFeed.Items {
+		item := &jsonItem{JSONItem: e}
+		j.Items = append(j.Items, item)
+
+		m := meta[feed.Items[n]]
+		if m == nil {
+			continue
+		}
+
+		if m.Avatar != "" && e.Author != nil {
+			e.Author.Avatar = m.Avatar
+		}
+
+		e.Tags = m.categories()
+
+		if m.EntryID != "" || len(m.Neighbors) > 0 || m.Diff != "" || len(m.Fields) > 0 || !m.ClampedFrom.IsZero() || m.Position > 0 {
+			item.Ext = &jsonExt{EntryID: m.EntryID, Neighbors: m.Neighbors, Diff: m.Diff, File: m.File, DescriptionSource: m.DescriptionSource}
+			if m.Position > 0 {
+				item.Ext.Section, item.Ext.Position, item.Ext.SectionTotal = m.Section, m.Position, m.SectionTotal
+			}
+			if !m.ClampedFrom.IsZero() {
+				t := m.ClampedFrom
+				item.Ext.ClampedFrom = &t
+			}
+			for _, f := range m.Fields {
+				if item.Ext.Fields == nil {
+					item.Ext.Fields = make(map[string]string)
+				}
+				item.Ext.Fields[f.Name] = f.Value
+			}
+		}
+	}
+
+	return j
+}
+
+// xmlEscape escapes a string for use in xml text or attribute values
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
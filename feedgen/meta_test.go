@@ -0,0 +1,61 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+func TestAuthorAvatars(t *testing.T) {
+	history := goldenHistory()
+	for n := range history {
+		if history[n].Author == "Bob" {
+			history[n].Email = "123+bobby@users.noreply.github.com"
+		}
+	}
+	// the mapping counts as of the head, for the whole history
+	last := &history[len(history)-1]
+	last.Files[repoConfigFile] = feedgentest.File("authors:\n  Alice@Example.org: alice-vegan\n")
+
+	avatars := func(data []byte) map[string]string {
+		t.Helper()
+
+		var feed struct {
+			Items []struct {
+				Title  string `json:"title"`
+				Author struct {
+					Avatar string `json:"avatar"`
+				} `json:"author"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(data, &feed); err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, item := range feed.Items {
+			got[item.Title] = item.Author.Avatar
+		}
+
+		return got
+	}
+
+	got := avatars(generate(t, nil, history...)["feed.json"])
+	want := map[string]string{
+		"Addition of Café Végétal": "https://github.com/bobby.png?size=64",
+		"Update of Oat Dream":      "https://github.com/alice-vegan.png?size=64",
+		"Addition of Seitan Co":    "",
+		"Removal of Vegan Shoes":   "https://github.com/bobby.png?size=64",
+		"Addition of Сумки":        "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got avatars %q, want %q", got, want)
+	}
+
+	for title, avatar := range avatars(generate(t, []string{"-no-avatars"}, history...)["feed.json"]) {
+		if avatar != "" {
+			t.Errorf("%s: avatar %s with -no-avatars", title, avatar)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package feedgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// metaFile is an additional file of the repository, e.g. MAINTAINERS.md, whose added lines
+// matching a pattern are announced in the feeds as changes of their own kind
+type metaFile struct {
+	path  string
+	re    *regexp.Regexp
+	title *template.Template
+}
+
+// metaMatch is an added line of a meta file with the title of its item
+type metaMatch struct {
+	line  string
+	title string
+}
+
+// parseMetaFile splits a path:pattern:template flag value; the pattern ends at the first
+// colon outside of groups and classes, so both the pattern and the template may contain colons
+func parseMetaFile(s string) (*metaFile, error) {
+	path, rest, _ := strings.Cut(s, ":")
+	pattern, tmpl, found := cutPattern(rest)
+	if path == "" || pattern == "" || !found || tmpl == "" {
+		return nil, fmt.Errorf("invalid meta file, expected path:pattern:template: %s", s)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid meta file pattern: %s: %v", path, err)
+	}
+	title, err := template.New(path).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid meta file template: %s: %v", path, err)
+	}
+
+	mf := &metaFile{path: path, re: re, title: title}
+	// every group the template refers to has to exist
+	if _, err := mf.render(nil, ""); err != nil {
+		return nil, fmt.Errorf("invalid meta file template: %s: %v", path, err)
+	}
+
+	return mf, nil
+}
+
+// cutPattern cuts a regular expression at the first colon outside of groups, classes and escapes
+func cutPattern(s string) (string, string, bool) {
+	depth, class, escaped := 0, false, false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case class:
+			class = r != ']'
+		case r == '[':
+			class = true
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ':' && depth == 0:
+			return s[:i], s[i+1:], true
+		}
+	}
+
+	return s, "", false
+}
+
+// render executes the title template with the named groups of the pattern and the whole line
+func (mf *metaFile) render(groups []string, line string) (string, error) {
+	data := map[string]string{"line": line}
+	for n, name := range mf.re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		data[name] = ""
+		if n < len(groups) {
+			data[name] = groups[n]
+		}
+	}
+
+	var b strings.Builder
+	if err := mf.title.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// matches returns the lines added to the meta file by a commit that match its pattern, compared
+// to all patches of the commit so a merge only announces what is new to all of its parents;
+// lines removed elsewhere in the file were moved and are left out
+func (mf *metaFile) matches(patches []*object.Patch) ([]metaMatch, error) {
+	added := metaLines(patches[0], mf.path)
+	for _, other := range patches[1:] {
+		more := metaLines(other, mf.path)
+		for line, n := range added {
+			if more[line] < n {
+				added[line] = more[line]
+			}
+		}
+	}
+
+	var matches []metaMatch
+	for _, l := range workfileLines(patches[0], mf.path) {
+		line := strings.TrimSpace(l[1:])
+		if l[0] != '+' || added[line] <= 0 {
+			continue
+		}
+		added[line]--
+
+		groups := mf.re.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		title, err := mf.render(groups, line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", mf.path, err)
+		}
+		matches = append(matches, metaMatch{line: line, title: strings.TrimSpace(title)})
+	}
+
+	return matches, nil
+}
+
+// metaLines counts the net additions of every distinct non-blank line of a file in a patch
+func metaLines(patch *object.Patch, path string) map[string]int {
+	lines := make(map[string]int)
+	for _, l := range workfileLines(patch, path) {
+		line := strings.TrimSpace(l[1:])
+		if line == "" {
+			continue
+		}
+		switch l[0] {
+		case '+':
+			lines[line]++
+		case '-':
+			lines[line]--
+		}
+	}
+
+	return lines
+}
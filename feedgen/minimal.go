@@ -0,0 +1,116 @@
+package feedgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/gorilla/feeds"
+)
+
+// default title of items in the minimal feed
+const defaultMinimalTitle = "{{.Kind}}: {{.Name}}"
+
+// minimalTitle holds the values available to the title template of the minimal feed
+type minimalTitle struct {
+	Kind    string
+	Name    string
+	Title   string
+	Section string
+}
+
+// authorFilter decides by the commit email of their author which items notification services
+// hear of through the minimal feed, without removing them from any other feed
+type authorFilter struct {
+	announce []*regexp.Regexp
+	mute     []*regexp.Regexp
+}
+
+func newAuthorFilter(announce []string, mute []string) (*authorFilter, error) {
+	f := &authorFilter{}
+	for _, list := range []struct {
+		flag     string
+		patterns []string
+		res      *[]*regexp.Regexp
+	}{
+		{"-announce-authors", announce, &f.announce},
+		{"-mute-authors", mute, &f.mute},
+	} {
+		for _, p := range list.patterns {
+			re, err := compilePattern(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s pattern: %s: %v", list.flag, p, err)
+			}
+			*list.res = append(*list.res, re)
+		}
+	}
+
+	return f, nil
+}
+
+// muted reports whether the items of an author are kept from notifications: muted authors
+// never get through, and with an allowlist only the authors on it do; items without an
+// author email, like digests, are never muted
+func (f *authorFilter) muted(email string) bool {
+	if f == nil || email == "" {
+		return false
+	}
+	for _, re := range f.mute {
+		if re.MatchString(email) {
+			return true
+		}
+	}
+	for _, re := range f.announce {
+		if re.MatchString(email) {
+			return false
+		}
+	}
+
+	return len(f.announce) > 0
+}
+
+// minimalFeed strips items down to a title and the link, leaving out the items of authors
+// muted for notifications, whose number it returns; item ids are the ones of the main feed,
+// so both feeds agree on them
+func minimalFeed(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, tmpl *template.Template, authors *authorFilter) (*feeds.AtomFeed, int, error) {
+	minimal := &feeds.Feed{
+		Title:       feed.Title,
+		Link:        feed.Link,
+		Description: feed.Description,
+		Created:     feed.Created,
+		Updated:     feed.Updated,
+	}
+
+	muted := 0
+	for _, item := range feed.Items {
+		v := minimalTitle{Title: item.Title}
+		if m := meta[item]; m != nil {
+			if authors.muted(m.Email) {
+				muted++
+				continue
+			}
+			v.Kind, v.Name, v.Section = m.Kind, m.Name, m.Section
+		}
+
+		var b strings.Builder
+		if err := tmpl.Execute(&b, v); err != nil {
+			return nil, 0, err
+		}
+
+		minimal.Items = append(minimal.Items, &feeds.Item{
+			Id:      item.Id,
+			Title:   b.String(),
+			Link:    item.Link,
+			Created: item.Created,
+			Updated: item.Updated,
+		})
+	}
+
+	af := (&feeds.Atom{Feed: minimal}).AtomFeed()
+	for _, e := range af.Entries {
+		e.Summary = nil
+	}
+
+	return af, muted, nil
+}
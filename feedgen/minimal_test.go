@@ -0,0 +1,68 @@
+package feedgen
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAuthorFilterMuted(t *testing.T) {
+	tests := []struct {
+		name     string
+		announce []string
+		mute     []string
+		email    string
+		muted    bool
+	}{
+		{"no patterns", nil, nil, "bob@example.org", false},
+		{"muted", nil, []string{"bob@*"}, "bob@example.org", true},
+		{"muted case-insensitive", nil, []string{"bob@*"}, "Bob@Example.org", true},
+		{"not muted", nil, []string{"bob@*"}, "alice@example.org", false},
+		{"announced", []string{"alice@example.org"}, nil, "alice@example.org", false},
+		{"not announced", []string{"alice@example.org"}, nil, "bob@example.org", true},
+		{"mute wins over announce", []string{"*@example.org"}, []string{"/^bob@/"}, "bob@example.org", true},
+		{"no email", []string{"alice@example.org"}, []string{"*"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newAuthorFilter(tt.announce, tt.mute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.muted(tt.email); got != tt.muted {
+				t.Errorf("muted(%q) = %v, want %v", tt.email, got, tt.muted)
+			}
+		})
+	}
+
+	if _, err := newAuthorFilter(nil, []string{"/(/"}); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+// TestMutedAuthorsOnlyLeaveTheMinimalFeed checks the items of muted authors are left out of
+// the minimal feed and nothing else
+func TestMutedAuthorsOnlyLeaveTheMinimalFeed(t *testing.T) {
+	all := generate(t, []string{"-minimal-feed", "minimal.xml"}, goldenHistory()...)
+	muted := generate(t, []string{"-minimal-feed", "minimal.xml", "-mute-authors", "bob@*"}, goldenHistory()...)
+	announced := generate(t, []string{"-minimal-feed", "minimal.xml", "-announce-authors", "bob@example.org"}, goldenHistory()...)
+
+	for _, files := range []map[string][]byte{muted, announced} {
+		for _, name := range []string{"feed.xml", "feed.json", "feed.rss"} {
+			if !bytes.Equal(files[name], all[name]) {
+				t.Errorf("%s differs with muted authors", name)
+			}
+		}
+	}
+
+	entries := func(files map[string][]byte) int {
+		return bytes.Count(files["minimal.xml"], []byte("<entry>"))
+	}
+	n, m, a := entries(all), entries(muted), entries(announced)
+	if m == 0 || m >= n {
+		t.Errorf("%d of %d entries left after muting bob", m, n)
+	}
+	if a == 0 || m+a != n {
+		t.Errorf("%d entries announced for bob, %d muted of %d", a, n-m, n)
+	}
+}
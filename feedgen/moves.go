@@ -0,0 +1,61 @@
+package feedgen
+
+// movedEntries returns the additions of entries that were removed with the same title,
+// url and description in the same commit, i.e. entries that only moved within the file
+func movedEntries(matches [][]string, changes map[string]int) [][]string {
+	removed := make(map[string]bool)
+	for _, m := range matches {
+		if m[1] == "-" {
+			removed[m[2]+"\x00"+m[3]+"\x00"+m[4]] = true
+		}
+	}
+
+	var moved [][]string
+	for _, m := range matches {
+		if m[1] == "+" && changes[m[2]] == 0 && removed[m[2]+"\x00"+m[3]+"\x00"+m[4]] {
+			moved = append(moved, m)
+		}
+	}
+
+	return moved
+}
+
+// update pairs the removed and the added line of an entry kept under the same title
+type update struct {
+	old []string
+	// index of the added line in the matches
+	index int
+}
+
+// updatedEntries finds entries removed and added under the same title in the same commit
+// with another url or description, pairing the first removal with the first addition
+func updatedEntries(matches [][]string, changes map[string]int) map[string]update {
+	removed := make(map[string][]string)
+	for _, m := range matches {
+		if m[1] == "-" && changes[m[2]] == 0 && removed[m[2]] == nil {
+			removed[m[2]] = m
+		}
+	}
+
+	updates := make(map[string]update)
+	for n, m := range matches {
+		old := removed[m[2]]
+		if m[1] != "+" || old == nil {
+			continue
+		}
+		if _, found := updates[m[2]]; !found && (old[3] != m[3] || old[4] != m[4]) {
+			updates[m[2]] = update{old: old, index: n}
+		}
+		// later additions under the same title are never paired
+		delete(removed, m[2])
+	}
+
+	return updates
+}
+
+// sectionOf returns the heading an entry is listed under, empty when it is not found
+func sectionOf(entries []entry, name string) string {
+	section, _, _, _ := neighbors(entries, name)
+
+	return section
+}
@@ -0,0 +1,141 @@
+package feedgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// postProcessor changes a rendered feed document of the given format, atom, rss or json,
+// after the built-in processing and before it is checked and written
+type postProcessor func(format string, data []byte) ([]byte, error)
+
+// feed formats by content type, other artifacts are never post processed
+var documentFormats = map[string]string{
+	"application/atom+xml":  "atom",
+	"application/rss+xml":   "rss",
+	"application/feed+json": "json",
+}
+
+// postProcessSink runs the post processors over every feed document and refuses to write a
+// document they left malformed
+type postProcessSink struct {
+	OutputSink
+	hooks []postProcessor
+}
+
+func (s *postProcessSink) Write(name string, contentType string, data []byte) error {
+	format, found := documentFormats[contentType]
+	if !found {
+		return s.OutputSink.Write(name, contentType, data)
+	}
+
+	for _, hook := range s.hooks {
+		var err error
+		if data, err = hook(format, data); err != nil {
+			return fmt.Errorf("failed to post process %s: %v", name, err)
+		}
+	}
+	if err := checkDocument(format, data); err != nil {
+		return fmt.Errorf("post processing left %s malformed: %v", name, err)
+	}
+
+	return s.OutputSink.Write(name, contentType, data)
+}
+
+// checkDocument makes sure a document is still valid json or well-formed xml with a root element
+func checkDocument(format string, data []byte) error {
+	if format == "json" {
+		if !json.Valid(data) {
+			return fmt.Errorf("invalid json")
+		}
+		return nil
+	}
+
+	root := false
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if _, ok := t.(xml.StartElement); ok {
+			root = true
+		}
+	}
+	if !root {
+		return fmt.Errorf("missing root element")
+	}
+
+	return nil
+}
+
+// commandPostProcessor pipes documents through an external command, which finds the format in
+// the AVFEED_FORMAT environment variable; a command that fails or runs into the timeout fails
+// the document
+func commandPostProcessor(args []string, timeout time.Duration) postProcessor {
+	return func(format string, data []byte) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Env = append(os.Environ(), "AVFEED_FORMAT="+format)
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("timed out after %s", timeout)
+			}
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%s: %v: %s", args[0], err, msg)
+			}
+			return nil, fmt.Errorf("%s: %v", args[0], err)
+		}
+
+		return stdout.Bytes(), nil
+	}
+}
+
+// SplitColumns splits a line at whitespace, double quotes keep values with spaces together
+func SplitColumns(line string) ([]string, error) {
+	var cols []string
+	var b strings.Builder
+	quoted, started := false, false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if started {
+				cols = append(cols, b.String())
+				b.Reset()
+				started = false
+			}
+		default:
+			b.WriteRune(r)
+			started = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if started {
+		cols = append(cols, b.String())
+	}
+
+	return cols, nil
+}
@@ -0,0 +1,282 @@
+package feedgen
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// maximum number of commits followed back from a merge to find the commits of its pull request
+const prCommitLimit = 1000
+
+// pullRequest is a pull request as far as it can be told from commit messages
+type pullRequest struct {
+	Number int
+	Title  string
+}
+
+// parsePullRequest finds the pull request a squash or merge commit message refers to in the
+// formats of all known forges, nil when it is in none of them
+func parsePullRequest(msg string) *pullRequest {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(msg), "\r\n", "\n"), "\n")
+	subject := strings.TrimSpace(lines[0])
+
+	for _, f := range forges {
+		if pr := f.parse(subject, lines[1:]); pr != nil {
+			return pr
+		}
+	}
+
+	return nil
+}
+
+// trailers at the end of commit messages, e.g. Signed-off-by: or Co-authored-by:
+var trailerRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*: `)
+
+// commitBody returns the first paragraph of a commit message body as a single line, skipping
+// the pull request title forges put into merge commits, conflict lists and trailers
+func commitBody(msg string) string {
+	paragraphs := strings.Split(strings.ReplaceAll(strings.TrimSpace(msg), "\r\n", "\n"), "\n\n")
+	if len(paragraphs) < 2 {
+		return ""
+	}
+
+	body := paragraphs[1:]
+	if pr := parsePullRequest(msg); pr != nil && strings.TrimSpace(strings.SplitN(body[0], "\n", 2)[0]) == pr.Title {
+		body = body[1:]
+	}
+
+	for _, p := range body {
+		lines := strings.Split(strings.TrimSpace(p), "\n")
+		if lines[0] == "" || strings.HasPrefix(lines[0], "Conflicts:") || strings.HasPrefix(lines[0], "# Conflicts:") || mergeRequestRe.MatchString(lines[0]) {
+			continue
+		}
+
+		trailers := true
+		for _, l := range lines {
+			if !trailerRe.MatchString(l) {
+				trailers = false
+			}
+		}
+		if trailers {
+			continue
+		}
+
+		for n, l := range lines {
+			lines[n] = strings.TrimSpace(l)
+		}
+		return strings.Join(lines, " ")
+	}
+
+	return ""
+}
+
+// pullRequests maps commit hashes to their pull request: squash and merge commits by their
+// message, and the commits brought in by a merge by following its other parents; all of
+// history is looked at as merges do not necessarily touch the work file themselves
+func pullRequests(r *git.Repository, from plumbing.Hash) (map[plumbing.Hash]*pullRequest, error) {
+	iter, err := r.Log(&git.LogOptions{From: from, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+
+	prs := make(map[plumbing.Hash]*pullRequest)
+	err = iter.ForEach(func(c *object.Commit) error {
+		pr := parsePullRequest(c.Message)
+		if pr == nil {
+			return nil
+		}
+		if _, found := prs[c.Hash]; !found {
+			prs[c.Hash] = pr
+		}
+
+		if c.NumParents() < 2 {
+			return nil
+		}
+
+		// everything reachable from the merged branch but not from the mainline belongs to the pull request
+		mainline, err := c.Parent(0)
+		if err != nil {
+			return err
+		}
+
+		var queue []*object.Commit
+		for i := 1; i < c.NumParents(); i++ {
+			if p, err := c.Parent(i); err == nil {
+				queue = append(queue, p)
+			}
+		}
+
+		for seen := 0; len(queue) > 0 && seen < prCommitLimit; seen++ {
+			b := queue[0]
+			queue = queue[1:]
+
+			if _, found := prs[b.Hash]; found {
+				continue
+			}
+			if merged, err := b.IsAncestor(mainline); err != nil || merged {
+				continue
+			}
+
+			prs[b.Hash] = pr
+			b.Parents().ForEach(func(p *object.Commit) error {
+				queue = append(queue, p)
+				return nil
+			})
+		}
+
+		return nil
+	})
+
+	return prs, err
+}
+
+// OriginRepo finds the host and the owner/repo path of the origin remote, empty when unknown
+func OriginRepo(r *git.Repository) (string, string) {
+	rm, err := r.Remote("origin")
+	if err != nil || len(rm.Config().URLs) == 0 {
+		return "", ""
+	}
+
+	// both git@host:owner/repo.git and https://host/owner/repo.git
+	u := rm.Config().URLs[0]
+	u = strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+		if j := strings.Index(u, "@"); j >= 0 && j < strings.Index(u+"/", "/") {
+			u = u[j+1:]
+		}
+	} else if i := strings.Index(u, "@"); i >= 0 {
+		u = strings.Replace(u[i+1:], ":", "/", 1)
+	}
+
+	host, repo, found := strings.Cut(u, "/")
+	if !found {
+		return "", ""
+	}
+
+	return host, repo
+}
+
+// prURLTemplate derives the pull request url of the origin remote, empty for unknown hosts
+func prURLTemplate(r *git.Repository) string {
+	host, repo := OriginRepo(r)
+	if f := forgeOf(host); f != nil {
+		return f.prURL(host, repo)
+	}
+
+	return ""
+}
+
+// validPRURLTemplate reports whether the template has exactly one %d and no other verbs,
+// literal percent signs have to be written as %%
+func validPRURLTemplate(t string) bool {
+	n := 0
+	for i := 0; i < len(t); i++ {
+		if t[i] != '%' {
+			continue
+		}
+		i++
+		switch {
+		case i < len(t) && t[i] == '%':
+		case i < len(t) && t[i] == 'd':
+			n++
+		default:
+			return false
+		}
+	}
+
+	return n == 1
+}
+
+// groupByPullRequest replaces consecutive items of the same pull request with one item
+// listing all their changes, items of commits without pull request are kept as they are
+func groupByPullRequest(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, prs map[plumbing.Hash]*pullRequest, urlTemplate string, authority string) {
+	var items []*feeds.Item
+	var group []*feeds.Item
+	var current *pullRequest
+
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+
+		var b strings.Builder
+		b.WriteString("<ul>\n")
+		var names []string
+		for _, item := range group {
+			fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a>: %s</li>\n", html.EscapeString(item.Link.Href), html.EscapeString(item.Title), html.EscapeString(item.Description))
+			names = append(names, meta[item].Name)
+		}
+		b.WriteString("</ul>")
+
+		link := feed.Link.Href
+		if urlTemplate != "" {
+			link = fmt.Sprintf(urlTemplate, current.Number)
+		}
+
+		// a pull request can come back after other commits, its first commit tells the groups apart
+		last := group[len(group)-1]
+		item := &feeds.Item{
+			Id:          fmt.Sprintf("tag:%s,%d:pr/%d/%s", authority, last.Created.UTC().Year(), current.Number, meta[group[0]].Commit),
+			Title:       fmt.Sprintf("%s (#%d)", current.Title, current.Number),
+			Link:        &feeds.Link{Href: link},
+			Description: fmt.Sprintf("%d %s: %s", len(group), plural(len(group), "change", "changes"), strings.Join(names, ", ")),
+			Content:     b.String(),
+			Author:      group[0].Author,
+			Created:     last.Created,
+		}
+		meta[item] = &ItemMeta{Kind: "PullRequest", Name: current.Title, Avatar: meta[group[0]].Avatar, Email: meta[group[0]].Email}
+
+		items = append(items, item)
+		group, current = nil, nil
+	}
+
+	for _, item := range feed.Items {
+		pr := prs[plumbing.NewHash(meta[item].Commit)]
+		if pr == nil || current != nil && pr.Number != current.Number {
+			flush()
+		}
+		if pr == nil {
+			items = append(items, item)
+			continue
+		}
+
+		current = pr
+		group = append(group, item)
+	}
+	flush()
+
+	feed.Items = items
+}
+
+func plural(n int, one string, many string) string {
+	if n == 1 {
+		return one
+	}
+
+	return many
+}
+
+// commitURL links a commit on the forge of a repository: a url with a {hash} placeholder is
+// used as is, gitlab hosts get their /-/commit/ path and everything else the /commit/ path
+// github, gitea and forgejo share
+func commitURL(repo string, hash string) string {
+	if strings.Contains(repo, "{hash}") {
+		return strings.ReplaceAll(repo, "{hash}", hash)
+	}
+
+	repo = strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	if u, err := url.Parse(repo); err == nil && strings.HasPrefix(u.Hostname(), "gitlab.") {
+		return repo + "/-/commit/" + hash
+	}
+
+	return repo + "/commit/" + hash
+}
@@ -0,0 +1,92 @@
+package feedgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// flags that only decide where, under which names or how verbosely a run publishes, which
+// additional files it writes or whether it fails, not what the feeds contain
+var provenanceIgnored = map[string]bool{
+	"destdir": true, "workdir": true, "verbose": true, "output": true, "git-branch": true,
+	"s3-bucket": true, "s3-prefix": true, "s3-region": true, "s3-endpoint": true,
+	"sftp": true, "sftp-key": true, "sftp-known-hosts": true, "sftp-insecure": true,
+	"user-agent": true, "contact-email": true, "manifest": true, "config": true,
+	"state": true, "full": true, "dates": true, "cache-policy": true, "headers-file": true,
+	"eventlog": true, "atom-file": true, "json-file": true, "rss-file": true,
+	"stale-feed": true, "timeseries": true, "maintainer-feed": true, "minimal-feed": true,
+	"announce-authors": true, "mute-authors": true,
+	"compat-feed": true, "badge": true, "badge-svg": true, "registry": true,
+	"removed-page": true, "removed-json": true, "filter-feed": true, "tag-feeds": true,
+	"annotations": true, "postprocess-timeout": true, "git-exclude-outputs": true,
+	"strict": true, "require-default-branch": true, "theme": true, "theme-dir": true,
+}
+
+// Provenance identifies what a feed was generated from, it deliberately has no timestamp
+// so regenerating unchanged content yields identical bytes
+type Provenance struct {
+	Head    string `json:"head"`
+	Version string `json:"version"`
+	Parser  string `json:"parser_version"`
+	Options string `json:"options"`
+	// oldest commit processed one by one in the tiered mode, older items come from monthly chunks
+	Tiered string `json:"tiered,omitempty"`
+}
+
+// optionsFingerprint hashes all flags changed from their default that affect the generated content
+func optionsFingerprint(fs *flag.FlagSet) string {
+	return flagsFingerprint(fs, func(name string) bool {
+		return !provenanceIgnored[name]
+	})
+}
+
+// flagsFingerprint hashes the selected flags that were changed from their default
+func flagsFingerprint(fs *flag.FlagSet, selected func(name string) bool) string {
+	var set []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if selected(f.Name) && f.Value.String() != f.DefValue {
+			set = append(set, f.Name+"="+f.Value.String())
+		}
+	})
+	sort.Strings(set)
+
+	sum := sha256.Sum256([]byte(strings.Join(set, "\n")))
+
+	return hex.EncodeToString(sum[:6])
+}
+
+// provenance comments are found by this expression when inspecting a feed
+var provenanceRe = regexp.MustCompile(`<!-- provenance: head=(\S*) version=(\S*) parser=(\S*) options=(\S*)(?: tiered=(\S*))? -->`)
+
+// ParseProvenance finds the provenance of a published atom, rss or json feed
+func ParseProvenance(data []byte) (*Provenance, error) {
+	if m := provenanceRe.FindSubmatch(data); m != nil {
+		return &Provenance{Head: string(m[1]), Version: string(m[2]), Parser: string(m[3]), Options: string(m[4]), Tiered: string(m[5])}, nil
+	}
+
+	var doc struct {
+		Provenance *Provenance `json:"_provenance"`
+	}
+	if json.Unmarshal(data, &doc) != nil || doc.Provenance == nil {
+		return nil, errors.New("no provenance found")
+	}
+
+	return doc.Provenance, nil
+}
+
+// comment is the text of the provenance comment put right after the xml declaration
+func (p *Provenance) comment() string {
+	s := fmt.Sprintf("provenance: head=%s version=%s parser=%s options=%s", p.Head, p.Version, p.Parser, p.Options)
+	if p.Tiered != "" {
+		s += " tiered=" + p.Tiered
+	}
+
+	return s
+}
@@ -0,0 +1,317 @@
+package feedgen
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// publish renders all outputs of a collection and hands them to the output sink
+func publish(o *options, col *collection) error {
+	feed, meta := col.feed, col.meta
+
+	// generating into the checkout must never touch the list itself
+	opts := sinkOptions{SinkOptions: o.Sink, Destdir: o.Destdir, Cache: o.cache, Client: o.client}
+	for _, f := range col.workfiles {
+		opts.Protect = append(opts.Protect, filepath.Join(o.Workdir, filepath.FromSlash(f)))
+	}
+	inRepo := o.Sink.Output == "fs" && InsideDir(o.Workdir, o.Destdir)
+	if inRepo && o.Verbose {
+		log.Printf("destdir %s is inside the repository in %s", o.Destdir, o.Workdir)
+	}
+
+	// whoever follows the log must never see an output of a change it has not got
+	if o.EventLog != "" {
+		added, err := appendEvents(o.EventLog, col.head.Hash.String(), col.changes, meta)
+		if err != nil {
+			return fmt.Errorf("failed to append to event log: %v", err)
+		}
+		if o.Verbose {
+			log.Printf("event log: %d events added", added)
+		}
+	}
+
+	report := &sinkReport{}
+	sink, err := newSink(opts, col.repo, report)
+	if err != nil {
+		return fmt.Errorf("failed to setup output: %v", err)
+	}
+	sink = &timedSink{OutputSink: sink, timings: col.timings}
+	if len(o.postProcessors) > 0 {
+		sink = &postProcessSink{OutputSink: sink, hooks: o.postProcessors}
+	}
+
+	// caps only apply to the combined feeds, additional feeds always carry everything
+	combined := feed
+	if len(col.caps) > 0 {
+		combined = capCategories(feed, meta, col.caps, o.IDAuthority)
+	}
+
+	if o.Compat == "v1" {
+		err = publishV1(o, feed, sink, col.timings)
+	} else {
+		err = publishFeeds(o, combined, meta, sink, col.timings, col.provenance)
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.TagFeeds {
+		tagged := tagFeeds(feed, meta)
+		for _, tag := range sortedKeys(tagged) {
+			if err := writeAtomFeed(o, sink, "tag-"+slug(tag)+".xml", tagged[tag], meta, col.provenance); err != nil {
+				return fmt.Errorf("failed to write tag feed: %s: %v", tag, err)
+			}
+		}
+	}
+
+	for _, f := range col.filters {
+		if err := writeAtomFeed(o, sink, f.file, f.apply(feed, meta), meta, col.provenance); err != nil {
+			return fmt.Errorf("failed to write filter feed: %s: %v", f.name, err)
+		}
+	}
+
+	if o.StaleFile != "" {
+		if o.StaleAfter == 0 {
+			return errors.New("missing -stale-after for stale feed")
+		}
+
+		// a list gone for a while has nothing to review
+		entries, err := currentEntries(col.commits[0], col.workfiles)
+		if errors.Is(err, object.ErrFileNotFound) {
+			entries = nil
+		} else if err != nil {
+			return fmt.Errorf("failed to parse current entries: %v", err)
+		}
+
+		// entries with disallowed links or excluded ones are not offered for review
+		var allowed []entry
+		for _, e := range entries {
+			if schemeAllowed(e.URL, o.schemes) && !col.exclude.excluded(e.Name, e.URL) && !col.scrub.matches(e.Name, e.URL, o.URLIdentity) {
+				allowed = append(allowed, e)
+			}
+		}
+		entries = allowed
+
+		sf := staleFeed(feed, entries, col.history, col.registry, time.Duration(o.StaleAfter), time.Now(), o.IDAuthority)
+		sanitizeFeed(sf, nil)
+
+		stale, err := marshalFeed(newAtomXMLFeed((&feeds.Atom{Feed: sf}).AtomFeed(), o.publicPath(o.StaleFile)), col.provenance, o.Stylesheet)
+		if err != nil {
+			return fmt.Errorf("failed to generate stale feed: %v", err)
+		}
+		if err := sink.Write(o.StaleFile, "application/atom+xml", []byte(stale)); err != nil {
+			return fmt.Errorf("failed to write stale feed: %v", err)
+		}
+	}
+
+	if o.MaintainerFile != "" {
+		mff := maintainerFeed(feed, col.warnings, col.head, o.IDAuthority)
+		sanitizeFeed(mff, nil)
+
+		mf, err := marshalFeed(newAtomXMLFeed((&feeds.Atom{Feed: mff}).AtomFeed(), o.publicPath(o.MaintainerFile)), col.provenance, o.Stylesheet)
+		if err != nil {
+			return fmt.Errorf("failed to generate maintainer feed: %v", err)
+		}
+		if err := sink.Write(o.MaintainerFile, "application/atom+xml", []byte(mf)); err != nil {
+			return fmt.Errorf("failed to write maintainer feed: %v", err)
+		}
+	}
+
+	if o.MinimalFile != "" {
+		af, muted, err := minimalFeed(combined, meta, o.minimalTitle, o.authorFilter)
+		if err != nil {
+			return fmt.Errorf("failed to generate minimal feed: %v", err)
+		}
+		col.muted = muted
+		doc := newAtomXMLFeed(af, o.publicPath(o.MinimalFile))
+		doc.Generator = newGenerator()
+		atom, err := marshalFeed(doc, col.provenance, "")
+		if err != nil {
+			return fmt.Errorf("failed to generate minimal feed: %v", err)
+		}
+		if err := sink.Write(o.MinimalFile, "application/atom+xml", []byte(atom)); err != nil {
+			return fmt.Errorf("failed to write minimal feed: %v", err)
+		}
+	}
+
+	// old readers get the same items without anything they might trip over
+	if o.LegacyFile != "" {
+		atom, err := marshalLegacy(legacyDocument(combined))
+		if err != nil {
+			return fmt.Errorf("failed to generate compat feed: %v", err)
+		}
+		if err := sink.Write(o.LegacyFile, "application/atom+xml", []byte(atom)); err != nil {
+			return fmt.Errorf("failed to write compat feed: %v", err)
+		}
+	}
+
+	if o.BadgeFile != "" || o.BadgeSVGFile != "" {
+		b := newBadge(newThisMonth(feed, meta, time.Now().In(o.location)))
+		if o.BadgeFile != "" {
+			data, err := b.marshal()
+			if err != nil {
+				return fmt.Errorf("failed to generate badge: %v", err)
+			}
+			if err := sink.Write(o.BadgeFile, "application/json", data); err != nil {
+				return fmt.Errorf("failed to write badge: %v", err)
+			}
+		}
+		if o.BadgeSVGFile != "" {
+			if err := sink.Write(o.BadgeSVGFile, "image/svg+xml", b.svg()); err != nil {
+				return fmt.Errorf("failed to write badge: %v", err)
+			}
+		}
+	}
+
+	if o.RegistryFile != "" {
+		data, err := col.registry.marshal()
+		if err != nil {
+			return fmt.Errorf("failed to generate registry: %v", err)
+		}
+		if err := sink.Write(o.RegistryFile, "application/json", data); err != nil {
+			return fmt.Errorf("failed to write registry: %v", err)
+		}
+	}
+
+	if o.RemovedPage != "" || o.RemovedJSON != "" {
+		removed := col.registry.removedEntries()
+		if o.RemovedJSON != "" {
+			data, err := marshalRemoved(removed)
+			if err != nil {
+				return fmt.Errorf("failed to generate removed entries: %v", err)
+			}
+			if err := sink.Write(o.RemovedJSON, "application/json", data); err != nil {
+				return fmt.Errorf("failed to write removed entries: %v", err)
+			}
+		}
+		if o.RemovedPage != "" {
+			data, err := renderRemoved(feed.Title, removed, o.RepoURL, o.theme)
+			if err != nil {
+				return fmt.Errorf("failed to generate removed entries page: %v", err)
+			}
+			if err := sink.Write(o.RemovedPage, "text/html", data); err != nil {
+				return fmt.Errorf("failed to write removed entries page: %v", err)
+			}
+		}
+	}
+
+	if o.theme != nil {
+		if err := o.theme.write(sink, o.Stylesheet); err != nil {
+			return fmt.Errorf("failed to write theme: %v", err)
+		}
+	}
+
+	if o.TimeseriesFile != "" {
+		// extend the previously written series instead of counting every commit again
+		previous, err := loadTimeseries(sink, o.TimeseriesFile)
+		if err != nil {
+			return fmt.Errorf("failed to read time series: %v", err)
+		}
+
+		points, computed, err := buildTimeseries(col.commits, col.workfiles, previous)
+		if err != nil {
+			return fmt.Errorf("failed to count entries: %v", err)
+		}
+		if o.Verbose {
+			log.Printf("time series: %d points, %d newly counted", len(points), computed)
+		}
+
+		data, err := marshalTimeseries(points)
+		if err != nil {
+			return fmt.Errorf("failed to generate time series: %v", err)
+		}
+		if err := sink.Write(o.TimeseriesFile, "application/json", data); err != nil {
+			return fmt.Errorf("failed to write time series: %v", err)
+		}
+	}
+
+	if err := sink.Finalize(); err != nil {
+		return fmt.Errorf("failed to finalize output: %v", err)
+	}
+
+	// the state only moves on once everything it covers was published
+	if col.state != nil {
+		if err := saveState(filepath.Join(o.Destdir, o.StateFile), col.state); err != nil {
+			return fmt.Errorf("failed to write state: %v", err)
+		}
+	}
+
+	if o.GitExcludeOutputs && inRepo {
+		files := append(append([]string{filepath.Join(o.Destdir, StagingDir), filepath.Join(o.Destdir, lockFileName)}, report.Written...), report.Skipped...)
+		if col.state != nil {
+			files = append(files, filepath.Join(o.Destdir, o.StateFile))
+		}
+		if err := gitExcludeOutputs(o.Workdir, files); err != nil {
+			return fmt.Errorf("failed to exclude outputs from git: %v", err)
+		}
+	}
+
+	if o.Verbose {
+		log.Printf("entries excluded: %d", col.excluded)
+		log.Printf("items scrubbed: %d", col.scrubbed)
+		if o.MinimalFile != "" {
+			log.Printf("items muted for notifications: %d", col.muted)
+		}
+		log.Print(report)
+		log.Print(col.timings)
+		for _, f := range col.filters {
+			log.Printf("filter feed %s: %d items", f.name, f.count)
+		}
+	}
+
+	return nil
+}
+
+// writeAtomFeed renders an additional atom feed the same way as the main one
+func writeAtomFeed(o *options, sink OutputSink, name string, feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, prov *Provenance) error {
+	atom, err := marshalFeed(atomDocument(feed, meta, o.publicPath(name), o.topics), prov, o.Stylesheet)
+	if err != nil {
+		return err
+	}
+
+	return sink.Write(name, "application/atom+xml", []byte(atom))
+}
+
+// publishFeeds writes the main atom, json and rss feeds
+func publishFeeds(o *options, feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, sink OutputSink, t *timings, prov *Provenance) error {
+	done := t.start("render")
+	atom, err := marshalFeed(atomDocument(feed, meta, o.selfURL(o.AtomFile, o.AtomSelfURL), o.topics), prov, o.Stylesheet)
+	if err != nil {
+		return fmt.Errorf("failed to generate atom feed: %v", err)
+	}
+	done(1)
+	if err := sink.Write(o.AtomFile, "application/atom+xml", []byte(atom)); err != nil {
+		return fmt.Errorf("failed to write atom feed: %v", err)
+	}
+
+	done = t.start("render")
+	doc := jsonFeed(feed, meta)
+	doc.Provenance = prov
+	doc.FeedUrl = o.JSONFeedURL
+	json, err := doc.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to generate json feed: %v", err)
+	}
+	done(1)
+	if err := sink.Write(o.JSONFile, "application/feed+json", []byte(json)); err != nil {
+		return fmt.Errorf("failed to write json feed: %v", err)
+	}
+
+	done = t.start("render")
+	rss, err := marshalFeed(rssDocument(feed, meta, o.selfURL(o.RSSFile, o.RSSSelfURL), o.topics), prov, "")
+	if err != nil {
+		return fmt.Errorf("failed to generate rss feed: %v", err)
+	}
+	done(1)
+	if err := sink.Write(o.RSSFile, "application/rss+xml", []byte(rss)); err != nil {
+		return fmt.Errorf("failed to write rss feed: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// registryEntry is one logical entry of the list across renames and url changes
+type registryEntry struct {
+	ID        string     `json:"id"`
+	Names     []string   `json:"names"`
+	URLs      []string   `json:"urls"`
+	FirstSeen time.Time  `json:"first_seen"`
+	Commit    string     `json:"commit"`
+	Removals  []*removal `json:"removals,omitempty"`
+}
+
+// removal is the tombstone of an entry leaving the list, kept when it comes back later
+type removal struct {
+	Name    string     `json:"name"`
+	URL     string     `json:"url"`
+	When    time.Time  `json:"when"`
+	Author  string     `json:"author"`
+	Reason  string     `json:"reason"`
+	Commit  string     `json:"commit"`
+	Readded *time.Time `json:"readded,omitempty"`
+}
+
+// registry assigns stable ids to entries, it is rebuilt from the history on every run
+// and therefore yields the same ids for the same history
+type registry struct {
+	entries  []*registryEntry
+	byName   map[string]*registryEntry
+	identity string
+}
+
+// newRegistry sets up an empty registry comparing urls with the given identity mode
+func newRegistry(identity string) *registry {
+	return &registry{byName: make(map[string]*registryEntry), identity: identity}
+}
+
+// id returns the entry id of a name, empty for names never seen in a change
+func (r *registry) id(name string) string {
+	if e := r.byName[name]; e != nil {
+		return e.ID
+	}
+
+	return ""
+}
+
+// observe follows the entries changed in a commit: a name added in the same commit another
+// one with the same url is removed is a rename, a known name with a new url a url change
+func (r *registry) observe(matches [][]string, changes map[string]int, c *object.Commit) {
+	removed := make(map[string]*registryEntry)
+	for _, m := range matches {
+		if m[1] != "-" {
+			continue
+		}
+
+		e := r.byName[m[2]]
+		if e == nil {
+			e = r.add(m[2], m[3], c)
+		}
+		if changes[m[2]] < 0 {
+			removed[urlIdentity(m[3], r.identity)] = e
+		}
+	}
+
+	for _, m := range matches {
+		if m[1] != "+" {
+			continue
+		}
+
+		e := r.byName[m[2]]
+		if e == nil {
+			if e = removed[urlIdentity(m[3], r.identity)]; e != nil {
+				e.Names = append(e.Names, m[2])
+				r.byName[m[2]] = e
+			} else {
+				e = r.add(m[2], m[3], c)
+			}
+		}
+
+		u := urlIdentity(m[3], r.identity)
+		known := false
+		for _, v := range e.URLs {
+			if v == u {
+				known = true
+			}
+		}
+		if !known {
+			e.URLs = append(e.URLs, u)
+		}
+	}
+
+	r.bury(matches, changes, c)
+}
+
+// bury leaves a tombstone for every entry gone from the list in a commit and marks the latest
+// one of every entry coming back; renamed entries show up under both names and cancel out
+func (r *registry) bury(matches [][]string, changes map[string]int, c *object.Commit) {
+	net := make(map[*registryEntry]int)
+	for name, v := range changes {
+		net[r.byName[name]] += v
+	}
+
+	for _, m := range matches {
+		e := r.byName[m[2]]
+		if m[1] != "-" || net[e] >= 0 {
+			continue
+		}
+		// an entry listed twice is only buried once
+		net[e] = 0
+
+		e.Removals = append(e.Removals, &removal{
+			Name:   m[2],
+			URL:    m[3],
+			When:   c.Author.When,
+			Author: c.Author.Name,
+			Reason: removalReason(c.Message),
+			Commit: c.Hash.String(),
+		})
+	}
+
+	for e, v := range net {
+		if v <= 0 || len(e.Removals) == 0 {
+			continue
+		}
+		if last := e.Removals[len(e.Removals)-1]; last.Readded == nil {
+			when := c.Author.When
+			last.Readded = &when
+		}
+	}
+}
+
+func (r *registry) add(name string, link string, c *object.Commit) *registryEntry {
+	e := &registryEntry{
+		Names: []string{name},
+		URLs:  []string{urlIdentity(link, r.identity)},
+	}
+	e.seen(c)
+	r.entries = append(r.entries, e)
+	r.byName[name] = e
+
+	return e
+}
+
+// seen dates an entry by the commit it was first seen in, which its id is derived from
+func (e *registryEntry) seen(c *object.Commit) {
+	e.ID = slug(e.Names[0]) + "-" + c.Hash.String()[:7]
+	e.FirstSeen = c.Author.When
+	e.Commit = c.Hash.String()
+}
+
+func (r *registry) marshal() ([]byte, error) {
+	return json.MarshalIndent(sanitizeRegistry(r.entries), "", "  ")
+}
@@ -0,0 +1,139 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+)
+
+// removedEntry is a tombstone of the registry as listed on the removed entries page
+type removedEntry struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	URL     string     `json:"url"`
+	Removed time.Time  `json:"removed"`
+	Author  string     `json:"author"`
+	Reason  string     `json:"reason"`
+	Commit  string     `json:"commit"`
+	Readded *time.Time `json:"readded,omitempty"`
+}
+
+// removalReason is the title of the pull request a commit belongs to, or else its subject
+func removalReason(msg string) string {
+	if pr := parsePullRequest(msg); pr != nil {
+		return pr.Title
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(msg), "\n")
+
+	return strings.TrimSpace(subject)
+}
+
+// removedEntries lists every removal ever recorded in the registry, latest first
+func (r *registry) removedEntries() []removedEntry {
+	removed := []removedEntry{}
+	for _, e := range r.entries {
+		// later removals of an entry come first when dated the same
+		for n := len(e.Removals) - 1; n >= 0; n-- {
+			t := e.Removals[n]
+			removed = append(removed, removedEntry{
+				ID:      e.ID,
+				Name:    sanitizeText(t.Name, maxTextLength),
+				URL:     sanitizeText(t.URL, maxTextLength),
+				Removed: t.When,
+				Author:  sanitizeText(t.Author, maxTextLength),
+				Reason:  sanitizeText(t.Reason, maxTextLength),
+				Commit:  t.Commit,
+				Readded: t.Readded,
+			})
+		}
+	}
+
+	sort.SliceStable(removed, func(i, j int) bool {
+		if !removed[i].Removed.Equal(removed[j].Removed) {
+			return removed[i].Removed.After(removed[j].Removed)
+		}
+		return removed[i].ID < removed[j].ID
+	})
+
+	return removed
+}
+
+func marshalRemoved(removed []removedEntry) ([]byte, error) {
+	return json.MarshalIndent(removed, "", "  ")
+}
+
+// functions of the removed entries page, for the one of a theme directory as well
+var removedFuncs = template.FuncMap{
+	"date": func(t time.Time) string { return t.UTC().Format("2006-01-02") },
+}
+
+var removedPage = template.Must(template.New("removed").Funcs(removedFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Removed from {{.Title}}</title>
+{{- if .CSS}}
+<style>
+{{.CSS}}</style>
+{{- end}}
+</head>
+<body>
+<h1>Removed from {{.Title}}</h1>
+<table>
+<thead>
+<tr><th>Removed</th><th>Entry</th><th>By</th><th>Reason</th><th>Status</th></tr>
+</thead>
+<tbody>
+{{- range .Entries}}
+<tr id="{{.ID}}">
+<td>{{date .Removed}}</td>
+<td><a href="{{.URL}}">{{.Name}}</a></td>
+<td>{{.Author}}</td>
+<td>{{if .Link}}<a href="{{.Link}}">{{.Reason}}</a>{{else}}{{.Reason}}{{end}}</td>
+<td>{{if .Readded}}re-added {{date .Readded}}{{else}}removed{{end}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
+
+// renderRemoved renders the removed entries page, reasons link their commits when the
+// repository url is known; a theme styles the page or replaces it with its own template
+func renderRemoved(title string, removed []removedEntry, repo string, th *theme) ([]byte, error) {
+	type row struct {
+		removedEntry
+		Link string
+	}
+
+	var rows []row
+	for _, e := range removed {
+		r := row{removedEntry: e}
+		if repo != "" {
+			r.Link = commitURL(repo, e.Commit)
+		}
+		rows = append(rows, r)
+	}
+
+	page, css := removedPage, ""
+	if th != nil {
+		page, css = th.removed, th.css
+	}
+
+	var b bytes.Buffer
+	if err := page.Execute(&b, struct {
+		Title   string
+		Entries []row
+		// the css is written by the maintainers or built in and never escaped
+		CSS template.CSS
+	}{title, rows, template.CSS(css)}); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,292 @@
+package feedgen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// namespaces of the extensions used in the rss feed
+const (
+	atomNamespace    = "http://www.w3.org/2005/Atom"
+	contentNamespace = "http://purl.org/rss/1.0/modules/content/"
+	dcNamespace      = "http://purl.org/dc/elements/1.1/"
+	mediaNamespace   = "http://search.yahoo.com/mrss/"
+)
+
+// atomXMLFeed is an atom feed with what the feeds package has no room for: separate self and
+// alternate links, the generator and the categories of entries
+type atomXMLFeed struct {
+	XMLName     xml.Name `xml:"feed"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	Title       string   `xml:"title"`
+	Id          string   `xml:"id"`
+	Updated     string   `xml:"updated"`
+	Generator   *atomGenerator
+	Icon        string `xml:"icon,omitempty"`
+	Logo        string `xml:"logo,omitempty"`
+	Rights      string `xml:"rights,omitempty"`
+	Subtitle    string `xml:"subtitle,omitempty"`
+	Links       []feeds.AtomLink
+	Categories  []atomCategory
+	Author      *feeds.AtomAuthor `xml:"author,omitempty"`
+	Contributor *feeds.AtomContributor
+	Entries     []*atomEntry `xml:"entry"`
+}
+
+type atomGenerator struct {
+	XMLName xml.Name `xml:"generator"`
+	URI     string   `xml:"uri,attr"`
+	Version string   `xml:"version,attr"`
+	Name    string   `xml:",chardata"`
+}
+
+// atomEntry is an entry as the feeds package builds it followed by its categories
+type atomEntry struct {
+	*feeds.AtomEntry
+	Categories []atomCategory
+}
+
+type atomCategory struct {
+	XMLName xml.Name `xml:"category"`
+	Term    string   `xml:"term,attr"`
+}
+
+// newAtomXMLFeed takes over an atom feed of the feeds package, the single link of the feed
+// becomes the alternate link next to the self link of the published file, given as path below
+// the feed link or as absolute url
+func newAtomXMLFeed(af *feeds.AtomFeed, self string) *atomXMLFeed {
+	doc := &atomXMLFeed{
+		Xmlns:       af.Xmlns,
+		Title:       af.Title,
+		Id:          af.Id,
+		Updated:     af.Updated,
+		Icon:        af.Icon,
+		Logo:        af.Logo,
+		Rights:      af.Rights,
+		Subtitle:    af.Subtitle,
+		Author:      af.Author,
+		Contributor: af.Contributor,
+	}
+
+	if af.Link != nil {
+		doc.Links = []feeds.AtomLink{
+			{Href: selfURL(af.Link.Href, self), Rel: "self"},
+			{Href: af.Link.Href, Rel: "alternate"},
+		}
+	}
+
+	for _, e := range af.Entries {
+		sortLinks(e.Links)
+		doc.Entries = append(doc.Entries, &atomEntry{AtomEntry: e})
+	}
+	sortLinks(doc.Links)
+
+	return doc
+}
+
+// sortLinks puts links in their canonical order, by relation and then by url, so the same
+// links always serialize the same way
+func sortLinks(links []feeds.AtomLink) {
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Rel != links[j].Rel {
+			return links[i].Rel < links[j].Rel
+		}
+		return links[i].Href < links[j].Href
+	})
+}
+
+// sortTerms returns categories in their canonical order without duplicates
+func sortTerms(terms []string) []string {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	sorted := append([]string{}, terms...)
+	sort.Strings(sorted)
+
+	n := 1
+	for _, t := range sorted[1:] {
+		if t != sorted[n-1] {
+			sorted[n] = t
+			n++
+		}
+	}
+
+	return sorted[:n]
+}
+
+// newGenerator names this tool and the version of its extraction rules
+func newGenerator() *atomGenerator {
+	return &atomGenerator{URI: repoURL, Version: parserVersion, Name: "awesome-veganism-feed"}
+}
+
+// atomDocument builds the atom feed with generator, avatars and categories from the item
+// metadata, topics become categories of the feed itself
+func atomDocument(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, self string, topics []string) *atomXMLFeed {
+	doc := newAtomXMLFeed(atomFeed(feed, meta), self)
+	doc.Generator = newGenerator()
+	for _, t := range sortTerms(topics) {
+		doc.Categories = append(doc.Categories, atomCategory{Term: t})
+	}
+
+	for n, e := range doc.Entries {
+		if m := meta[feed.Items[n]]; m != nil {
+			for _, tag := range m.categories() {
+				e.Categories = append(e.Categories, atomCategory{Term: tag})
+			}
+		}
+	}
+
+	return doc
+}
+
+// rssXMLFeed is an rss feed with the extensions in use: dublin core creators of items, the atom
+// self link of the channel and media thumbnails
+type rssXMLFeed struct {
+	XMLName          xml.Name `xml:"rss"`
+	Version          string   `xml:"version,attr"`
+	ContentNamespace string   `xml:"xmlns:content,attr"`
+	DCNamespace      string   `xml:"xmlns:dc,attr"`
+	AtomNamespace    string   `xml:"xmlns:atom,attr"`
+	MediaNamespace   string   `xml:"xmlns:media,attr,omitempty"`
+	Channel          *rssChannel
+}
+
+type rssChannel struct {
+	XMLName        xml.Name `xml:"channel"`
+	Title          string   `xml:"title"`
+	Link           string   `xml:"link"`
+	AtomLink       *rssAtomLink
+	Description    string `xml:"description"`
+	Copyright      string `xml:"copyright,omitempty"`
+	ManagingEditor string `xml:"managingEditor,omitempty"`
+	PubDate        string `xml:"pubDate,omitempty"`
+	LastBuildDate  string `xml:"lastBuildDate,omitempty"`
+	Image          *feeds.RssImage
+	Categories     []string   `xml:"category"`
+	Items          []*rssItem `xml:"item"`
+}
+
+type rssAtomLink struct {
+	XMLName xml.Name `xml:"atom:link"`
+	Href    string   `xml:"href,attr"`
+	Rel     string   `xml:"rel,attr"`
+	Type    string   `xml:"type,attr"`
+}
+
+type rssItem struct {
+	XMLName     xml.Name `xml:"item"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Content     *feeds.RssContent
+	Creator     string `xml:"dc:creator,omitempty"`
+	Enclosure   *feeds.RssEnclosure
+	Guid        *rssGuid
+	PubDate     string `xml:"pubDate,omitempty"`
+	Source      string `xml:"source,omitempty"`
+	Thumbnail   *rssThumbnail
+	Categories  []string `xml:"category"`
+}
+
+// rssGuid tells readers that item ids are no links
+type rssGuid struct {
+	XMLName     xml.Name `xml:"guid"`
+	IsPermaLink string   `xml:"isPermaLink,attr"`
+	Id          string   `xml:",chardata"`
+}
+
+type rssThumbnail struct {
+	XMLName xml.Name `xml:"media:thumbnail"`
+	URL     string   `xml:"url,attr"`
+}
+
+// rssDocument builds the rss feed with creators, thumbnails and categories from the item
+// metadata, topics become categories of the channel
+func rssDocument(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, self string, topics []string) *rssXMLFeed {
+	rf := (&feeds.Rss{Feed: feed}).RssFeed()
+
+	channel := &rssChannel{
+		Title:          rf.Title,
+		Link:           rf.Link,
+		AtomLink:       &rssAtomLink{Href: selfURL(rf.Link, self), Rel: "self", Type: "application/rss+xml"},
+		Description:    rf.Description,
+		Copyright:      rf.Copyright,
+		ManagingEditor: rf.ManagingEditor,
+		PubDate:        rf.PubDate,
+		LastBuildDate:  rf.LastBuildDate,
+		Image:          rf.Image,
+		Categories:     sortTerms(topics),
+	}
+	doc := &rssXMLFeed{
+		Version:          "2.0",
+		ContentNamespace: contentNamespace,
+		DCNamespace:      dcNamespace,
+		AtomNamespace:    atomNamespace,
+		Channel:          channel,
+	}
+
+	for n, ri := range rf.Items {
+		item := &rssItem{
+			Title:       ri.Title,
+			Link:        ri.Link,
+			Description: ri.Description,
+			Content:     ri.Content,
+			Creator:     ri.Author,
+			Enclosure:   ri.Enclosure,
+			PubDate:     ri.PubDate,
+			Source:      ri.Source,
+		}
+		if ri.Guid != "" {
+			item.Guid = &rssGuid{IsPermaLink: "false", Id: ri.Guid}
+		}
+
+		if m := meta[feed.Items[n]]; m != nil {
+			if m.Avatar != "" {
+				item.Thumbnail = &rssThumbnail{URL: m.Avatar}
+				doc.MediaNamespace = mediaNamespace
+			}
+			item.Categories = m.categories()
+		}
+
+		channel.Items = append(channel.Items, item)
+	}
+
+	return doc
+}
+
+// marshalFeed renders a feed document after the xml declaration, the provenance comment and
+// the stylesheet processing instruction, the latter two only when given
+func marshalFeed(doc interface{}, prov *Provenance, stylesheet string) (string, error) {
+	var b strings.Builder
+	enc := xml.NewEncoder(&b)
+	enc.Indent("", "  ")
+
+	prolog := []xml.Token{xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)}}
+	if prov != nil {
+		prolog = append(prolog, xml.Comment(" "+prov.comment()+" "))
+	}
+	if stylesheet != "" {
+		prolog = append(prolog, xml.ProcInst{Target: "xml-stylesheet", Inst: []byte(fmt.Sprintf(`href="%s" type="text/xsl"`, xmlEscape(stylesheet)))})
+	}
+	// the encoder only indents elements, everything before the root goes on a line of its own
+	for _, t := range prolog {
+		if err := enc.EncodeToken(t); err != nil {
+			return "", err
+		}
+		if err := enc.Flush(); err != nil {
+			return "", err
+		}
+		b.WriteString("\n")
+	}
+
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,300 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"awesome-veganism-feed/feedgentest"
+
+	"github.com/gorilla/feeds"
+)
+
+// atomLinks lists the links of an atom document as "rel href" lines, those of the entries
+// prefixed by the entry title
+func atomLinks(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	type link struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	}
+	var doc struct {
+		Links   []link `xml:"link"`
+		Entries []struct {
+			Title string `xml:"title"`
+			Links []link `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var links []string
+	for _, l := range doc.Links {
+		links = append(links, fmt.Sprintf("%s %s", l.Rel, l.Href))
+	}
+	for _, e := range doc.Entries {
+		for _, l := range e.Links {
+			links = append(links, fmt.Sprintf("%s: %s %s", e.Title, l.Rel, l.Href))
+		}
+	}
+
+	return links
+}
+
+func TestAtomLinkSet(t *testing.T) {
+	entries := []string{
+		"Addition of Café Végétal: alternate https://café.example/crème",
+		"Update of Oat Dream: alternate https://oatdream.example/",
+		"Addition of Seitan Co: alternate https://seitan.example/",
+		"Removal of Vegan Shoes: alternate https://shoes.example/",
+		"Addition of Сумки: alternate https://bags.example/",
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		feed []string
+	}{
+		{"default", nil, []string{
+			"alternate https://awesome-veganism.com/",
+			"self https://awesome-veganism.com/feed.xml",
+		}},
+		{"prefix", []string{"-link", "https://example.org/list/", "-url-prefix", "feeds", "-atom-file", "atom.xml"}, []string{
+			"alternate https://example.org/list/",
+			"self https://example.org/list/feeds/atom.xml",
+		}},
+		{"override", []string{"-atom-self-url", "https://feeds.example.net/vegan.atom"}, []string{
+			"alternate https://awesome-veganism.com/",
+			"self https://feeds.example.net/vegan.atom",
+		}},
+		// the self url is taken as it is, the prefix only applies to derived ones
+		{"override with prefix", []string{"-link", "https://example.org/list/", "-url-prefix", "feeds", "-atom-file", "atom.xml", "-atom-self-url", "https://feeds.example.net/vegan.atom"}, []string{
+			"alternate https://example.org/list/",
+			"self https://feeds.example.net/vegan.atom",
+		}},
+	}
+
+	for _, tt := range tests {
+		files := generate(t, tt.args, goldenHistory()...)
+
+		file := "feed.xml"
+		if strings.Contains(tt.name, "prefix") {
+			file = "atom.xml"
+		}
+		got := atomLinks(t, files[file])
+		want := append(append([]string{}, tt.feed...), entries...)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got links\n%q\nwant\n%q", tt.name, got, want)
+		}
+	}
+}
+
+func TestAtomRoundTrip(t *testing.T) {
+	files := generate(t, []string{"-stylesheet", "style.xsl?v=2&theme=dark"}, goldenHistory()...)
+
+	d := xml.NewDecoder(bytes.NewReader(files["feed.xml"]))
+	var pis []string
+	var root xml.StartElement
+	for root.Name.Local == "" {
+		tok, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch tok := tok.(type) {
+		case xml.ProcInst:
+			pis = append(pis, fmt.Sprintf("%s %s", tok.Target, tok.Inst))
+		case xml.StartElement:
+			root = tok
+		}
+	}
+
+	want := []string{
+		`xml version="1.0" encoding="UTF-8"`,
+		`xml-stylesheet href="style.xsl?v=2&amp;theme=dark" type="text/xsl"`,
+	}
+	if !reflect.DeepEqual(pis, want) {
+		t.Errorf("processing instructions: got %q, want %q", pis, want)
+	}
+	if root.Name.Space != "http://www.w3.org/2005/Atom" || root.Name.Local != "feed" {
+		t.Errorf("root element: %v", root.Name)
+	}
+
+	var doc struct {
+		Entries []struct {
+			Authors []string `xml:"author>name"`
+		} `xml:"http://www.w3.org/2005/Atom entry"`
+	}
+	if err := xml.Unmarshal(files["feed.xml"], &doc); err != nil {
+		t.Fatal(err)
+	}
+	var authors []string
+	for _, e := range doc.Entries {
+		authors = append(authors, e.Authors...)
+	}
+	if want := []string{"Bob", "Alice", "Chloé Dupont", "Bob", "Дмитрий"}; !reflect.DeepEqual(authors, want) {
+		t.Errorf("authors: got %q, want %q", authors, want)
+	}
+}
+
+func TestRSSRoundTrip(t *testing.T) {
+	const (
+		atomNS = "http://www.w3.org/2005/Atom"
+		dcNS   = "http://purl.org/dc/elements/1.1/"
+	)
+
+	files := generate(t, nil, goldenHistory()...)
+
+	type atomLink struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+	}
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			// the namespaced link comes first so the plain one does not take it too
+			AtomLinks []atomLink `xml:"http://www.w3.org/2005/Atom link"`
+			Link      string     `xml:"link"`
+			Creators  []string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			Authors   []string   `xml:"managingEditor"`
+			Items     []struct {
+				Link     string   `xml:"link"`
+				Creators []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Authors  []string `xml:"author"`
+				PubDate  string   `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(files["feed.rss"], &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Version != "2.0" {
+		t.Errorf("version %q", doc.Version)
+	}
+	if doc.Channel.Link != "https://awesome-veganism.com/" {
+		t.Errorf("channel link %q", doc.Channel.Link)
+	}
+	want := []atomLink{{Href: "https://awesome-veganism.com/feed.rss", Rel: "self", Type: "application/rss+xml"}}
+	if !reflect.DeepEqual(doc.Channel.AtomLinks, want) {
+		t.Errorf("channel atom links: got %+v, want %+v", doc.Channel.AtomLinks, want)
+	}
+	if len(doc.Channel.Creators) > 0 || len(doc.Channel.Authors) > 0 {
+		t.Errorf("channel has creators %q and editors %q", doc.Channel.Creators, doc.Channel.Authors)
+	}
+
+	var creators, links, dates []string
+	for _, item := range doc.Channel.Items {
+		if len(item.Creators) != 1 || len(item.Authors) > 0 {
+			t.Errorf("%s: creators %q, authors %q", item.Link, item.Creators, item.Authors)
+		}
+		creators = append(creators, item.Creators...)
+		links = append(links, item.Link)
+		dates = append(dates, item.PubDate)
+	}
+	if want := []string{"Bob", "Alice", "Chloé Dupont", "Bob", "Дмитрий"}; !reflect.DeepEqual(creators, want) {
+		t.Errorf("creators: got %q, want %q", creators, want)
+	}
+	if want := []string{"https://café.example/crème", "https://oatdream.example/", "https://seitan.example/", "https://shoes.example/", "https://bags.example/"}; !reflect.DeepEqual(links, want) {
+		t.Errorf("links: got %q, want %q", links, want)
+	}
+	if want := []string{"Sat, 02 Mar 2024 09:30:00 +0000", "Sun, 03 Mar 2024 09:30:00 +0000", "Mon, 04 Mar 2024 09:30:00 +0000", "Tue, 05 Mar 2024 09:30:00 +0000", "Thu, 07 Mar 2024 09:30:00 +0000"}; !reflect.DeepEqual(dates, want) {
+		t.Errorf("dates: got %q, want %q", dates, want)
+	}
+
+	// the namespaces are declared where they are used, on the root element
+	for _, ns := range []string{`xmlns:atom="` + atomNS + `"`, `xmlns:dc="` + dcNS + `"`} {
+		if !bytes.Contains(files["feed.rss"], []byte(ns)) {
+			t.Errorf("%s missing", ns)
+		}
+	}
+}
+
+// TestCanonicalOrderIgnoresInputOrder renders the same feed from links, categories, tags
+// and topics given in random order, which has to give the same bytes every time
+func TestCanonicalOrderIgnoresInputOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	shuffled := func(list []string) []string {
+		s := append([]string{}, list...)
+		rng.Shuffle(len(s), func(i, j int) { s[i], s[j] = s[j], s[i] })
+		return s
+	}
+
+	render := func() string {
+		when := time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC)
+		feed := &feeds.Feed{Title: "Awesome Veganism Feed", Link: &feeds.Link{Href: "https://awesome-veganism.com/"}, Updated: when}
+		meta := make(map[*feeds.Item]*ItemMeta)
+		for n, name := range []string{"Tofu Town", "Oat Dream", "Seitan Co"} {
+			item := &feeds.Item{
+				Id:      fmt.Sprintf("tag:awesome-veganism.com,2024:%d", n),
+				Title:   "Addition of " + name,
+				Link:    &feeds.Link{Href: fmt.Sprintf("https://%d.example/", n)},
+				Author:  &feeds.Author{Name: "Alice"},
+				Created: when,
+			}
+			feed.Items = append(feed.Items, item)
+
+			fields := []field{{Name: "city", Value: "Berlin"}, {Name: "price", Value: "$$"}, {Name: "country", Value: "Germany"}}
+			rng.Shuffle(len(fields), func(i, j int) { fields[i], fields[j] = fields[j], fields[i] })
+			meta[item] = &ItemMeta{
+				Kind:     "Addition",
+				Name:     name,
+				Category: "Food",
+				File:     "lists/food.md",
+				Tags:     shuffled([]string{"tofu", "berlin", "Food", "organic", "tofu"}),
+				Fields:   fields,
+				Avatar:   "https://github.com/alice.png?size=64",
+			}
+		}
+		topics := shuffled([]string{"veganism", "awesome-list", "plant-based", "awesome", "veganism"})
+
+		var out []string
+		for _, doc := range []interface{}{atomDocument(feed, meta, "feed.xml", topics), rssDocument(feed, meta, "feed.rss", topics)} {
+			data, err := marshalFeed(doc, nil, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, data)
+		}
+		data, err := jsonFeed(feed, meta).ToJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// links of other relations in any order, as further extensions would add them
+		af := &feeds.AtomFeed{Title: feed.Title, Link: &feeds.AtomLink{Href: "https://awesome-veganism.com/"}}
+		var links []feeds.AtomLink
+		for _, rel := range shuffled([]string{"alternate", "icon", "related", "enclosure", "via"}) {
+			for _, href := range shuffled([]string{"https://b.example/", "https://a.example/"}) {
+				links = append(links, feeds.AtomLink{Href: href, Rel: rel})
+			}
+		}
+		af.Entries = []*feeds.AtomEntry{{Title: "Addition of Tofu Town", Links: links}}
+		entries, err := marshalFeed(newAtomXMLFeed(af, "feed.xml"), nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return strings.Join(append(out, data, entries), "\n")
+	}
+
+	want := render()
+	for n := 0; n < 50; n++ {
+		if got := render(); got != want {
+			t.Fatalf("round %d differs:\n%s", n, feedgentest.FirstDifference([]byte(got), []byte(want)))
+		}
+	}
+
+	for _, term := range []string{"<category>$$</category>", "<category>Berlin</category>", "<category>Food</category>", `rel="enclosure"`} {
+		if !strings.Contains(want, term) {
+			t.Errorf("%s missing", term)
+		}
+	}
+}
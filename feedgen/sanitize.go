@@ -0,0 +1,104 @@
+package feedgen
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/feeds"
+)
+
+// longest text kept in a single line field and in html content
+const (
+	maxTextLength    = 4096
+	maxContentLength = 65536
+)
+
+// sanitizeText makes commit derived text safe for every output format: invalid utf-8 is
+// replaced, control characters other than newline become spaces, and overlong text is cut
+func sanitizeText(s string, max int) string {
+	s = strings.ToValidUTF8(s, "�")
+
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r < 0x20 || r == 0x7f:
+			return ' '
+		case r >= 0x80 && r < 0xa0:
+			return ' '
+		case r == 0xfffe || r == 0xffff:
+			return '�'
+		}
+		return r
+	}, s)
+
+	if utf8.RuneCountInString(s) > max {
+		s = string([]rune(s)[:max-1]) + "…"
+	}
+
+	return s
+}
+
+// sanitizeFeed cleans all text of a feed and its items in place, including the item metadata
+func sanitizeFeed(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta) {
+	feed.Title = sanitizeText(feed.Title, maxTextLength)
+	feed.Description = sanitizeText(feed.Description, maxTextLength)
+
+	for _, item := range feed.Items {
+		item.Title = sanitizeText(item.Title, maxTextLength)
+		item.Description = sanitizeText(item.Description, maxTextLength)
+		item.Content = sanitizeText(item.Content, maxContentLength)
+		if item.Link != nil {
+			item.Link.Href = sanitizeText(item.Link.Href, maxTextLength)
+		}
+		if item.Author != nil {
+			item.Author.Name = sanitizeText(item.Author.Name, maxTextLength)
+		}
+
+		m := meta[item]
+		if m == nil {
+			continue
+		}
+		m.Name = sanitizeText(m.Name, maxTextLength)
+		m.Section = sanitizeText(m.Section, maxTextLength)
+		m.Diff = sanitizeText(m.Diff, maxContentLength)
+		for i := range m.Tags {
+			m.Tags[i] = sanitizeText(m.Tags[i], maxTextLength)
+		}
+		for i := range m.Neighbors {
+			m.Neighbors[i] = sanitizeText(m.Neighbors[i], maxTextLength)
+		}
+		for i := range m.Fields {
+			m.Fields[i].Value = sanitizeText(m.Fields[i].Value, maxTextLength)
+		}
+	}
+}
+
+// sanitizeRegistry returns cleaned copies of registry entries for publishing, the registry
+// itself keeps the names and urls as found so identities stay the same
+func sanitizeRegistry(entries []*registryEntry) []*registryEntry {
+	clean := make([]*registryEntry, 0, len(entries))
+	for _, e := range entries {
+		c := *e
+		c.Names = make([]string, len(e.Names))
+		for i, name := range e.Names {
+			c.Names[i] = sanitizeText(name, maxTextLength)
+		}
+		c.URLs = make([]string, len(e.URLs))
+		for i, u := range e.URLs {
+			c.URLs[i] = sanitizeText(u, maxTextLength)
+		}
+		c.Removals = nil
+		for _, r := range e.Removals {
+			rc := *r
+			rc.Name = sanitizeText(r.Name, maxTextLength)
+			rc.URL = sanitizeText(r.URL, maxTextLength)
+			rc.Author = sanitizeText(r.Author, maxTextLength)
+			rc.Reason = sanitizeText(r.Reason, maxTextLength)
+			c.Removals = append(c.Removals, &rc)
+		}
+		clean = append(clean, &c)
+	}
+
+	return clean
+}
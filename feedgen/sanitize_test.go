@@ -0,0 +1,109 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"Tofu Town", 100, "Tofu Town"},
+		{"Bad\tActor", 100, "Bad Actor"},
+		{"nul\x00byte", 100, "nul byte"},
+		{"line\nbreak\r\n", 100, "line\nbreak \n"},
+		{"del\x7f and c1\u0085", 100, "del  and c1 "},
+		{"invalid \xff\xfe utf-8", 100, "invalid � utf-8"},
+		{"noncharacter ￿", 100, "noncharacter �"},
+		{"Café Végétal", 100, "Café Végétal"},
+		{"Сумки без кожи", 6, "Сумки…"},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeText(tt.in, tt.max); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestHostileCommitMetadata feeds control characters, invalid utf-8 and absurd lengths from
+// author names, messages and the list itself through every output that shows them; the list
+// has no nul bytes since git takes such a file for binary and has no patch for it
+func TestHostileCommitMetadata(t *testing.T) {
+	long := strings.Repeat("very ", 4000) + "long"
+	history := goldenHistory()[:3]
+	hostile := listSnapshot("Mallory", 4, "Add\x00 entries\n\nImported\x1b[31m from a broken\x00 migration \xff\xfe.",
+		"Food",
+		"- [Tofu Town](https://tofu.example/) - All things tofu.",
+		"- [Bell\aBar](https://bell.example/) - Control\x07characters\x1b[0m here.",
+		"- [Broken \xc3\x28 UTF](https://broken.example/) - Invalid \xff bytes.",
+		"- [Long](https://long.example/) - "+long+".",
+		"Fashion",
+		"- [Vegan Shoes](https://shoes.example/) - Shoes without leather.",
+	)
+	hostile.Author = "Bad\tActor\x00\xff"
+	hostile.Email = "bad@example.org"
+	history = append(history, hostile)
+
+	files := generate(t, []string{
+		"-commit-body", "-prefer-commit-body", "-include-diff",
+		"-compat-feed", "legacy.xml", "-removed-page", "removed.html", "-removed-json", "removed.json", "-registry", "registry.json",
+	}, append(history, listSnapshot("Alice", 5, "Remove the imported entries", "Food", "- [Tofu Town](https://tofu.example/) - All things tofu."))...)
+
+	for _, name := range []string{"feed.xml", "feed.rss", "legacy.xml", "feed.json", "removed.html", "removed.json", "registry.json"} {
+		data, found := files[name]
+		if !found {
+			t.Errorf("%s was not written", name)
+			continue
+		}
+		if !utf8.Valid(data) {
+			t.Errorf("%s: invalid utf-8", name)
+		}
+		for _, b := range data {
+			if b < 0x20 && b != '\n' || b == 0x7f {
+				t.Errorf("%s: control character %#x", name, b)
+				break
+			}
+		}
+		if bytes.Contains(data, []byte("Bad\\tActor")) || bytes.Contains(data, []byte("\\u0000")) {
+			t.Errorf("%s: escaped control characters", name)
+		}
+
+		switch {
+		case strings.HasSuffix(name, ".json"):
+			var v interface{}
+			if err := json.Unmarshal(data, &v); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		case strings.HasSuffix(name, ".xml"), strings.HasSuffix(name, ".rss"):
+			d := xml.NewDecoder(bytes.NewReader(data))
+			for {
+				_, err := d.Token()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Errorf("%s: %v", name, err)
+					break
+				}
+			}
+		}
+	}
+
+	feed := string(files["feed.xml"])
+	for _, want := range []string{"Bad Actor", "Addition of Bell Bar", "Control characters", "Broken �( UTF", "Imported [31m from a broken  migration �."} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("feed.xml: %q missing", want)
+		}
+	}
+	if strings.Contains(feed, long) {
+		t.Error("feed.xml: overlong description kept")
+	}
+}
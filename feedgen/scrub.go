@@ -0,0 +1,111 @@
+package feedgen
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// name of the file in the list repository naming entries to remove from every output and from
+// all history, e.g. after a legal request
+const scrubFile = ".feedgen-remove"
+
+// scrubList holds the entry ids and urls of the scrub file, urls in identity form, and the
+// names of the entries found for them
+type scrubList struct {
+	ids   map[string]bool
+	urls  map[string]bool
+	names map[string]bool
+}
+
+// loadScrubList reads the scrub file at the given commit, one entry id or url per line with #
+// starting a comment; a missing file yields an empty list
+func loadScrubList(c *object.Commit, identity string) (*scrubList, error) {
+	s := &scrubList{ids: make(map[string]bool), urls: make(map[string]bool), names: make(map[string]bool)}
+
+	f, err := c.File(scrubFile)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, "://") || strings.HasPrefix(line, "mailto:") {
+			s.urls[urlIdentity(line, identity)] = true
+		} else {
+			s.ids[line] = true
+		}
+	}
+
+	return s, scanner.Err()
+}
+
+// matches reports whether an entry is to be scrubbed by its name or url
+func (s *scrubList) matches(name string, link string, identity string) bool {
+	return s.names[name] || s.urls[urlIdentity(link, identity)]
+}
+
+// scrub removes every trace of the listed entries from a collection: their items, whether
+// collected now or restored from a state, their registry entries with all tombstones and their
+// history; it returns the ids of the scrubbed entries and the number of items removed
+func scrub(col *collection, s *scrubList) ([]string, int) {
+	if len(s.ids) == 0 && len(s.urls) == 0 {
+		return nil, 0
+	}
+
+	var ids []string
+	scrubbed := make(map[string]bool)
+	var kept []*registryEntry
+	for _, e := range col.registry.entries {
+		found := s.ids[e.ID]
+		for _, u := range e.URLs {
+			found = found || s.urls[u]
+		}
+		if !found {
+			kept = append(kept, e)
+			continue
+		}
+
+		ids = append(ids, e.ID)
+		scrubbed[e.ID] = true
+		for _, name := range e.Names {
+			s.names[name] = true
+			delete(col.registry.byName, name)
+		}
+		delete(col.history, e.ID)
+	}
+	col.registry.entries = kept
+
+	var items []*feeds.Item
+	for _, item := range col.feed.Items {
+		m := col.meta[item]
+		if m != nil && (scrubbed[m.EntryID] || (m.EntryID == "" && s.names[m.Name])) {
+			delete(col.meta, item)
+			continue
+		}
+		if item.Link != nil && s.urls[urlIdentity(item.Link.Href, col.registry.identity)] {
+			delete(col.meta, item)
+			continue
+		}
+		items = append(items, item)
+	}
+	removed := len(col.feed.Items) - len(items)
+	col.feed.Items = items
+
+	return ids, removed
+}
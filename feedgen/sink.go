@@ -0,0 +1,259 @@
+package feedgen
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// OutputSink receives every generated artifact of a run
+type OutputSink interface {
+	// Write publishes a single artifact under the given name
+	Write(name string, contentType string, data []byte) error
+	// Finalize completes publishing after all artifacts were written
+	Finalize() error
+	// Read returns an artifact as an earlier run published it, failing with an error
+	// matching os.ErrNotExist when there is none
+	Read(name string) ([]byte, error)
+}
+
+// sinkReport collects the per file outcome of publishing
+type sinkReport struct {
+	Written []string
+	Skipped []string
+}
+
+func (r *sinkReport) String() string {
+	var parts []string
+	if len(r.Written) > 0 {
+		parts = append(parts, "files written: "+strings.Join(r.Written, ", "))
+	}
+	if len(r.Skipped) > 0 {
+		parts = append(parts, "files unchanged: "+strings.Join(r.Skipped, ", "))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// SinkOptions holds the settings of all output sinks
+type SinkOptions struct {
+	Output     string
+	GitBranch  string
+	S3Bucket   string
+	S3Prefix   string
+	S3Region   string
+	S3Endpoint string
+	// user@host:/dir to upload to with the sftp output
+	SFTPTarget     string
+	SFTPKey        string
+	SFTPKnownHosts string
+	SFTPInsecure   bool
+	Manifest       bool
+	// web server or host to write a snippet setting the cache control of every file for
+	HeadersFile string
+}
+
+// sinkOptions completes the sink settings with the ones derived from the run
+type sinkOptions struct {
+	SinkOptions
+	Destdir string
+	Cache   *cachePolicy
+	Client  *http.Client
+	// files the fs output must never overwrite, such as the work file
+	Protect []string
+}
+
+// newSink sets up the output sink selected in the options
+func newSink(opts sinkOptions, repo *git.Repository, report *sinkReport) (OutputSink, error) {
+	sink, err := newOutputSink(opts, repo, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// the manifest still comes last, after the headers snippet
+	later := make(map[string]string)
+	if opts.Manifest {
+		ms, err := newManifestSink(sink, opts.Cache)
+		if err != nil {
+			return nil, err
+		}
+		sink = ms
+		later[manifestFile] = "application/json"
+	}
+	if opts.HeadersFile != "" {
+		return newHeadersSink(sink, opts.HeadersFile, opts.Cache, later)
+	}
+
+	return sink, nil
+}
+
+func newOutputSink(opts sinkOptions, repo *git.Repository, report *sinkReport) (OutputSink, error) {
+	switch opts.Output {
+	case "fs":
+		return newFsSink(opts.Destdir, opts.Protect, report)
+	case "stdout":
+		return &stdoutSink{report: report}, nil
+	case "git":
+		if opts.GitBranch == "" {
+			return nil, fmt.Errorf("missing git branch")
+		}
+		// committing onto the checked out branch would leave the worktree behind its own branch
+		if head, err := repo.Head(); err == nil && head.Name() == plumbing.NewBranchReferenceName(opts.GitBranch) {
+			return nil, fmt.Errorf("refusing to commit feeds onto the checked out branch %s, use a separate branch like gh-pages", opts.GitBranch)
+		}
+		return &gitSink{repo: repo, branch: opts.GitBranch, report: report}, nil
+	case "s3":
+		return newS3Sink(opts.S3Bucket, opts.S3Prefix, opts.S3Region, opts.S3Endpoint, opts.Client, report)
+	case "sftp":
+		if opts.SFTPTarget == "" {
+			return nil, fmt.Errorf("missing sftp target")
+		}
+		return newSFTPSink(opts.SFTPTarget, opts.SFTPKey, opts.SFTPKnownHosts, opts.SFTPInsecure, report)
+	}
+
+	return nil, fmt.Errorf("unknown output: %s", opts.Output)
+}
+
+// StagingDir is the directory inside destdir artifacts are staged in before they are moved into place
+const StagingDir = ".feedgen-tmp"
+
+// fsSink writes artifacts into a local directory in two phases: all of them are first
+// staged in a temporary directory and only moved into place on Finalize, one rename per
+// file in the order they were written, with the manifest always last. Each rename is atomic
+// but the set is not, so a crash in between leaves feeds newer than the manifest, never older.
+type fsSink struct {
+	dir     string
+	protect []string
+	report  *sinkReport
+	staged  []string
+}
+
+func newFsSink(dir string, protect []string, report *sinkReport) (*fsSink, error) {
+	// a leftover staging directory belongs to a crashed run and is never completed
+	if err := os.RemoveAll(filepath.Join(dir, StagingDir)); err != nil {
+		return nil, fmt.Errorf("failed to remove staging directory: %v", err)
+	}
+
+	return &fsSink{dir: dir, protect: protect, report: report}, nil
+}
+
+func (s *fsSink) Write(name string, contentType string, data []byte) error {
+	path := filepath.Join(s.dir, name)
+
+	for _, p := range s.protect {
+		if sameFile(p, path) {
+			return fmt.Errorf("refusing to overwrite %s", path)
+		}
+	}
+
+	// leave files alone that already have the right content
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		s.report.Skipped = append(s.report.Skipped, path)
+		return nil
+	}
+
+	tmp := filepath.Join(s.dir, StagingDir, name)
+	if err := os.MkdirAll(filepath.Dir(tmp), 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %v", err)
+	}
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := chmodFile(f, 0644); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to change file permission: %s: %v", tmp, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	// make sure the content is on disk before the rename can expose it
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	s.staged = append(s.staged, name)
+
+	return nil
+}
+
+func (s *fsSink) Finalize() error {
+	// the manifest signals a complete run and therefore moves last
+	sort.SliceStable(s.staged, func(i, j int) bool {
+		return s.staged[i] != manifestFile && s.staged[j] == manifestFile
+	})
+
+	for _, name := range s.staged {
+		path := filepath.Join(s.dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := renameFile(filepath.Join(s.dir, StagingDir, name), path); err != nil {
+			return fmt.Errorf("failed to move file into place: %s: %v", path, err)
+		}
+
+		s.report.Written = append(s.report.Written, path)
+	}
+
+	return os.RemoveAll(filepath.Join(s.dir, StagingDir))
+}
+
+func (s *fsSink) Read(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, name))
+}
+
+// stdoutSink prints all artifacts to standard output
+type stdoutSink struct {
+	report *sinkReport
+}
+
+func (s *stdoutSink) Write(name string, contentType string, data []byte) error {
+	if _, err := os.Stdout.Write(data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		if _, err := os.Stdout.Write([]byte("\n")); err != nil {
+			return err
+		}
+	}
+
+	s.report.Written = append(s.report.Written, name)
+
+	return nil
+}
+
+func (s *stdoutSink) Finalize() error {
+	return nil
+}
+
+// Read finds nothing, printed artifacts are gone
+func (s *stdoutSink) Read(name string) ([]byte, error) {
+	return nil, os.ErrNotExist
+}
+
+// sameFile reports whether two paths name the same existing file
+func sameFile(a string, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(fa, fb)
+}
@@ -0,0 +1,204 @@
+package feedgen
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// gitSink commits all artifacts onto a branch of the repository without touching the worktree
+type gitSink struct {
+	repo   *git.Repository
+	branch string
+	files  map[string]object.TreeEntry
+	report *sinkReport
+}
+
+func (s *gitSink) Write(name string, contentType string, data []byte) error {
+	obj := s.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	hash, err := s.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+
+	if s.files == nil {
+		s.files = make(map[string]object.TreeEntry)
+	}
+	s.files[path.Clean(name)] = object.TreeEntry{Name: path.Clean(name), Mode: filemode.Regular, Hash: hash}
+
+	return nil
+}
+
+func (s *gitSink) Finalize() error {
+	refname := plumbing.NewBranchReferenceName(s.branch)
+
+	// start from the files already on the branch
+	files := make(map[string]object.TreeEntry)
+	var parents []plumbing.Hash
+	var oldTree plumbing.Hash
+
+	ref, err := s.repo.Reference(refname, true)
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+	case err != nil:
+		return err
+	default:
+		parent, err := s.repo.CommitObject(ref.Hash())
+		if err != nil {
+			return err
+		}
+		tree, err := parent.Tree()
+		if err != nil {
+			return err
+		}
+		err = tree.Files().ForEach(func(f *object.File) error {
+			files[f.Name] = object.TreeEntry{Name: f.Name, Mode: f.Mode, Hash: f.Hash}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		parents = append(parents, parent.Hash)
+		oldTree = parent.TreeHash
+	}
+
+	for name, e := range s.files {
+		if old, found := files[name]; found && old.Hash == e.Hash {
+			s.report.Skipped = append(s.report.Skipped, s.branch+":"+name)
+		} else {
+			s.report.Written = append(s.report.Written, s.branch+":"+name)
+		}
+		files[name] = e
+	}
+	sort.Strings(s.report.Written)
+	sort.Strings(s.report.Skipped)
+
+	treeHash, err := s.writeTree(files, "")
+	if err != nil {
+		return err
+	}
+
+	// nothing changed, so no commit needed
+	if treeHash == oldTree {
+		return nil
+	}
+
+	sig := object.Signature{Name: "awesome-veganism-feed", When: time.Now()}
+	commit := &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      "Update feeds\n",
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+
+	obj := s.repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return err
+	}
+	hash, err := s.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Storer.SetReference(plumbing.NewHashReference(refname, hash))
+}
+
+// Read returns a file of the last commit on the branch
+func (s *gitSink) Read(name string) ([]byte, error) {
+	ref, err := s.repo.Reference(plumbing.NewBranchReferenceName(s.branch), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, os.ErrNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	c, err := s.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.File(path.Clean(name))
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, os.ErrNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(content), nil
+}
+
+// writeTree stores the tree for all files below dir and returns its hash
+func (s *gitSink) writeTree(files map[string]object.TreeEntry, dir string) (plumbing.Hash, error) {
+	var entries []object.TreeEntry
+	subdirs := make(map[string]bool)
+
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	for name, e := range files {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		rest := strings.TrimPrefix(name, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			subdirs[rest[:i]] = true
+			continue
+		}
+
+		entries = append(entries, object.TreeEntry{Name: rest, Mode: e.Mode, Hash: e.Hash})
+	}
+
+	for sub := range subdirs {
+		hash, err := s.writeTree(files, prefix+sub)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: sub, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// git orders tree entries as if directory names had a trailing slash
+	sortName := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortName(entries[i]) < sortName(entries[j])
+	})
+
+	tree := &object.Tree{Entries: entries}
+	obj := s.repo.Storer.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return s.repo.Storer.SetEncodedObject(obj)
+}
@@ -0,0 +1,203 @@
+package feedgen
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Sink uploads artifacts into an S3 compatible bucket using signature version 4
+type s3Sink struct {
+	bucket   string
+	prefix   string
+	region   string
+	endpoint string
+	client   *http.Client
+	report   *sinkReport
+
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func newS3Sink(bucket string, prefix string, region string, endpoint string, client *http.Client, report *sinkReport) (*s3Sink, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("missing s3 bucket")
+	}
+
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	s := &s3Sink{
+		bucket:       bucket,
+		prefix:       prefix,
+		region:       region,
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		client:       client,
+		report:       report,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("missing AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY")
+	}
+
+	return s, nil
+}
+
+func (s *s3Sink) Write(name string, contentType string, data []byte) error {
+	key := s.prefix + name
+
+	// single part uploads carry the md5 of their content as etag
+	sum := md5.Sum(data)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	res, err := s.do(http.MethodHead, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK && res.Header.Get("ETag") == etag {
+		s.report.Skipped = append(s.report.Skipped, "s3://"+s.bucket+"/"+key)
+		return nil
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+
+	res, err = s.do(http.MethodPut, key, data, header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("failed to upload %s: %s: %s", key, res.Status, body)
+	}
+
+	s.report.Written = append(s.report.Written, "s3://"+s.bucket+"/"+key)
+
+	return nil
+}
+
+func (s *s3Sink) Finalize() error {
+	return nil
+}
+
+func (s *s3Sink) Read(name string) ([]byte, error) {
+	key := s.prefix + name
+
+	res, err := s.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(res.Body)
+	case http.StatusNotFound:
+		return nil, os.ErrNotExist
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+
+	return nil, fmt.Errorf("failed to download %s: %s: %s", key, res.Status, body)
+}
+
+// do sends a signed path style request for the given object key
+func (s *s3Sink) do(method string, key string, body []byte, header http.Header) (*http.Response, error) {
+	path := "/" + s.bucket + "/" + s3Escape(key)
+
+	req, err := http.NewRequest(method, s.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	signV4(req, path, payloadHash, s.accessKey, s.secretKey, s.region, "s3", time.Now())
+
+	return s.client.Do(req)
+}
+
+// signV4 adds the date and the signature version 4 authorization to a request, signing the
+// host and every header already set; path is the escaped path as the server sees it
+func signV4(req *http.Request, path string, payloadHash string, accessKey string, secretKey string, region string, service string, now time.Time) {
+	now = now.UTC()
+	amzdate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzdate)
+
+	signed := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		signed[strings.ToLower(k)] = strings.Join(strings.Fields(strings.Join(v, ",")), " ")
+	}
+	var names []string
+	for k := range signed {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, signed[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{req.Method, path, strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20"), canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzdate, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+// s3Escape encodes an object key the way signature version 4 expects it, keeping slashes
+func s3Escape(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,424 @@
+package feedgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// attempts of an upload before giving up, the connection is set up anew after each failure
+const sftpAttempts = 3
+
+// file in the target directory recording what the previous runs uploaded
+const sftpSumsFile = ".feedgen-sums.json"
+
+// sftpTarget is where the sftp output uploads to
+type sftpTarget struct {
+	User string
+	Addr string
+	Dir  string
+}
+
+// parseSFTPTarget reads user@host:/dir as scp does or sftp://user@host:port/dir, the user
+// defaults to the local one and the port to 22
+func parseSFTPTarget(s string) (*sftpTarget, error) {
+	t := &sftpTarget{}
+
+	if strings.HasPrefix(s, "sftp://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sftp target: %s: %v", s, err)
+		}
+		t.User = u.User.Username()
+		t.Addr = u.Host
+		t.Dir = u.Path
+	} else {
+		host, dir, found := strings.Cut(s, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid sftp target, expected user@host:/dir: %s", s)
+		}
+		if name, h, found := strings.Cut(host, "@"); found {
+			t.User, host = name, h
+		}
+		t.Addr = host
+		t.Dir = dir
+	}
+
+	if t.Addr == "" {
+		return nil, fmt.Errorf("missing host in sftp target: %s", s)
+	}
+	if _, _, err := net.SplitHostPort(t.Addr); err != nil {
+		t.Addr = net.JoinHostPort(t.Addr, "22")
+	}
+	if t.User == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("missing user in sftp target: %s", s)
+		}
+		t.User = u.Username
+	}
+	if t.Dir == "" {
+		t.Dir = "."
+	}
+
+	return t, nil
+}
+
+// sftpSum is what an upload left on the server: the checksum of the content together with
+// size and modification time as the server reported them right after the upload
+type sftpSum struct {
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+}
+
+// sftpSink uploads artifacts over sftp: each file goes to a temporary name first and is
+// renamed into place once complete. Files whose size and modification time on the server
+// still match the recorded upload of the same content are skipped without reading them.
+type sftpSink struct {
+	target *sftpTarget
+	config *ssh.ClientConfig
+	report *sinkReport
+	// connection to the ssh agent, kept open for reconnects
+	agent net.Conn
+
+	conn   *ssh.Client
+	client *sftp.Client
+	sums   map[string]sftpSum
+	dirty  bool
+}
+
+func newSFTPSink(target string, keyFile string, knownHostsFile string, insecure bool, report *sinkReport) (*sftpSink, error) {
+	t, err := parseSFTPTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sftpSink{target: t, report: report}
+
+	var auth []ssh.AuthMethod
+	if keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sftp key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sftp key: %s: %v", keyFile, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if c, err := net.Dial("unix", sock); err == nil {
+			s.agent = c
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(c).Signers))
+		}
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("missing sftp credentials, pass -sftp-key or run an ssh agent")
+	}
+
+	// the host key is verified unless explicitly asked not to
+	hostKey := ssh.InsecureIgnoreHostKey()
+	if !insecure {
+		if knownHostsFile == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				s.close()
+				return nil, fmt.Errorf("missing -sftp-known-hosts: %v", err)
+			}
+			knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+		}
+		if hostKey, err = knownhosts.New(knownHostsFile); err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to read known hosts: %v", err)
+		}
+	}
+
+	s.config = &ssh.ClientConfig{
+		User:            t.User,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         30 * time.Second,
+	}
+
+	// fail early on wrong credentials or host keys instead of after generating everything
+	if err := s.connect(); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// connect sets up the ssh connection and the sftp client on it, the recorded uploads are
+// read once with the first connection
+func (s *sftpSink) connect() error {
+	conn, err := ssh.Dial("tcp", s.target.Addr, s.config)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", s.target.Addr, err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start sftp on %s: %v", s.target.Addr, err)
+	}
+	s.conn, s.client = conn, client
+
+	if s.sums == nil {
+		if s.sums, err = s.readSums(); err != nil {
+			s.disconnect()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readSums loads the recorded uploads, a missing or unreadable record means nothing is known
+func (s *sftpSink) readSums() (map[string]sftpSum, error) {
+	sums := make(map[string]sftpSum)
+
+	f, err := s.client.Open(path.Join(s.target.Dir, sftpSumsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return sums, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &sums); err != nil {
+		return make(map[string]sftpSum), nil
+	}
+
+	return sums, nil
+}
+
+// disconnect closes the connection to the server, the agent stays available
+func (s *sftpSink) disconnect() error {
+	if s.client == nil {
+		return nil
+	}
+
+	s.client.Close()
+	err := s.conn.Close()
+	s.client, s.conn = nil, nil
+
+	return err
+}
+
+// close releases the server and agent connections
+func (s *sftpSink) close() error {
+	err := s.disconnect()
+	if s.agent != nil {
+		s.agent.Close()
+		s.agent = nil
+	}
+
+	return err
+}
+
+func (s *sftpSink) Write(name string, contentType string, data []byte) error {
+	remote := path.Join(s.target.Dir, name)
+
+	var err error
+	for attempt := 1; attempt <= sftpAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * time.Second)
+		}
+
+		if s.client == nil {
+			if err = s.connect(); err != nil {
+				continue
+			}
+		}
+
+		var skipped bool
+		skipped, err = s.upload(name, remote, data)
+		if err == nil {
+			where := "sftp://" + s.target.Addr + "/" + strings.TrimPrefix(remote, "/")
+			if skipped {
+				s.report.Skipped = append(s.report.Skipped, where)
+			} else {
+				s.report.Written = append(s.report.Written, where)
+			}
+			return nil
+		}
+
+		// the server refusing something does not get better by asking again
+		if sftpRefused(err) {
+			break
+		}
+		s.disconnect()
+	}
+
+	return fmt.Errorf("failed to upload %s: %v", remote, err)
+}
+
+func (s *sftpSink) Read(name string) ([]byte, error) {
+	remote := path.Join(s.target.Dir, name)
+
+	if s.client == nil {
+		if err := s.connect(); err != nil {
+			return nil, err
+		}
+	}
+	f, err := s.client.Open(remote)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// sftpRefused reports whether the server answered with an error, as opposed to the
+// connection failing
+func sftpRefused(err error) bool {
+	var serr *sftp.StatusError
+	var perr *os.PathError
+
+	return errors.As(err, &serr) || errors.As(err, &perr) || errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission)
+}
+
+// upload puts data at remote unless the recorded upload of it is still there, reporting
+// whether it was skipped
+func (s *sftpSink) upload(name string, remote string, data []byte) (bool, error) {
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+
+	fi, err := s.client.Stat(remote)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return false, err
+	case !fi.Mode().IsRegular():
+		return false, fmt.Errorf("not a regular file: %s", remote)
+	default:
+		if old, found := s.sums[name]; found && old.SHA256 == sum && old.Size == fi.Size() && old.ModTime == fi.ModTime().Unix() {
+			return true, nil
+		}
+	}
+
+	if err := s.put(remote, data); err != nil {
+		return false, err
+	}
+
+	// the server clock decides the modification time, so it is read back
+	if fi, err = s.client.Stat(remote); err != nil {
+		return false, err
+	}
+	s.sums[name] = sftpSum{SHA256: sum, Size: fi.Size(), ModTime: fi.ModTime().Unix()}
+	s.dirty = true
+
+	return false, nil
+}
+
+// put writes data to a temporary name next to remote and moves it into place
+func (s *sftpSink) put(remote string, data []byte) error {
+	if err := s.client.MkdirAll(path.Dir(remote)); err != nil {
+		return err
+	}
+
+	tmp := path.Join(path.Dir(remote), ".feedgen-tmp-"+path.Base(remote))
+	f, err := s.client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		s.client.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		s.client.Remove(tmp)
+		return err
+	}
+	// the permissions of new files are subject to the umask of the server
+	if err := s.client.Chmod(tmp, 0644); err != nil {
+		s.client.Remove(tmp)
+		return err
+	}
+	if err := s.replace(tmp, remote); err != nil {
+		s.client.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// replace moves a file over an existing one, atomically where the server supports it;
+// elsewhere the existing file is moved aside first and put back when the move fails
+func (s *sftpSink) replace(from string, to string) error {
+	if _, found := s.client.HasExtension("posix-rename@openssh.com"); found {
+		return s.client.PosixRename(from, to)
+	}
+
+	// plain renames refuse to replace files, but succeed when there is none yet
+	err := s.client.Rename(from, to)
+	if err == nil {
+		return nil
+	}
+
+	old := path.Join(path.Dir(to), ".feedgen-old-"+path.Base(to))
+	if s.client.Rename(to, old) != nil {
+		return err
+	}
+	if err := s.client.Rename(from, to); err != nil {
+		s.client.Rename(old, to)
+		return err
+	}
+
+	return s.client.Remove(old)
+}
+
+func (s *sftpSink) Finalize() error {
+	err := s.writeSums()
+	if cerr := s.close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// writeSums records the uploads of this run for the next one
+func (s *sftpSink) writeSums() error {
+	if !s.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(s.sums)
+	if err != nil {
+		return err
+	}
+	if s.client == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	if err := s.put(path.Join(s.target.Dir, sftpSumsFile), data); err != nil {
+		return fmt.Errorf("failed to record uploads: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package feedgen
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSFTPServer serves the local file system over sftp on a random port, accepting
+// only the returned client key; it returns the key file and the known hosts file
+func startSFTPServer(t *testing.T) (addr string, keyFile string, knownHostsFile string) {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshClientPub, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), sshClientPub.Marshal()) {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(c, config)
+		}
+	}()
+
+	dir := t.TempDir()
+	der, err := x509.MarshalPKCS8PrivateKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile = filepath.Join(dir, "id")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	knownHostsFile = filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(l.Addr().String())}, hostSigner.PublicKey())
+	if err := os.WriteFile(knownHostsFile, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return l.Addr().String(), keyFile, knownHostsFile
+}
+
+func serveSFTP(c net.Conn, config *ssh.ServerConfig) {
+	conn, chans, reqs, err := ssh.NewServerConn(c, config)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		ch, creqs, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range creqs {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					go func() {
+						if s, err := sftp.NewServer(ch); err == nil {
+							s.Serve()
+						}
+						ch.Close()
+					}()
+				}
+			}
+		}()
+	}
+}
+
+func newTestSFTPSink(t *testing.T, addr string, keyFile string, knownHostsFile string, dir string) (*sftpSink, *sinkReport) {
+	t.Helper()
+
+	report := &sinkReport{}
+	s, err := newSFTPSink("sftp://feeds@"+addr+dir, keyFile, knownHostsFile, false, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, report
+}
+
+func TestSFTPSinkUploadsAndSkipsUnchanged(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	addr, keyFile, knownHostsFile := startSFTPServer(t)
+	dir := t.TempDir()
+
+	s, report := newTestSFTPSink(t, addr, keyFile, knownHostsFile, dir)
+	if err := s.Write("feed.xml", "application/atom+xml", []byte("<feed/>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write("tags/tag-tofu.xml", "application/atom+xml", []byte("<feed>tofu</feed>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Written) != 2 || len(report.Skipped) != 0 {
+		t.Fatalf("first run: written %v, skipped %v", report.Written, report.Skipped)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "tags", "tag-tofu.xml"))
+	if err != nil || string(data) != "<feed>tofu</feed>" {
+		t.Fatalf("uploaded file: %q, %v", data, err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "feed.xml")); err != nil || fi.Mode().Perm() != 0644 {
+		t.Fatalf("uploaded file mode: %v, %v", fi, err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".feedgen-tmp-*")); len(matches) > 0 {
+		t.Fatalf("temporary files left: %v", matches)
+	}
+
+	// the same content is skipped, changed content uploaded
+	s, report = newTestSFTPSink(t, addr, keyFile, knownHostsFile, dir)
+	if err := s.Write("feed.xml", "application/atom+xml", []byte("<feed/>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write("tags/tag-tofu.xml", "application/atom+xml", []byte("<feed>seitan</feed>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Written) != 1 || len(report.Skipped) != 1 {
+		t.Fatalf("second run: written %v, skipped %v", report.Written, report.Skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "tags", "tag-tofu.xml")); string(data) != "<feed>seitan</feed>" {
+		t.Fatalf("replaced file: %q", data)
+	}
+}
+
+func TestSFTPSinkReuploadsChangedRemoteFile(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	addr, keyFile, knownHostsFile := startSFTPServer(t)
+	dir := t.TempDir()
+
+	s, _ := newTestSFTPSink(t, addr, keyFile, knownHostsFile, dir)
+	if err := s.Write("feed.xml", "application/atom+xml", []byte("<feed/>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	// someone else replaced the file with content of the same size
+	path := filepath.Join(dir, "feed.xml")
+	if err := os.WriteFile(path, []byte("<xxxx/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, report := newTestSFTPSink(t, addr, keyFile, knownHostsFile, dir)
+	if err := s.Write("feed.xml", "application/atom+xml", []byte("<feed/>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Written) != 1 {
+		t.Fatalf("changed remote file not uploaded again: written %v, skipped %v", report.Written, report.Skipped)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "<feed/>" {
+		t.Fatalf("remote file: %q", data)
+	}
+}
+
+func TestSFTPSinkRefusesFileAsDirectory(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	addr, keyFile, knownHostsFile := startSFTPServer(t)
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "tags"), []byte("not a directory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := newTestSFTPSink(t, addr, keyFile, knownHostsFile, dir)
+	defer s.Finalize()
+
+	if err := s.Write("tags/tag-tofu.xml", "application/atom+xml", []byte("<feed/>")); err == nil {
+		t.Fatal("uploading below a regular file succeeded")
+	}
+}
+
+func TestSFTPSinkRejectsUnknownHostKey(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	addr, keyFile, _ := startSFTPServer(t)
+	_, _, otherKnownHosts := startSFTPServer(t)
+
+	if _, err := newSFTPSink("sftp://feeds@"+addr+t.TempDir(), keyFile, otherKnownHosts, false, &sinkReport{}); err == nil {
+		t.Fatal("connected despite an unknown host key")
+	}
+}
+
+func TestParseSFTPTarget(t *testing.T) {
+	tests := []struct {
+		in   string
+		want sftpTarget
+	}{
+		{"feeds@example.org:/srv/www", sftpTarget{User: "feeds", Addr: "example.org:22", Dir: "/srv/www"}},
+		{"feeds@example.org:", sftpTarget{User: "feeds", Addr: "example.org:22", Dir: "."}},
+		{"sftp://feeds@example.org:2222/srv/www", sftpTarget{User: "feeds", Addr: "example.org:2222", Dir: "/srv/www"}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSFTPTarget(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.in, *got, tt.want)
+		}
+	}
+
+	if _, err := parseSFTPTarget("example.org"); err == nil {
+		t.Error("target without directory accepted")
+	}
+}
+
+func TestSFTPSinkRead(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	addr, keyFile, knownHostsFile := startSFTPServer(t)
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "timeseries.json"), []byte("[]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := newTestSFTPSink(t, addr, keyFile, knownHostsFile, dir)
+	defer s.Finalize()
+
+	if data, err := s.Read("timeseries.json"); err != nil || string(data) != "[]\n" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if _, err := s.Read("missing.json"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("reading a missing file: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+func TestSinkReadsWhatWasPublished(t *testing.T) {
+	r, err := feedgentest.NewRepository(feedgentest.Snapshot{
+		Files: map[string][]byte{"README.md": feedgentest.File("# List\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, output := range []string{"fs", "git"} {
+		opts := sinkOptions{SinkOptions: SinkOptions{Output: output, GitBranch: "gh-pages"}, Destdir: t.TempDir()}
+
+		sink, err := newSink(opts, r, &sinkReport{})
+		if err != nil {
+			t.Fatalf("%s: %v", output, err)
+		}
+		if _, err := sink.Read("timeseries.json"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: reading before the first run: %v", output, err)
+		}
+		if err := sink.Write("data/timeseries.json", "application/json", []byte("[]\n")); err != nil {
+			t.Fatalf("%s: %v", output, err)
+		}
+		if err := sink.Finalize(); err != nil {
+			t.Fatalf("%s: %v", output, err)
+		}
+
+		// a later run sees what the earlier one published
+		sink, err = newSink(opts, r, &sinkReport{})
+		if err != nil {
+			t.Fatalf("%s: %v", output, err)
+		}
+		data, err := sink.Read("data/timeseries.json")
+		if err != nil || string(data) != "[]\n" {
+			t.Errorf("%s: got %q, %v", output, data, err)
+		}
+		if _, err := sink.Read("missing.json"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: reading a missing file: %v", output, err)
+		}
+	}
+}
+
+func TestManifestComparesWithPublishedManifest(t *testing.T) {
+	r, err := feedgentest.NewRepository(feedgentest.Snapshot{
+		Files: map[string][]byte{"README.md": feedgentest.File("# List\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the destination directory stays empty, the previous manifest is only on the branch
+	opts := sinkOptions{SinkOptions: SinkOptions{Output: "git", GitBranch: "gh-pages", Manifest: true}, Destdir: t.TempDir()}
+	run := func(files map[string]string) map[string]bool {
+		t.Helper()
+
+		sink, err := newSink(opts, r, &sinkReport{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, data := range files {
+			if err := sink.Write(name, "application/xml", []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sink.Finalize(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := sink.Read(manifestFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &manifest{}
+		if err := json.Unmarshal(data, m); err != nil {
+			t.Fatal(err)
+		}
+		changed := make(map[string]bool)
+		for _, e := range m.Files {
+			changed[e.Name] = e.Changed
+		}
+
+		return changed
+	}
+
+	if got := run(map[string]string{"feed.xml": "<feed/>", "feed.rss": "<rss/>"}); !got["feed.xml"] || !got["feed.rss"] {
+		t.Errorf("first run: %v, want everything changed", got)
+	}
+	if got := run(map[string]string{"feed.xml": "<feed/>", "feed.rss": "<rss></rss>"}); got["feed.xml"] || !got["feed.rss"] {
+		t.Errorf("second run: %v, want only feed.rss changed", got)
+	}
+}
+
+func TestFsSinkFinalizeOrder(t *testing.T) {
+	dir := t.TempDir()
+
+	// a crashed run left a half staged feed behind, which must never be moved into place
+	if err := os.MkdirAll(filepath.Join(dir, StagingDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, StagingDir, "feed.rss"), []byte("<rss"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := newCachePolicy(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := &sinkReport{}
+	sink, err := newSink(sinkOptions{SinkOptions: SinkOptions{Output: "fs", Manifest: true, HeadersFile: "netlify"}, Destdir: dir, Cache: policy}, nil, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, StagingDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("leftover staging directory still there: %v", err)
+	}
+
+	for _, name := range []string{"feed.xml", "feed.json", "index.html"} {
+		if err := sink.Write(name, "text/plain", []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+		// nothing is visible before the end of the run
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s in place before finalize: %v", name, err)
+		}
+	}
+	if err := sink.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	// feeds in the order they were written, then the index, the headers and the manifest last
+	var got []string
+	for _, p := range report.Written {
+		got = append(got, filepath.Base(p))
+	}
+	want := []string{"feed.xml", "feed.json", "index.html", "_headers", manifestFile}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("moved into place in order %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "feed.rss")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("leftover of the crashed run published: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, StagingDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("staging directory kept after the run: %v", err)
+	}
+}
+
+func TestFsSinkFailedFinalizeKeepsManifest(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a directory in the way of a feed makes its rename fail
+	if err := os.MkdirAll(filepath.Join(dir, "feed.json", "blocked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := newSink(sinkOptions{SinkOptions: SinkOptions{Output: "fs", Manifest: true}, Destdir: dir}, nil, &sinkReport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"feed.xml", "feed.json"} {
+		if err := sink.Write(name, "text/plain", []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Finalize(); err == nil {
+		t.Fatal("finalize succeeded with a feed that cannot be moved into place")
+	}
+
+	// the feeds before the failure are newer than the manifest, which still is the old one
+	if data, err := os.ReadFile(filepath.Join(dir, "feed.xml")); err != nil || string(data) != "feed.xml" {
+		t.Errorf("feed.xml: %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, manifestFile)); err != nil || string(data) != "{}\n" {
+		t.Errorf("manifest replaced after a failed run: %q, %v", data, err)
+	}
+
+	// the next run starts over without the staged files of the failed one
+	if _, err := newSink(sinkOptions{SinkOptions: SinkOptions{Output: "fs"}, Destdir: dir}, nil, &sinkReport{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, StagingDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("staging directory of the failed run kept: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package feedgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// kinds of change that squashing merges, moves and groups are left alone
+var squashKinds = map[string]bool{"Addition": true, "Removal": true, "Update": true}
+
+// squashChanges merges the changes to an entry that one author made within window of the first
+// of them into a single item with their net effect: the final url and description under the
+// date and id of the first change. An entry added and removed again disappears entirely, and
+// a change by another author in between starts a new group. It returns the number of items
+// dropped.
+func squashChanges(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta, window time.Duration, context bool) int {
+	// the order of the history walk follows committer dates, items are dated by their authors
+	items := append([]*feeds.Item{}, feed.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Created.Before(items[j].Created)
+	})
+
+	var groups [][]*feeds.Item
+	open := make(map[string]int)
+	for _, item := range items {
+		m := meta[item]
+		if m == nil || m.EntryID == "" || !squashKinds[m.Kind] {
+			continue
+		}
+
+		if n, found := open[m.EntryID]; found {
+			first := groups[n][0]
+			if itemAuthor(first) == itemAuthor(item) && item.Created.Sub(first.Created) <= window {
+				groups[n] = append(groups[n], item)
+				continue
+			}
+		}
+		open[m.EntryID] = len(groups)
+		groups = append(groups, []*feeds.Item{item})
+	}
+
+	drop := make(map[*feeds.Item]bool)
+	replace := make(map[*feeds.Item]*feeds.Item)
+	for _, g := range groups {
+		if len(g) < 2 {
+			continue
+		}
+		for _, item := range g {
+			drop[item] = true
+		}
+
+		first, last := g[0], g[len(g)-1]
+		existed, exists := meta[first].Kind != "Addition", meta[last].Kind != "Removal"
+		if !existed && !exists {
+			continue
+		}
+
+		kind := "Update"
+		if !existed {
+			kind = "Addition"
+		} else if !exists {
+			kind = "Removal"
+		}
+
+		merged := *last
+		merged.Id, merged.Created = first.Id, first.Created
+		m := *meta[last]
+		if kind != m.Kind {
+			merged.Title = fmt.Sprintf("%s of %s", kind, m.Name)
+			if context {
+				merged.Description = recontext(merged.Description, m.Kind, kind)
+				// the description is the first paragraph of the content
+				if i := strings.Index(merged.Content, "</p>"); i >= 0 {
+					merged.Content = recontext(merged.Content[:i], m.Kind, kind) + merged.Content[i:]
+				}
+			}
+			m.Kind = kind
+		}
+		m.ClampedFrom = meta[first].ClampedFrom
+		meta[&merged] = &m
+		replace[first] = &merged
+	}
+	if len(drop) == 0 {
+		return 0
+	}
+
+	kept := feed.Items[:0]
+	for _, item := range feed.Items {
+		if merged, found := replace[item]; found {
+			kept = append(kept, merged)
+		} else if !drop[item] {
+			kept = append(kept, item)
+		}
+	}
+	dropped := len(feed.Items) - len(kept)
+	feed.Items = kept
+
+	if len(feed.Items) > 0 {
+		feed.Updated = time.Time{}
+		for _, item := range feed.Items {
+			if item.Created.After(feed.Updated) {
+				feed.Updated = item.Created
+			}
+		}
+	}
+
+	return dropped
+}
+
+// itemAuthor is the name of the author of an item, empty when unknown
+func itemAuthor(item *feeds.Item) string {
+	if item.Author == nil {
+		return ""
+	}
+
+	return item.Author.Name
+}
+
+// verbs of the context sentence by kind of change, and at the top or bottom of a section
+var (
+	contextVerbs = map[string]string{"Addition": "Added to", "Removal": "Removed from", "Update": "Updated in"}
+	contextEdges = map[string]string{"Addition": "Added to", "Removal": "Removed from", "Update": "Updated at"}
+)
+
+// recontext rewrites the context sentence at the end of a text from one kind of change to another
+func recontext(s string, from string, to string) string {
+	at, n, edge := -1, 0, false
+	for _, where := range []string{" the top of ", " the bottom of "} {
+		if i := strings.LastIndex(s, contextEdges[from]+where); i > at {
+			at, n, edge = i, len(contextEdges[from]), true
+		}
+	}
+	if i := strings.LastIndex(s, contextVerbs[from]+" "); i > at {
+		at, n, edge = i, len(contextVerbs[from]), false
+	}
+	if at < 0 {
+		return s
+	}
+
+	verb := contextVerbs[to]
+	if edge {
+		verb = contextEdges[to]
+	}
+
+	return s[:at] + verb + s[at+n:]
+}
@@ -0,0 +1,97 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+// TestSquashInterleavedAuthors has two maintainers fixing up their own entries in turns, and
+// touching the same entry one after the other
+func TestSquashInterleavedAuthors(t *testing.T) {
+	const (
+		tofu    = "- [Tofu Town](https://tofu.example/) - All things tofu."
+		oat     = "- [Oat Dream](https://oat.example/) - Oat milk for coffee."
+		oat2    = "- [Oat Dream](https://oatdream.example/) - Oat milk for coffee."
+		oat3    = "- [Oat Dream](https://oatdream.example/) - Oat milk for coffee and tea."
+		seitan  = "- [Seitan Co](https://seitan.exmaple/) - Wheat meats."
+		seitan2 = "- [Seitan Co](https://seitan.example/) - Wheat meats."
+		seitan3 = "- [Seitan Co](https://seitan.example/) - Wheat based meats."
+		seitan4 = "- [Seitan Co](https://seitan.example/) - Wheat based meats and more."
+		shoes   = "- [Vegan Shoes](https://shoes.example/) - Shoes."
+		shoes2  = "- [Vegan Shoes](https://shoes.example/) - Shoes without leather."
+		bags    = "- [Bags](https://bags.example/) - Bags."
+	)
+
+	at := func(s feedgentest.Snapshot, minute int) feedgentest.Snapshot {
+		s.When = time.Date(2024, time.March, 1, 10, minute, 0, 0, time.UTC)
+		return s
+	}
+	history := []feedgentest.Snapshot{
+		at(listSnapshot("Alice", 1, "Start the list", "Food", tofu, oat, "Fashion"), 0),
+		at(listSnapshot("Alice", 1, "Add Seitan Co", "Food", tofu, oat, seitan, "Fashion"), 10),
+		at(listSnapshot("Bob", 1, "Add Vegan Shoes", "Food", tofu, oat, seitan, "Fashion", shoes), 12),
+		at(listSnapshot("Alice", 1, "Fix Seitan Co url", "Food", tofu, oat, seitan2, "Fashion", shoes), 14),
+		at(listSnapshot("Bob", 1, "Describe Vegan Shoes", "Food", tofu, oat, seitan2, "Fashion", shoes2), 15),
+		at(listSnapshot("Alice", 1, "Fix Seitan Co description", "Food", tofu, oat, seitan3, "Fashion", shoes2), 16),
+		at(listSnapshot("Bob", 1, "Add Bags", "Food", tofu, oat, seitan3, "Fashion", shoes2, bags), 17),
+		at(listSnapshot("Alice", 1, "Update Oat Dream url", "Food", tofu, oat2, seitan3, "Fashion", shoes2, bags), 18),
+		at(listSnapshot("Bob", 1, "Remove Bags again", "Food", tofu, oat2, seitan3, "Fashion", shoes2), 19),
+		at(listSnapshot("Bob", 1, "Update Oat Dream description", "Food", tofu, oat3, seitan3, "Fashion", shoes2), 20),
+		at(listSnapshot("Alice", 1, "Extend Seitan Co", "Food", tofu, oat3, seitan4, "Fashion", shoes2), 40),
+	}
+
+	items := func(data []byte) []string {
+		var doc struct {
+			Entries []struct {
+				Title   string `xml:"title"`
+				Updated string `xml:"updated"`
+				Link    struct {
+					Href string `xml:"href,attr"`
+				} `xml:"link"`
+				Summary string `xml:"summary"`
+				Author  string `xml:"author>name"`
+			} `xml:"entry"`
+		}
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+
+		var list []string
+		for _, e := range doc.Entries {
+			list = append(list, fmt.Sprintf("%s %s by %s: %s %s", e.Updated[11:16], e.Title, e.Author, e.Link.Href, e.Summary))
+		}
+		return list
+	}
+
+	files := generate(t, []string{"-squash-window", "15m"}, history...)
+	got := items(files["feed.xml"])
+	want := []string{
+		// the three quick changes of Alice to her entry are one addition with the final state
+		"10:10 Addition of Seitan Co by Alice: https://seitan.example/ Wheat based meats.",
+		"10:12 Addition of Vegan Shoes by Bob: https://shoes.example/ Shoes without leather.",
+		// Bob's change to the entry Alice just updated is his own, bags vanished altogether
+		"10:18 Update of Oat Dream by Alice: https://oatdream.example/ Oat milk for coffee.",
+		"10:20 Update of Oat Dream by Bob: https://oatdream.example/ Oat milk for coffee and tea.",
+		// outside of the window from the addition
+		"10:40 Update of Seitan Co by Alice: https://seitan.example/ Wheat based meats and more.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got items\n%q\nwant\n%q", got, want)
+	}
+
+	// without a window every change is an item of its own
+	if n := len(items(generate(t, nil, history...)["feed.xml"])); n != 10 {
+		t.Errorf("%d items without squashing, want 10", n)
+	}
+
+	// the grouping does not depend on anything but the history
+	if again := generate(t, []string{"-squash-window", "15m"}, history...); !bytes.Equal(again["feed.xml"], files["feed.xml"]) {
+		t.Error("second run differs")
+	}
+}
@@ -0,0 +1,272 @@
+package feedgen
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// ageValue is a flag value accepting go durations plus days, weeks and years, e.g. 90d or 3y
+type ageValue time.Duration
+
+func (a *ageValue) String() string {
+	if a == nil || *a == 0 {
+		return ""
+	}
+
+	return time.Duration(*a).String()
+}
+
+func (a *ageValue) Set(s string) error {
+	d, err := parseAge(s)
+	if err != nil {
+		return err
+	}
+	*a = ageValue(d)
+
+	return nil
+}
+
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+
+	for suffix, unit := range units {
+		if !strings.HasSuffix(s, suffix) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %s", s)
+		}
+
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age: %s", s)
+	}
+
+	return d, nil
+}
+
+// entry is a single list item as found in the work file
+type entry struct {
+	Name        string
+	URL         string
+	Description string
+	// heading the entry is listed under
+	Section string
+	// file the entry is listed in
+	File string
+}
+
+// currentEntries parses all list items of the files at the given commit in the order of the
+// files, a missing file is an error only when all of them are missing
+func currentEntries(c *object.Commit, workfiles []string) ([]entry, error) {
+	var entries []entry
+	missing := 0
+	for _, workfile := range workfiles {
+		f, err := c.File(workfile)
+		if errors.Is(err, object.ErrFileNotFound) && len(workfiles) > 1 {
+			missing++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := f.Contents()
+		if err != nil {
+			return nil, err
+		}
+
+		if entries, err = parseEntries(entries, contents, workfile); err != nil {
+			return nil, err
+		}
+	}
+	if missing == len(workfiles) {
+		return nil, object.ErrFileNotFound
+	}
+
+	return entries, nil
+}
+
+// parseEntries appends the list items of the contents of a file to entries
+func parseEntries(entries []entry, contents string, file string) ([]entry, error) {
+	var section string
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+		if strings.HasPrefix(line, "#") {
+			section = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+
+		// definition style entries have their description indented on the lines below
+		if name, url, ok := ParseTitle(line); ok && n+1 < len(lines) && IsDescription(lines[n+1]) {
+			var desc []string
+			for ; n+1 < len(lines) && IsDescription(lines[n+1]); n++ {
+				desc = append(desc, strings.TrimSpace(lines[n+1]))
+			}
+			entries = append(entries, entry{Name: name, URL: url, Description: strings.Join(desc, " "), Section: section, File: file})
+		} else if name, url, desc, ok := ParseEntry(line); ok {
+			entries = append(entries, entry{Name: name, URL: url, Description: desc, Section: section, File: file})
+		} else if name, url, desc, ok := ParseHTMLEntry(line); ok {
+			entries = append(entries, entry{Name: name, URL: url, Description: desc, Section: section, File: file})
+		}
+	}
+
+	return entries, nil
+}
+
+// entryHistory tracks when an entry was first added and last changed
+type entryHistory struct {
+	Added   time.Time
+	Touched time.Time
+	Hash    string
+}
+
+// recordHistory updates the per entry history index with the matches of one commit
+func recordHistory(history map[string]*entryHistory, reg *registry, matches [][]string, changes map[string]int, c *object.Commit) {
+	// count identical lines so a pure move of an entry does not count as a change
+	lines := make(map[string]map[string]int)
+	for _, m := range matches {
+		x := 1
+		if m[1] == "-" {
+			x = -1
+		}
+
+		id := reg.id(m[2])
+		if lines[id] == nil {
+			lines[id] = make(map[string]int)
+		}
+		lines[id][m[2]+" "+m[3]+" "+m[4]] += x
+	}
+
+	// renamed entries show up under both names
+	net := make(map[string]int)
+	for name, v := range changes {
+		net[reg.id(name)] += v
+	}
+
+	for id, v := range net {
+		h, found := history[id]
+		if !found {
+			h = &entryHistory{}
+			history[id] = h
+		}
+		if v > 0 && h.Added.IsZero() {
+			h.Added = c.Author.When
+		}
+
+		touched := v != 0
+		for _, n := range lines[id] {
+			if n != 0 {
+				touched = true
+			}
+		}
+
+		if touched {
+			h.Touched = c.Author.When
+			h.Hash = c.Hash.String()
+		}
+	}
+}
+
+// staleFeed lists current entries that were not changed within the given age as review candidates
+func staleFeed(feed *feeds.Feed, entries []entry, history map[string]*entryHistory, reg *registry, age time.Duration, now time.Time, authority string) *feeds.Feed {
+	// date everything to the generation period so readers see a monthly update
+	period := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	stale := &feeds.Feed{
+		Title:       feed.Title + ": Entries to Review",
+		Link:        feed.Link,
+		Description: fmt.Sprintf("Entries unchanged for more than %s.", formatAge(age)),
+		Created:     feed.Created,
+		Updated:     period,
+	}
+
+	for _, e := range entries {
+		h := history[reg.id(e.Name)]
+
+		// entries from before the first processed commit date back to the creation of the feed
+		added, touched, hash := feed.Created, feed.Created, ""
+		if h != nil && !h.Added.IsZero() {
+			added = h.Added
+		}
+		if h != nil && !h.Touched.IsZero() {
+			touched, hash = h.Touched, h.Hash
+		}
+
+		if now.Sub(touched) < age {
+			continue
+		}
+
+		// absolute dates only, so the feed stays the same as long as the entries do
+		section := ""
+		if e.Section != "" {
+			section = fmt.Sprintf(" in %s", e.Section)
+		}
+		stale.Items = append(stale.Items, &feeds.Item{
+			Title:       fmt.Sprintf("Review of %s", e.Name),
+			Link:        &feeds.Link{Href: e.URL},
+			Description: fmt.Sprintf("Listed%s, added on %s, last changed on %s: %s", section, added.Format("2006-01-02"), touched.Format("2006-01-02"), e.Description),
+			// stable as long as the entry stays untouched
+			Id:      fmt.Sprintf("tag:%s,%d:stale/%s/%s", authority, touched.UTC().Year(), slug(e.Name), hash),
+			Created: period,
+		})
+	}
+
+	return stale
+}
+
+// formatAge renders a duration in the largest sensible unit
+func formatAge(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d >= 365*day:
+		return fmt.Sprintf("%dy", d/(365*day))
+	case d >= day:
+		return fmt.Sprintf("%dd", d/day)
+	}
+
+	return d.Round(time.Second).String()
+}
+
+// slug reduces a name to lowercase letters, digits and dashes
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
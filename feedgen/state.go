@@ -0,0 +1,287 @@
+package feedgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/gorilla/feeds"
+)
+
+// stateItem is a feed item as collected from the history, before grouping and sanitizing
+type stateItem struct {
+	ID          string    `json:"id,omitempty"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Description string    `json:"description"`
+	Content     string    `json:"content,omitempty"`
+	Author      string    `json:"author"`
+	Created     time.Time `json:"created"`
+	Meta        *ItemMeta `json:"meta"`
+}
+
+// flags that change what the history walk collects, everything else is applied to the
+// collected items on every run and may differ between runs sharing a state
+var collectionFlags = map[string]bool{
+	"workfile": true, "files": true, "path-prefix": true, "follow-submodule": true,
+	"link": true, "github-meta": true, "id-authority": true, "no-avatars": true,
+	"context": true, "include-diff": true, "diff-limit": true, "max-line-length": true,
+	"max-patch-size": true, "merges": true, "restructure": true, "repo-url": true,
+	"section-categories": true, "sections": true, "filter-feed": true, "positions": true,
+	"category-move-items": true, "commit-body": true, "prefer-commit-body": true,
+	"exclude-entry": true, "invalid-url": true, "allowed-schemes": true, "url-identity": true,
+	"suffix-pattern": true, "meta-file": true, "compat": true, "tiered": true, "full-window": true,
+}
+
+// collectionFingerprint hashes the flags changed from their default that affect collecting
+func collectionFingerprint(fs *flag.FlagSet) string {
+	return flagsFingerprint(fs, func(name string) bool {
+		return collectionFlags[name]
+	})
+}
+
+// collectState is everything a run needs to continue where the previous one stopped; it is
+// only reused when it was produced by the same version from the same settings
+type collectState struct {
+	Version  string                   `json:"version"`
+	Parser   string                   `json:"parser"`
+	Options  string                   `json:"options"`
+	Config   string                   `json:"config"`
+	Workfile string                   `json:"workfile"`
+	Commits  []string                 `json:"commits"`
+	Updated  time.Time                `json:"updated"`
+	Items    []stateItem              `json:"items"`
+	Registry []*registryEntry         `json:"registry"`
+	History  map[string]*entryHistory `json:"history"`
+	Warnings []*warningClass          `json:"warnings"`
+	Excluded int                      `json:"excluded"`
+	Gaps     map[string]string        `json:"gaps,omitempty"`
+}
+
+// loadState reads the state of the previous run, a missing file yields nil
+func loadState(path string) (*collectState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	st := &collectState{}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// saveState replaces the state file atomically, it is kept next to the outputs but never
+// published since it is only meant for the next run
+func saveState(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return renameFile(tmp, path)
+}
+
+// configHash identifies the repository configuration a state was produced with
+func configHash(rcfg *repoConfig) string {
+	data, _ := json.Marshal(rcfg)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:6])
+}
+
+// usable reports whether the state was produced by this version with the same settings
+func (st *collectState) usable(o *options, workfiles []string) bool {
+	return st.Version == Version && st.Parser == parserVersion && st.Options == o.collectFingerprint &&
+		st.Workfile == strings.Join(workfiles, ",") && len(st.Commits) > 0
+}
+
+// logCommits lists the commits of the log newest first, up to and including the stop commit
+// when given; found reports whether the stop commit was reached
+func logCommits(r *git.Repository, opts *git.LogOptions, stop string) (commits []*object.Commit, found bool, err error) {
+	iter, err := r.Log(opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+
+		if stop != "" && c.Hash.String() == stop {
+			found = true
+			return storer.ErrStop
+		}
+
+		return nil
+	})
+
+	return commits, found, err
+}
+
+// continues reports whether every commit added since the state comes after its newest commit
+// in the log, so their items can be appended to those of the state; a merged branch with
+// commits older than that, or the state of a branch merged later, needs the full history
+func (st *collectState) continues(r *git.Repository, head plumbing.Hash) (bool, error) {
+	stop, err := r.CommitObject(plumbing.NewHash(st.Commits[0]))
+	if err != nil {
+		return false, err
+	}
+
+	processed := make(map[plumbing.Hash]bool)
+	err = object.NewCommitPreorderIter(stop, nil, nil).ForEach(func(c *object.Commit) error {
+		processed[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	c, err := r.CommitObject(head)
+	if err != nil {
+		return false, err
+	}
+	ok := true
+	err = object.NewCommitIterCTime(c, processed, nil).ForEach(func(c *object.Commit) error {
+		if !c.Committer.When.After(stop.Committer.When) {
+			ok = false
+			return storer.ErrStop
+		}
+		return nil
+	})
+
+	return ok, err
+}
+
+// olderCommits loads the commits of the state after the newest one, which the log already returned
+func (st *collectState) olderCommits(r *git.Repository) ([]*object.Commit, error) {
+	var commits []*object.Commit
+	for _, h := range st.Commits[1:] {
+		c, err := r.CommitObject(plumbing.NewHash(h))
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+
+	return commits, nil
+}
+
+// restore puts the items, entry history and warnings of the state into a collection
+func (st *collectState) restore(col *collection) {
+	for _, si := range st.Items {
+		item := &feeds.Item{
+			Id:          si.ID,
+			Title:       si.Title,
+			Link:        &feeds.Link{Href: si.Link},
+			Description: si.Description,
+			Content:     si.Content,
+			Author:      &feeds.Author{Name: si.Author},
+			Created:     si.Created,
+		}
+		col.feed.Items = append(col.feed.Items, item)
+		col.meta[item] = si.Meta
+	}
+	col.feed.Updated = st.Updated
+
+	for _, e := range st.Registry {
+		col.registry.entries = append(col.registry.entries, e)
+		for _, name := range e.Names {
+			col.registry.byName[name] = e
+		}
+	}
+	for id, h := range st.History {
+		col.history[id] = h
+	}
+
+	col.warnings.merge(st.Warnings)
+	col.excluded += st.Excluded
+
+	for f, h := range st.Gaps {
+		col.gaps[f] = plumbing.NewHash(h)
+	}
+}
+
+// snapshot captures a collection right after the history walk
+func snapshot(o *options, col *collection, rcfg *repoConfig) ([]byte, error) {
+	st := &collectState{
+		Version:  Version,
+		Parser:   parserVersion,
+		Options:  o.collectFingerprint,
+		Config:   configHash(rcfg),
+		Workfile: strings.Join(col.workfiles, ","),
+		Updated:  col.feed.Updated,
+		Items:    []stateItem{},
+		Registry: col.registry.entries,
+		History:  col.history,
+		Excluded: col.excluded,
+	}
+
+	for _, c := range col.commits {
+		st.Commits = append(st.Commits, c.Hash.String())
+	}
+
+	if len(col.gaps) > 0 {
+		st.Gaps = make(map[string]string)
+		for f, h := range col.gaps {
+			st.Gaps[f] = h.String()
+		}
+	}
+
+	for _, item := range col.feed.Items {
+		si := stateItem{
+			ID:          item.Id,
+			Title:       item.Title,
+			Link:        item.Link.Href,
+			Description: item.Description,
+			Content:     item.Content,
+			Created:     item.Created,
+			Meta:        col.meta[item],
+		}
+		if item.Author != nil {
+			si.Author = item.Author.Name
+		}
+		st.Items = append(st.Items, si)
+	}
+
+	// the branch check is repeated on every run and never part of the history
+	for _, c := range col.warnings.classes {
+		if c.Name != "default-branch" {
+			st.Warnings = append(st.Warnings, c)
+		}
+	}
+
+	return json.Marshal(st)
+}
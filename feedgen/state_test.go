@@ -0,0 +1,101 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+// TestStateUpgradeFromV1 continues from a state written by an older parser, which has to be
+// ignored in favour of the full history since its items may have other identities
+func TestStateUpgradeFromV1(t *testing.T) {
+	history := goldenHistory()
+	full := generate(t, nil, history...)
+
+	for _, tt := range []struct {
+		name    string
+		parser  string
+		rebuild bool
+	}{
+		{"current", parserVersion, false},
+		{"v1", "1.0.0", true},
+		{"unversioned", "", true},
+	} {
+		dir := t.TempDir()
+		args := []string{"-state", "state.json"}
+		generateInto(t, dir, args, history[:6]...)
+
+		// keep the commits but forget the items, so a reused state shows in the feed
+		path := filepath.Join(dir, "state.json")
+		var st map[string]interface{}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &st); err != nil {
+			t.Fatal(err)
+		}
+		if tt.parser == "" {
+			delete(st, "parser")
+		} else {
+			st["parser"] = tt.parser
+		}
+		st["items"] = []interface{}{}
+		data, err = json.Marshal(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var logged bytes.Buffer
+		log.SetOutput(&logged)
+		files := generateInto(t, dir, args, history...)
+		log.SetOutput(os.Stderr)
+
+		rebuilt := bytes.Equal(files["feed.xml"], full["feed.xml"])
+		if rebuilt != tt.rebuild {
+			t.Errorf("%s: full rebuild %v, want %v", tt.name, rebuilt, tt.rebuild)
+		}
+		noted := strings.Contains(logged.String(), "processing the full history with parser version "+parserVersion)
+		if noted != tt.rebuild {
+			t.Errorf("%s: rebuild logged %v, want %v:\n%s", tt.name, noted, tt.rebuild, logged.String())
+		}
+		if _, found := files["state.json"]; !found {
+			t.Errorf("%s: state not written", tt.name)
+		}
+
+		// the state written by the upgrade is the current one
+		if st, err := loadState(path); err != nil || st.Parser != parserVersion {
+			t.Errorf("%s: state after the run: %+v, %v", tt.name, st, err)
+		}
+	}
+}
+
+// TestIncrementalRunMatchesFullRun generates up to a commit, continues to the head from the
+// state and compares the outputs with those of a single run over the whole history
+func TestIncrementalRunMatchesFullRun(t *testing.T) {
+	history := goldenHistory()
+	args := []string{"-registry", "registry.json"}
+	full := generate(t, args, history...)
+
+	for n := 1; n < len(history); n++ {
+		dir := t.TempDir()
+		incremental := append([]string{"-state", "state.json"}, args...)
+		generateInto(t, dir, incremental, history[:n]...)
+		files := generateInto(t, dir, incremental, history...)
+
+		for _, name := range []string{"feed.xml", "feed.json", "feed.rss", "registry.json"} {
+			if !bytes.Equal(files[name], full[name]) {
+				t.Errorf("continued after commit %d: %s differs from the full run: %s", n, name, feedgentest.FirstDifference(files[name], full[name]))
+			}
+		}
+	}
+}
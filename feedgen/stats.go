@@ -0,0 +1,146 @@
+package feedgen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Count is a named number, used for rankings
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// YearStats holds the changes of a single year
+type YearStats struct {
+	Year    int `json:"year"`
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Updated int `json:"updated"`
+}
+
+// Stats summarizes the history of the list
+type Stats struct {
+	Added          int         `json:"added"`
+	Removed        int         `json:"removed"`
+	Updated        int         `json:"updated"`
+	Years          []YearStats `json:"years"`
+	Contributors   []Count     `json:"top_contributors"`
+	Categories     []Count     `json:"top_categories"`
+	CurrentEntries int         `json:"current_entries"`
+	OldestEntry    string      `json:"oldest_entry,omitempty"`
+	OldestAdded    *time.Time  `json:"oldest_entry_added,omitempty"`
+}
+
+// Summarize walks the history without writing any files and computes its statistics, with
+// the top contributors and categories
+func Summarize(ctx context.Context, cfg Config, top int) (*Stats, error) {
+	o, err := newOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// categories are only known when the sections of changed entries are looked up
+	o.sections = true
+
+	col, err := collect(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return summarize(col, top)
+}
+
+// summarize computes the statistics of a collection
+func summarize(col *collection, top int) (*Stats, error) {
+	st := &Stats{}
+
+	years := make(map[int]*YearStats)
+	contributors := make(map[string]int)
+	categories := make(map[string]int)
+
+	for _, item := range col.feed.Items {
+		m := col.meta[item]
+		if m == nil {
+			continue
+		}
+
+		y := item.Created.Year()
+		if years[y] == nil {
+			years[y] = &YearStats{Year: y}
+		}
+
+		switch m.Kind {
+		case "Addition":
+			st.Added++
+			years[y].Added++
+		case "Removal":
+			st.Removed++
+			years[y].Removed++
+		case "Update":
+			st.Updated++
+			years[y].Updated++
+		}
+
+		if item.Author != nil {
+			contributors[item.Author.Name]++
+		}
+		if m.Section != "" {
+			categories[m.Section]++
+		}
+	}
+
+	st.Years = []YearStats{}
+	for _, y := range years {
+		st.Years = append(st.Years, *y)
+	}
+	sort.Slice(st.Years, func(i, j int) bool {
+		return st.Years[i].Year < st.Years[j].Year
+	})
+
+	st.Contributors = ranking(contributors, top)
+	st.Categories = ranking(categories, top)
+
+	entries, err := currentEntries(col.commits[0], col.workfiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current entries: %v", err)
+	}
+	st.CurrentEntries = len(entries)
+
+	for _, e := range entries {
+		// entries from before the first processed commit date back to the creation of the feed
+		added := col.feed.Created
+		if h := col.history[col.registry.id(e.Name)]; h != nil && !h.Added.IsZero() {
+			added = h.Added
+		}
+
+		if st.OldestAdded == nil || added.Before(*st.OldestAdded) {
+			a := added
+			st.OldestEntry, st.OldestAdded = e.Name, &a
+		}
+	}
+
+	return st, nil
+}
+
+// ranking sorts counts descending, then by name, and keeps the first n
+func ranking(counts map[string]int, n int) []Count {
+	list := []Count{}
+	for name, c := range counts {
+		list = append(list, Count{Name: name, Count: c})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	if len(list) > n {
+		list = list[:n]
+	}
+
+	return list
+}
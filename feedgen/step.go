@@ -0,0 +1,20 @@
+package feedgen
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// Step is the outcome of processing a single pair of neighbouring commits
+type Step struct {
+	Parent *object.Commit
+	Commit *object.Commit
+	// extracted lines of the list files, each the line, its sign, name, url and description
+	Matches [][]string
+	// net change of every entry name, zero for a move
+	Changes map[string]int
+	// items created by the commit
+	Items []*feeds.Item
+	// per item data of all items so far
+	Meta map[*feeds.Item]*ItemMeta
+}
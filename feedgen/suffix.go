@@ -0,0 +1,86 @@
+package feedgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// suffixPattern names a pattern matched against the end of entry descriptions
+type suffixPattern struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// field is a named value split off an entry description
+type field struct {
+	Name  string
+	Value string
+}
+
+// suffixRule is a compiled suffix pattern
+type suffixRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// parseSuffixFlag splits a name=regex flag value
+func parseSuffixFlag(s string) (suffixPattern, error) {
+	name, pattern, found := strings.Cut(s, "=")
+	if !found || name == "" || pattern == "" {
+		return suffixPattern{}, fmt.Errorf("invalid suffix pattern, expected name=regex: %s", s)
+	}
+
+	return suffixPattern{Name: name, Pattern: pattern}, nil
+}
+
+func newSuffixRules(patterns []suffixPattern) ([]suffixRule, error) {
+	var rules []suffixRule
+	for _, p := range patterns {
+		if p.Name == "" {
+			return nil, fmt.Errorf("missing name of suffix pattern: %s", p.Pattern)
+		}
+
+		// patterns only ever apply to the end of a description
+		expr := p.Pattern
+		if !strings.HasSuffix(expr, "$") {
+			expr += "$"
+		}
+
+		re, err := regexp.Compile(`\s*` + expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid suffix pattern: %s: %v", p.Name, err)
+		}
+		rules = append(rules, suffixRule{name: p.Name, re: re})
+	}
+
+	return rules, nil
+}
+
+// splitSuffixes strips matching suffixes off a description, each rule applied once in order,
+// the value of a field is the first capture group or the whole suffix without one
+func splitSuffixes(desc string, rules []suffixRule) (string, []field) {
+	var fields []field
+	for _, r := range rules {
+		loc := r.re.FindStringSubmatchIndex(desc)
+		if loc == nil {
+			continue
+		}
+
+		value := desc[loc[0]:loc[1]]
+		if len(loc) > 2 && loc[2] >= 0 {
+			value = desc[loc[2]:loc[3]]
+		}
+		value = strings.TrimSpace(value)
+
+		rest := strings.TrimSpace(desc[:loc[0]])
+		if value == "" || rest == "" {
+			continue
+		}
+
+		desc = rest
+		fields = append(fields, field{Name: r.name, Value: value})
+	}
+
+	return desc, fields
+}
@@ -0,0 +1,78 @@
+package feedgen
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// a tag is a word-like token after a hash, so neither headings (##) nor issue references (#123) qualify
+var tagRe = regexp.MustCompile(`^#[\p{L}][\p{L}\p{N}_-]*$`)
+
+// splitTags strips trailing hashtags off a description and returns them in order of appearance
+func splitTags(desc string) (string, []string) {
+	fields := strings.Fields(desc)
+
+	n := len(fields)
+	for n > 0 && tagRe.MatchString(fields[n-1]) {
+		n--
+	}
+	if n == len(fields) {
+		return desc, nil
+	}
+
+	var tags []string
+	for _, f := range fields[n:] {
+		tags = append(tags, strings.ToLower(strings.TrimPrefix(f, "#")))
+	}
+
+	// keep the original spacing of the remaining description
+	rest := desc
+	for i := len(fields) - 1; i >= n; i-- {
+		rest = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(rest), fields[i]))
+	}
+
+	return rest, tags
+}
+
+// tagFeeds splits the items of a feed into one feed per tag
+func tagFeeds(feed *feeds.Feed, meta map[*feeds.Item]*ItemMeta) map[string]*feeds.Feed {
+	tagged := make(map[string]*feeds.Feed)
+	for _, item := range feed.Items {
+		m := meta[item]
+		if m == nil {
+			continue
+		}
+
+		for _, tag := range m.Tags {
+			f, found := tagged[tag]
+			if !found {
+				f = &feeds.Feed{
+					Title:       feed.Title + ": #" + tag,
+					Link:        feed.Link,
+					Description: feed.Description,
+					Created:     feed.Created,
+				}
+				tagged[tag] = f
+			}
+
+			f.Items = append(f.Items, item)
+			f.Updated = item.Created
+		}
+	}
+
+	return tagged
+}
+
+// sortedKeys returns the keys of a feed map in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
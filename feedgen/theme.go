@@ -0,0 +1,225 @@
+package feedgen
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// css of the built in themes, inlined into the removed entries page and the feed stylesheet
+var themeCSS = map[string]string{
+	"plain": `body { margin: 2em auto; max-width: 60em; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.25em 0.5em; text-align: left; vertical-align: top; }
+`,
+	"light": `body { margin: 2em auto; max-width: 60em; padding: 0 1em; font-family: system-ui, sans-serif; line-height: 1.5; color: #1b1f1d; background: #fafaf7; }
+a { color: #2e7d32; }
+h1 { border-bottom: 2px solid #a5d6a7; padding-bottom: 0.25em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.4em 0.6em; text-align: left; vertical-align: top; border-bottom: 1px solid #e0e0d8; }
+th { background: #e8f5e9; }
+.entry { margin: 1.5em 0; padding: 0.75em 1em; background: #ffffff; border-left: 4px solid #81c784; }
+.updated { color: #5f6b62; font-size: 0.9em; }
+`,
+	"dark": `body { margin: 2em auto; max-width: 60em; padding: 0 1em; font-family: system-ui, sans-serif; line-height: 1.5; color: #e4e8e5; background: #141816; }
+a { color: #81c784; }
+h1 { border-bottom: 2px solid #2e7d32; padding-bottom: 0.25em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.4em 0.6em; text-align: left; vertical-align: top; border-bottom: 1px solid #2c332f; }
+th { background: #1e2a22; }
+.entry { margin: 1.5em 0; padding: 0.75em 1em; background: #1b211d; border-left: 4px solid #388e3c; }
+.updated { color: #9aa59d; font-size: 0.9em; }
+`,
+}
+
+// feedStylesheet renders an atom feed as a page in browsers, generated with the css of the theme
+const feedStylesheet = `<?xml version="1.0" encoding="utf-8"?>
+<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform" xmlns:atom="http://www.w3.org/2005/Atom">
+<xsl:output method="html" encoding="utf-8" doctype-system="about:legacy-compat"/>
+<xsl:template match="/atom:feed">
+<html>
+<head>
+<meta charset="utf-8"/>
+<title><xsl:value-of select="atom:title"/></title>
+<style>
+{{xml .CSS}}</style>
+</head>
+<body>
+<h1><xsl:value-of select="atom:title"/></h1>
+<p><xsl:value-of select="atom:subtitle"/></p>
+<xsl:for-each select="atom:entry">
+<div class="entry">
+<h2><a href="{atom:link/@href}"><xsl:value-of select="atom:title"/></a></h2>
+<p class="updated"><xsl:value-of select="substring(atom:updated, 1, 10)"/></p>
+<p><xsl:value-of select="atom:summary"/></p>
+</div>
+</xsl:for-each>
+</body>
+</html>
+</xsl:template>
+</xsl:stylesheet>
+`
+
+// files of a theme directory that are templates or the css, everything else is an asset
+const (
+	themeCSSFile        = "theme.css"
+	themeRemovedFile    = "removed.html"
+	themeStylesheetFile = "feed.xsl"
+)
+
+// theme is the look of the removed entries page and of the generated feed stylesheet: the css
+// of a built in theme, unless a theme directory overrides it or the templates themselves
+type theme struct {
+	css        string
+	removed    *htmltemplate.Template
+	stylesheet *template.Template
+	// files copied into destdir as they are, by their slash separated path in the theme directory
+	assets map[string][]byte
+}
+
+// loadTheme sets up a built in theme with the overrides of a theme directory, when given; the
+// templates need to parse and every relative reference of them and of the css needs to name
+// an asset of the directory
+func loadTheme(name string, dir string) (*theme, error) {
+	css, found := themeCSS[name]
+	if !found {
+		return nil, fmt.Errorf("unknown theme: %s", name)
+	}
+
+	th := &theme{css: css, removed: removedPage, assets: make(map[string][]byte)}
+	stylesheet := feedStylesheet
+	if dir == "" {
+		th.stylesheet = template.Must(newStylesheetTemplate(stylesheet))
+		return th, nil
+	}
+
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("not a regular file: %s", p)
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme directory: %v", err)
+	}
+
+	var removed string
+	for name, data := range files {
+		switch name {
+		case themeCSSFile:
+			th.css = string(data)
+		case themeRemovedFile:
+			removed = string(data)
+		case themeStylesheetFile:
+			stylesheet = string(data)
+		default:
+			th.assets[name] = data
+		}
+	}
+
+	if removed != "" {
+		tmpl, err := htmltemplate.New("removed").Funcs(removedFuncs).Parse(removed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid theme template: %s: %v", themeRemovedFile, err)
+		}
+		th.removed = tmpl
+	}
+	if th.stylesheet, err = newStylesheetTemplate(stylesheet); err != nil {
+		return nil, fmt.Errorf("invalid theme template: %s: %v", themeStylesheetFile, err)
+	}
+
+	checks := map[string]string{themeCSSFile: th.css, themeRemovedFile: removed, themeStylesheetFile: stylesheet}
+	for _, file := range sortedKeys(checks) {
+		for _, ref := range themeReferences(checks[file]) {
+			if th.assets[ref] == nil {
+				return nil, fmt.Errorf("theme file %s references a missing asset: %s", file, ref)
+			}
+		}
+	}
+
+	return th, nil
+}
+
+// newStylesheetTemplate parses a feed stylesheet template, whose xml function escapes the css
+// for the text of the style element
+func newStylesheetTemplate(text string) (*template.Template, error) {
+	escaper := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+	return template.New("stylesheet").Funcs(template.FuncMap{"xml": escaper.Replace}).Parse(text)
+}
+
+// references of css and templates to other files: url() of css, href and src attributes
+var themeReferenceRe = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)|(?:href|src)\s*=\s*"([^"]*)"`)
+
+// themeReferences lists the files a theme file references relative to itself, leaving out
+// urls, absolute paths, fragments and references computed by the templates
+func themeReferences(text string) []string {
+	var refs []string
+	for _, m := range themeReferenceRe.FindAllStringSubmatch(text, -1) {
+		ref := m[1] + m[2]
+		if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "/") || strings.ContainsAny(ref, "{:") {
+			continue
+		}
+		ref, _, _ = strings.Cut(ref, "#")
+		ref, _, _ = strings.Cut(ref, "?")
+		refs = append(refs, path.Clean(ref))
+	}
+
+	return refs
+}
+
+// files lists the names the theme writes into destdir: the stylesheet, when given, and the assets
+func (th *theme) files(stylesheet string) []string {
+	var names []string
+	if stylesheet != "" {
+		names = append(names, stylesheet)
+	}
+
+	return append(names, sortedKeys(th.assets)...)
+}
+
+// write generates the feed stylesheet, when given, and copies the assets through the sink, so
+// they are staged, skipped when unchanged and listed in the manifest like every other output
+func (th *theme) write(sink OutputSink, stylesheet string) error {
+	if stylesheet != "" {
+		var b strings.Builder
+		if err := th.stylesheet.Execute(&b, struct{ CSS string }{th.css}); err != nil {
+			return fmt.Errorf("failed to generate stylesheet: %v", err)
+		}
+		if err := sink.Write(stylesheet, "application/xslt+xml", []byte(b.String())); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedKeys(th.assets) {
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		if err := sink.Write(name, contentType, th.assets[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
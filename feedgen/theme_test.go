@@ -0,0 +1,143 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wellFormed fails the test unless data is well formed xml
+func wellFormed(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("%s is not well formed: %v", name, err)
+		}
+	}
+}
+
+func TestThemes(t *testing.T) {
+	args := []string{"-removed-page", "removed.html", "-stylesheet", "feed.xsl"}
+	plain := generate(t, args, goldenHistory()...)
+	if plain["feed.xsl"] != nil {
+		t.Error("stylesheet generated without a theme")
+	}
+	if bytes.Contains(plain["removed.html"], []byte("<style>")) {
+		t.Error("removed entries page styled without a theme")
+	}
+
+	for name, css := range themeCSS {
+		t.Run(name, func(t *testing.T) {
+			files := generate(t, append([]string{"-theme", name}, args...), goldenHistory()...)
+
+			wellFormed(t, "feed.xsl", files["feed.xsl"])
+			if !bytes.Contains(files["feed.xsl"], []byte(css)) {
+				t.Errorf("css of the theme missing from the stylesheet:\n%s", files["feed.xsl"])
+			}
+			if !bytes.Contains(files["removed.html"], []byte("<style>\n"+css+"</style>")) {
+				t.Errorf("css of the theme missing from the removed entries page:\n%s", files["removed.html"])
+			}
+
+			// the feeds only reference the stylesheet and stay the same
+			for _, feed := range []string{"feed.xml", "feed.json", "feed.rss"} {
+				if !bytes.Equal(files[feed], plain[feed]) {
+					t.Errorf("%s differs with a theme", feed)
+				}
+			}
+		})
+	}
+}
+
+func TestThemeDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"theme.css":    "body { background: url(img/leaf.svg) no-repeat; }\n",
+		"img/leaf.svg": `<svg xmlns="http://www.w3.org/2000/svg"/>`,
+		"removed.html": "<html><head><style>{{.CSS}}</style><link rel=\"icon\" href=\"img/leaf.svg\"></head><body>{{range .Entries}}<p>{{.Name}} {{date .Removed}}</p>{{end}}</body></html>\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := generate(t, []string{"-theme", "dark", "-theme-dir", dir, "-removed-page", "removed.html", "-stylesheet", "feed.xsl", "-manifest"}, goldenHistory()...)
+
+	if string(files["img/leaf.svg"]) != `<svg xmlns="http://www.w3.org/2000/svg"/>` {
+		t.Errorf("asset not copied: %q", files["img/leaf.svg"])
+	}
+	if files["theme.css"] != nil || files["removed.html"] == nil || bytes.Contains(files["removed.html"], []byte("{{")) {
+		t.Error("templates of the theme copied as assets")
+	}
+	if got := string(files["removed.html"]); !strings.HasPrefix(got, "<html><head><style>body { background: url(img/leaf.svg) no-repeat; }\n</style>") || !strings.Contains(got, "<p>Vegan Shoes 2024-03-05</p>") {
+		t.Errorf("removed entries page not rendered by the theme template:\n%s", got)
+	}
+	if !bytes.Contains(files["feed.xsl"], []byte("url(img/leaf.svg)")) {
+		t.Errorf("css of the theme directory missing from the stylesheet:\n%s", files["feed.xsl"])
+	}
+
+	// theme files take part in the manifest like every other output
+	var m manifest
+	if err := json.Unmarshal(files[manifestFile], &m); err != nil {
+		t.Fatal(err)
+	}
+	listed := make(map[string]bool)
+	for _, e := range m.Files {
+		listed[e.Name] = true
+	}
+	for _, name := range []string{"feed.xsl", "img/leaf.svg", "removed.html"} {
+		if !listed[name] {
+			t.Errorf("%s missing from the manifest", name)
+		}
+	}
+}
+
+func TestLoadThemeValidates(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{"missing asset", map[string]string{"theme.css": "body { background: url('bg.png'); }"}, "references a missing asset: bg.png"},
+		{"asset outside", map[string]string{"theme.css": "body { background: url(../bg.png); }"}, "references a missing asset: ../bg.png"},
+		{"missing template asset", map[string]string{"removed.html": `<img src="logo.png">`}, "removed.html references a missing asset: logo.png"},
+		{"invalid page template", map[string]string{"removed.html": "{{range .Entries}}"}, "invalid theme template: removed.html"},
+		{"invalid stylesheet template", map[string]string{"feed.xsl": "{{xml .CSS"}, "invalid theme template: feed.xsl"},
+		{"urls and fragments", map[string]string{"theme.css": "body { background: url(https://cdn.example/bg.png); } a { background: url(#x); }", "removed.html": `<a href="/">home</a><a href="{{.Title}}">x</a>`}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := loadTheme("light", dir)
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("error %v, want %s", err, tt.err)
+			}
+		})
+	}
+
+	if _, err := loadTheme("sepia", ""); err == nil {
+		t.Error("unknown theme accepted")
+	}
+}
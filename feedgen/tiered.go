@@ -0,0 +1,237 @@
+package feedgen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Window is the part of the history the tiered mode processes commit by commit, either a
+// number of commits like 1000 or an age before the newest commit like 2y
+type Window struct {
+	Commits int
+	Age     time.Duration
+}
+
+func (w *Window) String() string {
+	if w == nil || (w.Commits == 0 && w.Age == 0) {
+		return ""
+	}
+	if w.Age > 0 {
+		return w.Age.String()
+	}
+
+	return strconv.Itoa(w.Commits)
+}
+
+func (w *Window) Set(s string) error {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 {
+			return fmt.Errorf("invalid window, expected at least one commit: %s", s)
+		}
+		*w = Window{Commits: n}
+		return nil
+	}
+
+	d, err := parseAge(s)
+	if err != nil || d == 0 {
+		return fmt.Errorf("invalid window, neither a number of commits nor an age: %s", s)
+	}
+	*w = Window{Age: d}
+
+	return nil
+}
+
+// tiers is the history walk of the tiered mode: the commits of the full window one by one,
+// older history in monthly chunks of the first parent chain, each the difference between the
+// newest commit of a month and that of the month before
+type tiers struct {
+	// commits to walk and the index of the oldest one, like the log they replace
+	commits []*object.Commit
+	start   int
+	// oldest commit processed one by one
+	window *object.Commit
+	// base of every chunk by the commit closing it
+	bases map[plumbing.Hash]*object.Commit
+	// commits of every chunk in the order the full walk processes them
+	chunks map[plumbing.Hash][]*object.Commit
+	// whether merges are only compared with their first parent
+	firstParent bool
+}
+
+// splitTiers splits the commits of a log up to start, newest first, into the full window and
+// monthly chunks below it; nil means there is no history below the window. A commit below the
+// window that only gets merged within it is no part of any chunk and is processed one by one.
+func splitTiers(commits []*object.Commit, start int, head *object.Commit, window Window, firstParent bool) (*tiers, error) {
+	w := 0
+	for w < start {
+		if window.Age > 0 && !commits[w].Committer.When.After(head.Committer.When.Add(-window.Age)) {
+			break
+		}
+		if window.Age == 0 && w == window.Commits {
+			break
+		}
+		w++
+	}
+	if w == 0 || w >= start {
+		return nil, nil
+	}
+
+	mainline, err := firstParents(head)
+	if err != nil {
+		return nil, err
+	}
+
+	// the newest commit of every month below the window closes a chunk
+	var boundaries []int
+	month := ""
+	for n := w; n < start; n++ {
+		c := commits[n]
+		if !mainline[c.Hash] {
+			continue
+		}
+		if m := c.Committer.When.UTC().Format("2006-01"); m != month {
+			boundaries = append(boundaries, n)
+			month = m
+		}
+	}
+	if len(boundaries) == 0 {
+		return nil, nil
+	}
+
+	// whatever the newest chunk does not contain yet is merged later and walked one by one
+	contained := make(map[plumbing.Hash]bool)
+	err = object.NewCommitPreorderIter(commits[boundaries[0]], nil, nil).ForEach(func(c *object.Commit) error {
+		contained[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tiers{
+		window:      commits[w-1],
+		bases:       make(map[plumbing.Hash]*object.Commit),
+		chunks:      make(map[plumbing.Hash][]*object.Commit),
+		firstParent: firstParent,
+	}
+	for i, b := range boundaries {
+		end := start
+		if i+1 < len(boundaries) {
+			end = boundaries[i+1]
+		}
+		t.bases[commits[b].Hash] = commits[end]
+
+		for n := end - 1; n >= b; n-- {
+			if contained[commits[n].Hash] && (!firstParent || mainline[commits[n].Hash]) {
+				t.chunks[commits[b].Hash] = append(t.chunks[commits[b].Hash], commits[n])
+			}
+		}
+	}
+
+	for n, c := range commits[:start] {
+		if n < w || !contained[c.Hash] || t.bases[c.Hash] != nil {
+			t.commits = append(t.commits, c)
+		}
+	}
+	t.start = len(t.commits)
+	t.commits = append(t.commits, commits[start:]...)
+
+	return t, nil
+}
+
+// base is what a commit closing a chunk is compared with, nil for every other commit
+func (t *tiers) base(c plumbing.Hash) *object.Commit {
+	if t == nil {
+		return nil
+	}
+
+	return t.bases[c]
+}
+
+// refine dates the registry entries first seen in a chunk by the commit of the chunk the full
+// walk would have first seen them in: the one whose list file got the added line of the entry,
+// or lost the removed one, compared with all of its parents. Where no such commit is found,
+// like for the line of an entry that only moved, the commit closing the chunk stays.
+func (t *tiers) refine(c plumbing.Hash, entries []*registryEntry, matches [][]string, workfiles []string) error {
+	chunk := t.chunks[c]
+	if len(entries) == 0 || len(chunk) == 0 {
+		return nil
+	}
+
+	contents := make(map[string]string)
+	content := func(c *object.Commit, file string) (string, error) {
+		key := c.Hash.String() + "\x00" + file
+		if s, found := contents[key]; found {
+			return s, nil
+		}
+		s := ""
+		f, err := c.File(file)
+		if err == nil {
+			s, err = f.Contents()
+		} else if errors.Is(err, object.ErrFileNotFound) {
+			err = nil
+		}
+		if err != nil {
+			return "", err
+		}
+		contents[key] = s
+		return s, nil
+	}
+	has := func(c *object.Commit, file string, line string) (bool, error) {
+		s, err := content(c, file)
+		return strings.Contains("\n"+s+"\n", "\n"+line+"\n"), err
+	}
+
+	for _, e := range entries {
+		// the match the entry was registered by, a removal is seen before an addition
+		var match []string
+		for _, m := range matches {
+			if m[2] == e.Names[0] && (match == nil || m[1] == "-") {
+				match = m
+			}
+		}
+		if match == nil {
+			continue
+		}
+		file := matchFile(match)
+		if file == "" {
+			file = workfiles[0]
+		}
+		line, added := match[0][1:], match[1] == "+"
+
+		for _, cc := range chunk {
+			found, err := has(cc, file, line)
+			if err != nil {
+				return err
+			}
+			if found != added {
+				continue
+			}
+
+			bases, err := parents(cc, t.firstParent)
+			if err != nil {
+				return err
+			}
+			changed := len(bases) > 0
+			for _, b := range bases {
+				if found, err := has(b, file, line); err != nil {
+					return err
+				} else if found == added {
+					changed = false
+				}
+			}
+			if changed {
+				e.seen(cc)
+				break
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+// tieredHistory spans five months with updates in the last two of entries added in the first
+// three, one of them before the list was first seen, and a branch started below the window
+// that is merged within it
+func tieredHistory() []feedgentest.Snapshot {
+	const (
+		tofu    = "- [Tofu Town](https://tofu.example/) - All things tofu."
+		oat     = "- [Oat Dream](https://oat.example/) - Oat milk for coffee."
+		oat2    = "- [Oat Dream](https://oatdream.example/) - Oat milk for coffee."
+		oat3    = "- [Oat Dream](https://oatdream.example/) - Oat milk for coffee and tea."
+		cafe    = "- [Café Végétal](https://café.example/) - Crème brûlée ohne Ei."
+		cafe2   = "- [Café Végétal](https://café.example/) - Crème brûlée ohne Ei, jeden Tag."
+		seitan  = "- [Seitan Co](https://seitan.example/) - Wheat based meats."
+		seitan2 = "- [Seitan Co](https://seitan.example/shop) - Wheat based meats."
+		shoes   = "- [Vegan Shoes](https://shoes.example/) - Shoes without leather."
+		bags    = "- [Bags](https://bags.example/) - Bags without leather."
+		soy     = "- [Soy Co](https://soy.example/) - Soy based everything."
+	)
+
+	at := func(s feedgentest.Snapshot, month time.Month, day int) feedgentest.Snapshot {
+		s.When = time.Date(2024, month, day, 9, 30, 0, 0, time.UTC)
+		return s
+	}
+
+	s := []feedgentest.Snapshot{
+		at(listSnapshot("Alice", 1, "Start the list", "Food", tofu, oat, "Fashion", shoes), time.January, 5),
+		at(listSnapshot("Bob", 1, "Add Café Végétal", "Food", tofu, cafe, oat, "Fashion", shoes), time.January, 20),
+		at(listSnapshot("Alice", 1, "Add Seitan Co", "Food", tofu, cafe, oat, seitan, "Fashion", shoes), time.February, 3),
+		at(listSnapshot("Bob", 1, "Update Oat Dream url", "Food", tofu, cafe, oat2, seitan, "Fashion", shoes), time.February, 17),
+		at(listSnapshot("Chloé Dupont", 1, "Add Bags", "Food", tofu, cafe, oat2, seitan, "Fashion", shoes, bags), time.March, 2),
+		at(listSnapshot("Дмитрий", 1, "Add Soy Co", "Food", tofu, cafe, oat2, seitan, soy, "Fashion", shoes, bags), time.March, 9),
+		at(listSnapshot("Alice", 1, "Remove Vegan Shoes", "Food", tofu, cafe, oat2, seitan, "Fashion", bags), time.March, 23),
+		at(listSnapshot("Bob", 1, "Update Café Végétal", "Food", tofu, cafe2, oat2, seitan, "Fashion", bags), time.April, 6),
+		at(listSnapshot("Alice", 1, "Merge branch 'soy'", "Food", tofu, cafe2, oat2, seitan, soy, "Fashion", bags), time.April, 20),
+		at(listSnapshot("Bob", 1, "Update Seitan Co and Oat Dream", "Food", tofu, cafe2, oat3, seitan2, soy, "Fashion", bags), time.May, 4),
+		at(listSnapshot("Alice", 1, "Remove Bags", "Food", tofu, cafe2, oat3, seitan2, soy, "Fashion"), time.May, 18),
+	}
+	// the branch adding soy starts off the bags, the shoes are removed on the main branch
+	s[6].Parents = []int{4}
+	s[7].Parents = []int{6}
+	s[8].Parents = []int{7, 5}
+
+	return s
+}
+
+// provenance comments and objects name the tiered mode, everything else has to be the same
+var provenanceCommentRe = regexp.MustCompile(`<!-- provenance: [^>]* -->`)
+
+func withoutProvenance(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+
+	if !strings.HasSuffix(name, ".json") {
+		return provenanceCommentRe.ReplaceAll(data, nil)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	delete(doc, "_provenance")
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// TestTieredRecentFeedMatchesFullWalk checks the items of the full window come out the same
+// whether the history below it is walked commit by commit or in monthly chunks
+func TestTieredRecentFeedMatchesFullWalk(t *testing.T) {
+	args := []string{"-context", "-since", "2024-04-01"}
+	full := generate(t, args, tieredHistory()...)
+	if n := bytes.Count(full["feed.xml"], []byte("<entry>")); n < 4 {
+		t.Fatalf("only %d recent items:\n%s", n, full["feed.xml"])
+	}
+
+	for _, window := range []string{"4", "45d"} {
+		t.Run(window, func(t *testing.T) {
+			tiered := generate(t, append([]string{"-tiered", "-full-window", window}, args...), tieredHistory()...)
+
+			for _, name := range []string{"feed.xml", "feed.rss", "feed.json"} {
+				if a, b := withoutProvenance(t, name, full[name]), withoutProvenance(t, name, tiered[name]); !bytes.Equal(a, b) {
+					t.Errorf("%s differs in the tiered mode at %s", name, feedgentest.FirstDifference(a, b))
+				}
+			}
+
+			m := provenanceRe.FindSubmatch(tiered["feed.xml"])
+			if m == nil || len(m[5]) != 40 {
+				t.Errorf("tiered mode missing from the provenance: %q", provenanceCommentRe.Find(tiered["feed.xml"]))
+			}
+			if m := provenanceRe.FindSubmatch(full["feed.xml"]); m == nil || len(m[5]) != 0 {
+				t.Error("full walk marked as tiered")
+			}
+		})
+	}
+}
+
+// TestTieredOlderItemsComeInChunks checks the items below the window are dated by the end of
+// their month and that a window covering everything leaves the walk as it is
+func TestTieredOlderItemsComeInChunks(t *testing.T) {
+	full := generate(t, nil, tieredHistory()...)
+	tiered := generate(t, []string{"-tiered", "-full-window", "4"}, tieredHistory()...)
+
+	// seitan is added early in february, the chunk of february ends mid month
+	if !bytes.Contains(full["feed.json"], []byte(`"date_published": "2024-02-03T09:30:00Z"`)) {
+		t.Fatalf("addition of seitan not dated by its commit:\n%s", full["feed.json"])
+	}
+	if bytes.Contains(tiered["feed.json"], []byte(`"date_published": "2024-02-03T09:30:00Z"`)) {
+		t.Errorf("addition of seitan dated by its commit in the tiered mode:\n%s", tiered["feed.json"])
+	}
+	if !bytes.Contains(tiered["feed.json"], []byte("Addition of Seitan Co")) {
+		t.Errorf("addition of seitan missing from the tiered mode:\n%s", tiered["feed.json"])
+	}
+
+	all := generate(t, []string{"-tiered", "-full-window", "100"}, tieredHistory()...)
+	for _, name := range []string{"feed.xml", "feed.rss", "feed.json"} {
+		if a, b := withoutProvenance(t, name, full[name]), withoutProvenance(t, name, all[name]); !bytes.Equal(a, b) {
+			t.Errorf("%s differs with a window covering all commits at %s", name, feedgentest.FirstDifference(a, b))
+		}
+	}
+	if m := provenanceRe.FindSubmatch(all["feed.xml"]); m == nil || len(m[5]) != 0 {
+		t.Error("window covering all commits marked as tiered")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	for s, want := range map[string]Window{
+		"1000": {Commits: 1000},
+		"2y":   {Age: 2 * 365 * 24 * time.Hour},
+		"90d":  {Age: 90 * 24 * time.Hour},
+		"36h":  {Age: 36 * time.Hour},
+	} {
+		var w Window
+		if err := w.Set(s); err != nil || w != want {
+			t.Errorf("Set(%q) = %+v, %v, want %+v", s, w, err, want)
+		}
+	}
+
+	for _, s := range []string{"0", "-3", "0d", "soon"} {
+		var w Window
+		if err := w.Set(s); err == nil {
+			t.Errorf("Set(%q) accepted", s)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// countPoint is the number of entries in the work file at one commit
+type countPoint struct {
+	Commit     string         `json:"commit"`
+	Date       time.Time      `json:"date"`
+	Total      int            `json:"total"`
+	Categories map[string]int `json:"categories,omitempty"`
+	// version of the parser that counted the entries, points of another version are counted again
+	Parser string `json:"parser"`
+}
+
+// loadTimeseries reads the series an earlier run published, a missing file yields an empty one
+// and so does a series counted by another parser version, which may see other entries
+func loadTimeseries(sink OutputSink, name string) ([]countPoint, error) {
+	data, err := sink.Read(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var points []countPoint
+	if err := json.Unmarshal(data, &points); err != nil {
+		return nil, err
+	}
+	for _, p := range points {
+		if p.Parser != parserVersion {
+			log.Printf("time series of parser version %q, counting all commits again with parser version %s", p.Parser, parserVersion)
+			return nil, nil
+		}
+	}
+
+	return points, nil
+}
+
+// buildTimeseries counts entries for every commit, oldest first, reusing points of
+// commits already in the previous series; points of commits no longer in the history are dropped
+func buildTimeseries(commits []*object.Commit, workfiles []string, previous []countPoint) ([]countPoint, int, error) {
+	known := make(map[string]countPoint)
+	for _, p := range previous {
+		known[p.Commit] = p
+	}
+
+	var points []countPoint
+	computed := 0
+	for n := len(commits) - 1; n >= 0; n-- {
+		c := commits[n]
+
+		if p, found := known[c.Hash.String()]; found {
+			points = append(points, p)
+			continue
+		}
+
+		entries, err := currentEntries(c, workfiles)
+		if errors.Is(err, object.ErrFileNotFound) {
+			entries = nil
+		} else if err != nil {
+			return nil, 0, err
+		}
+
+		p := countPoint{
+			Commit:     c.Hash.String(),
+			Date:       c.Author.When,
+			Total:      len(entries),
+			Categories: make(map[string]int),
+			Parser:     parserVersion,
+		}
+		for _, e := range entries {
+			if e.Section != "" {
+				p.Categories[e.Section]++
+			}
+		}
+
+		points = append(points, p)
+		computed++
+	}
+
+	return points, computed, nil
+}
+
+func marshalTimeseries(points []countPoint) ([]byte, error) {
+	return json.MarshalIndent(points, "", "  ")
+}
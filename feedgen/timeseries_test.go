@@ -0,0 +1,73 @@
+package feedgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTimeseriesOfAnotherParserIsRebuilt(t *testing.T) {
+	history := goldenHistory()
+	args := []string{"-timeseries", "timeseries.json"}
+	full := generate(t, args, history...)
+
+	for _, tt := range []struct {
+		name    string
+		parser  string
+		rebuild bool
+	}{
+		{"current", parserVersion, false},
+		{"v1", "1.0.0", true},
+		{"unversioned", "", true},
+	} {
+		dir := t.TempDir()
+		generateInto(t, dir, args, history[:6]...)
+
+		// miscount the earlier points, so reused ones show in the series
+		path := filepath.Join(dir, "timeseries.json")
+		var points []map[string]interface{}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &points); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range points {
+			p["total"] = 1000
+			if tt.parser == "" {
+				delete(p, "parser")
+			} else {
+				p["parser"] = tt.parser
+			}
+		}
+		data, err = json.Marshal(points)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var logged bytes.Buffer
+		log.SetOutput(&logged)
+		files := generateInto(t, dir, args, history...)
+		log.SetOutput(os.Stderr)
+
+		rebuilt := bytes.Equal(files["timeseries.json"], full["timeseries.json"])
+		if rebuilt != tt.rebuild {
+			t.Errorf("%s: rebuilt %v, want %v", tt.name, rebuilt, tt.rebuild)
+		}
+		noted := strings.Contains(logged.String(), "counting all commits again with parser version "+parserVersion)
+		if noted != tt.rebuild {
+			t.Errorf("%s: rebuild logged %v, want %v:\n%s", tt.name, noted, tt.rebuild, logged.String())
+		}
+		if !strings.Contains(string(files["timeseries.json"]), `"parser": "`+parserVersion+`"`) {
+			t.Errorf("%s: points without parser version:\n%s", tt.name, files["timeseries.json"])
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package feedgen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// phaseTiming is the accumulated wall time of one phase of a run and the number of things it processed
+type phaseTiming struct {
+	Name  string
+	Wall  time.Duration
+	Count int
+}
+
+// timings records the phases of a run in the order they first ran
+type timings struct {
+	phases []*phaseTiming
+}
+
+// start begins timing a phase, the returned function ends it with the number of processed things
+func (t *timings) start(name string) func(count int) {
+	begin := time.Now()
+
+	return func(count int) {
+		t.add(name, time.Since(begin), count)
+	}
+}
+
+func (t *timings) add(name string, d time.Duration, count int) {
+	for _, p := range t.phases {
+		if p.Name == name {
+			p.Wall += d
+			p.Count += count
+			return
+		}
+	}
+
+	t.phases = append(t.phases, &phaseTiming{Name: name, Wall: d, Count: count})
+}
+
+func (t *timings) String() string {
+	var parts []string
+	for _, p := range t.phases {
+		parts = append(parts, fmt.Sprintf("%s %s (%d)", p.Name, p.Wall.Round(time.Microsecond), p.Count))
+	}
+
+	return "timings: " + strings.Join(parts, ", ")
+}
+
+// timedSink accounts the time spent handing artifacts to the output sink to the write phase
+type timedSink struct {
+	OutputSink
+	timings *timings
+}
+
+func (s *timedSink) Write(name string, contentType string, data []byte) error {
+	defer s.timings.start("write")(1)
+
+	return s.OutputSink.Write(name, contentType, data)
+}
+
+func (s *timedSink) Finalize() error {
+	defer s.timings.start("write")(0)
+
+	return s.OutputSink.Finalize()
+}
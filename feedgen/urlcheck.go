@@ -0,0 +1,126 @@
+package feedgen
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// schemeAllowed reports whether a url has one of the allowed schemes, urls without a scheme never do
+func schemeAllowed(raw string, schemes []string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactURLs removes the links with disallowed schemes from the entries among diff lines,
+// so the context of another entry's change does not carry them into the feeds
+func redactURLs(lines []string, schemes []string) []string {
+	var redacted []string
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		_, link, _, ok := ParseEntry(line[1:])
+		if !ok {
+			_, link, _, ok = ParseHTMLEntry(line[1:])
+		}
+		if !ok || link == "" || schemeAllowed(link, schemes) {
+			continue
+		}
+
+		if redacted == nil {
+			redacted = append([]string{}, lines...)
+		}
+		redacted[n] = strings.Replace(line, link, "", 1)
+	}
+	if redacted == nil {
+		return lines
+	}
+
+	return redacted
+}
+
+// splitList splits a comma separated flag value, dropping empty elements
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// selfURL is the url a feed is published under: self as is when it is an absolute url,
+// otherwise the path self below the base url
+func selfURL(base string, self string) string {
+	if isAbsoluteURL(self) {
+		return self
+	}
+
+	return joinURL(base, self)
+}
+
+// isAbsoluteURL reports whether s is a url with scheme and host
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+
+	return err == nil && u.IsAbs() && u.Host != ""
+}
+
+// joinURL appends path elements to the path of a base url with exactly one slash between
+// them, a trailing slash of the last element is kept
+func joinURL(base string, elem ...string) string {
+	if len(elem) == 0 {
+		return base
+	}
+
+	p := path.Join(elem...)
+	if strings.HasSuffix(elem[len(elem)-1], "/") && p != "/" {
+		p += "/"
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(p, "/")
+	}
+
+	trailing := strings.HasSuffix(p, "/")
+	u.Path = path.Join("/", u.Path, p)
+	if trailing && u.Path != "/" {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// urlIdentity reduces a url to the parts that make two entries the same: all of it with
+// full, everything but the fragment with no-fragment, or just host and path with host-path
+func urlIdentity(raw string, mode string) string {
+	u, err := url.Parse(normalizeURL(raw))
+	if err != nil {
+		return raw
+	}
+
+	switch mode {
+	case "no-fragment":
+		u.Fragment, u.RawFragment = "", ""
+	case "host-path":
+		u.Fragment, u.RawFragment = "", ""
+		u.RawQuery, u.ForceQuery = "", false
+	}
+
+	return u.String()
+}
@@ -0,0 +1,240 @@
+package feedgen
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestSchemeAllowed(t *testing.T) {
+	web := []string{"http", "https"}
+	contact := []string{"http", "https", "mailto"}
+
+	tests := []struct {
+		url     string
+		schemes []string
+		want    bool
+	}{
+		{"https://tofu.example/", web, true},
+		{"HTTP://tofu.example/", web, true},
+		{" https://tofu.example/ ", web, true},
+		{"javascript:alert(1)", web, false},
+		{"JavaScript:alert(1)", contact, false},
+		{"data:text/html;base64,PHNjcmlwdD4=", web, false},
+		{"ftp://files.example/menu.pdf", web, false},
+		{"tofu.example/recipes", web, false},
+		{"//tofu.example/recipes", web, false},
+		{"/recipes", web, false},
+		{"mailto:hello@tofu.example", web, false},
+		{"mailto:hello@tofu.example", contact, true},
+		{"", web, false},
+	}
+
+	for _, tt := range tests {
+		if got := schemeAllowed(tt.url, tt.schemes); got != tt.want {
+			t.Errorf("%q with %v: got %v, want %v", tt.url, tt.schemes, got, tt.want)
+		}
+	}
+}
+
+func TestDisallowedURLsNeverPublished(t *testing.T) {
+	const (
+		js     = "- [Prank](javascript:alert(document.cookie)) - Click me."
+		data   = "- [Inline](data:text/html;base64,PHNjcmlwdD4=) - Embedded page."
+		ftp    = "- [Menu](ftp://files.example/menu.pdf) - The menu as pdf."
+		bare   = "- [Bare](bare.example/recipes) - Recipes without scheme."
+		mailto = "- [Contact](mailto:hello@tofu.example) - Write to us."
+	)
+	history := goldenHistory()[:1]
+	history = append(history, listSnapshot("Mallory", 2, "Add a few entries", "Food", js, data, ftp, bare, mailto))
+
+	bad := []string{"javascript:", "data:text", "ftp://", "bare.example", "PHNjcmlwdD4"}
+
+	tests := []struct {
+		name    string
+		args    []string
+		titles  []string
+		flagged int
+	}{
+		{"drop", nil, []string{"Removal of Oat Dream"}, 0},
+		{"flag", []string{"-invalid-url", "flag"}, []string{"Addition of Prank", "Addition of Inline", "Addition of Menu", "Addition of Bare", "Addition of Contact"}, 5},
+		{"mailto", []string{"-allowed-schemes", "http,https,mailto"}, []string{"Addition of Contact"}, 0},
+		{"diff", []string{"-invalid-url", "flag", "-include-diff"}, []string{"Removal of Oat Dream", "Addition of Prank"}, 5},
+	}
+
+	for _, tt := range tests {
+		files := generate(t, tt.args, history...)
+
+		for name, content := range files {
+			for _, b := range bad {
+				if bytes.Contains(content, []byte(b)) {
+					t.Errorf("%s: %s contains %q", tt.name, name, b)
+				}
+			}
+		}
+
+		feed := files["feed.xml"]
+		for _, title := range tt.titles {
+			if !bytes.Contains(feed, []byte("<title>"+title+"</title>")) {
+				t.Errorf("%s: %s missing", tt.name, title)
+			}
+		}
+		if n := bytes.Count(feed, []byte("link removed: disallowed url scheme")); n != tt.flagged {
+			t.Errorf("%s: %d flagged items, want %d", tt.name, n, tt.flagged)
+		}
+		// the removal next to the added entries shows them as context, without their links
+		if tt.name == "diff" && !bytes.Contains(feed, []byte("[Prank]() - Click me.")) {
+			t.Errorf("%s: redacted context missing", tt.name)
+		}
+		if tt.name == "mailto" && !bytes.Contains(feed, []byte("mailto:hello@tofu.example")) {
+			t.Errorf("%s: allowed mailto link missing", tt.name)
+		}
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		base string
+		elem []string
+		want string
+	}{
+		{"https://example.org", []string{"feed.xml"}, "https://example.org/feed.xml"},
+		{"https://example.org/", []string{"feed.xml"}, "https://example.org/feed.xml"},
+		{"https://example.org/feeds", []string{"/feed.xml"}, "https://example.org/feeds/feed.xml"},
+		{"https://example.org/feeds/", []string{"/feed.xml"}, "https://example.org/feeds/feed.xml"},
+		{"https://example.org/feeds//", []string{"tags", "tofu.xml"}, "https://example.org/feeds/tags/tofu.xml"},
+		{"https://example.org/feeds", []string{"removed/"}, "https://example.org/feeds/removed/"},
+		{"https://example.org/feeds", []string{"/"}, "https://example.org/feeds/"},
+		{"https://example.org/feeds", nil, "https://example.org/feeds"},
+		{"https://example.org/feeds?ref=list", []string{"feed.xml"}, "https://example.org/feeds/feed.xml?ref=list"},
+		{"https://example.org/feeds/?ref=list#top", []string{"feed.xml"}, "https://example.org/feeds/feed.xml?ref=list#top"},
+	}
+
+	for _, tt := range tests {
+		if got := joinURL(tt.base, tt.elem...); got != tt.want {
+			t.Errorf("joinURL(%q, %q) = %q, want %q", tt.base, tt.elem, got, tt.want)
+		}
+	}
+}
+
+func TestSelfURL(t *testing.T) {
+	tests := []struct {
+		base string
+		self string
+		want string
+	}{
+		{"https://example.org/feeds", "feed.xml", "https://example.org/feeds/feed.xml"},
+		{"https://example.org/feeds/", "feed.xml", "https://example.org/feeds/feed.xml"},
+		{"https://example.org/feeds", "/feed.xml", "https://example.org/feeds/feed.xml"},
+		{"https://example.org/feeds?ref=list", "feed.xml", "https://example.org/feeds/feed.xml?ref=list"},
+		{"https://example.org/feeds", "https://cdn.example.net/feed.xml", "https://cdn.example.net/feed.xml"},
+		{"https://example.org/feeds?ref=list", "https://cdn.example.net/feed.xml?v=2", "https://cdn.example.net/feed.xml?v=2"},
+		// without a host the url is taken as a path below the prefix
+		{"https://example.org/feeds", "feeds.example.net/feed.xml", "https://example.org/feeds/feeds.example.net/feed.xml"},
+	}
+
+	for _, tt := range tests {
+		if got := selfURL(tt.base, tt.self); got != tt.want {
+			t.Errorf("selfURL(%q, %q) = %q, want %q", tt.base, tt.self, got, tt.want)
+		}
+	}
+}
+
+func TestURLIdentity(t *testing.T) {
+	const (
+		milk     = "https://en.wikipedia.org/wiki/Soy#Milk"
+		tofu     = "https://en.wikipedia.org/wiki/Soy#Tofu"
+		shop     = "https://shop.example/"
+		shopUTM  = "https://Shop.example?utm_source=list&utm_medium=feed"
+		shopPage = "https://shop.example/?page=2"
+	)
+
+	tests := []struct {
+		mode string
+		a    string
+		b    string
+		same bool
+	}{
+		// entries told apart by fragment or query stay apart by default
+		{"full", milk, tofu, false},
+		{"full", shop, shopUTM, false},
+		{"full", shop, "HTTPS://SHOP.EXAMPLE", true},
+		{"no-fragment", milk, tofu, true},
+		{"no-fragment", shop, shopUTM, false},
+		// tracking parameters only collapse when the query does not count
+		{"host-path", shop, shopUTM, true},
+		{"host-path", shopUTM, shopPage, true},
+		{"host-path", milk, tofu, true},
+		{"host-path", shop, "https://shop.example/tofu", false},
+	}
+
+	for _, tt := range tests {
+		// in both directions, as either side can be the one already known
+		for _, pair := range [][2]string{{tt.a, tt.b}, {tt.b, tt.a}} {
+			got := urlIdentity(pair[0], tt.mode) == urlIdentity(pair[1], tt.mode)
+			if got != tt.same {
+				t.Errorf("%s: %s and %s same %v, want %v", tt.mode, pair[0], pair[1], got, tt.same)
+			}
+		}
+	}
+
+	// urls that do not parse are compared as they are
+	for _, mode := range []string{"full", "no-fragment", "host-path"} {
+		if got := urlIdentity("https://shop.example/%zz", mode); got != "https://shop.example/%zz" {
+			t.Errorf("%s: got %q for an unparsable url", mode, got)
+		}
+	}
+}
+
+func TestRegistryPairsByURLIdentity(t *testing.T) {
+	commit := func(n int) *object.Commit {
+		return &object.Commit{
+			Hash:   plumbing.NewHash(fmt.Sprintf("%040x", n)),
+			Author: object.Signature{Name: "Alice", When: time.Date(2024, 3, n, 9, 30, 0, 0, time.UTC)},
+		}
+	}
+	// renames keeping the url but for tracking parameters, in both directions, and one of two
+	// entries sharing a page dropped while the other is added under a new name
+	history := [][][]string{
+		{{"", "+", "Soy Milk", "https://en.wikipedia.org/wiki/Soy#Milk"}, {"", "+", "Shop", "https://shop.example/"}},
+		{{"", "-", "Shop", "https://shop.example/"}, {"", "+", "The Shop", "https://shop.example/?utm_source=list"}},
+		{{"", "-", "The Shop", "https://shop.example/?utm_source=list"}, {"", "+", "Shop", "https://shop.example/"}},
+		{{"", "-", "Soy Milk", "https://en.wikipedia.org/wiki/Soy#Milk"}, {"", "+", "Tofu", "https://en.wikipedia.org/wiki/Soy#Tofu"}},
+	}
+
+	tests := []struct {
+		mode string
+		want int
+	}{
+		{"full", 4},
+		{"no-fragment", 3},
+		{"host-path", 2},
+	}
+
+	for _, tt := range tests {
+		r := newRegistry(tt.mode)
+		for n, matches := range history {
+			changes := make(map[string]int)
+			for _, m := range matches {
+				if m[1] == "+" {
+					changes[m[2]]++
+				} else {
+					changes[m[2]]--
+				}
+			}
+			r.observe(matches, changes, commit(n+1))
+		}
+
+		if len(r.entries) != tt.want {
+			t.Errorf("%s: %d entries, want %d", tt.mode, len(r.entries), tt.want)
+		}
+		// the shop is one entry under all of its names whenever it is paired at all
+		if tt.mode == "host-path" && r.id("Shop") != r.id("The Shop") {
+			t.Errorf("%s: shop renames got ids %s and %s", tt.mode, r.id("Shop"), r.id("The Shop"))
+		}
+	}
+}
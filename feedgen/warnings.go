@@ -0,0 +1,113 @@
+package feedgen
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gorilla/feeds"
+)
+
+// number of examples kept per class of warning
+const warningExamples = 5
+
+// warningClass counts the warnings of one kind and keeps the first few of them
+type warningClass struct {
+	Name     string
+	Count    int
+	Examples []string
+}
+
+// warnings collects everything that looked wrong during a run
+type warnings struct {
+	classes []*warningClass
+	// warnings about changed lines of the list files
+	annotations []*annotation
+}
+
+// warn logs a warning and records it under the given class
+func (w *warnings) warn(class string, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("warning: %s", msg)
+
+	var wc *warningClass
+	for _, c := range w.classes {
+		if c.Name == class {
+			wc = c
+		}
+	}
+	if wc == nil {
+		wc = &warningClass{Name: class}
+		w.classes = append(w.classes, wc)
+	}
+
+	wc.Count++
+	if len(wc.Examples) < warningExamples {
+		wc.Examples = append(wc.Examples, msg)
+	}
+}
+
+// warnAt logs and records a warning about a changed line of a list file, given as is without
+// the diff sign, so it can be reported in place
+func (w *warnings) warnAt(class string, file string, line string, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	w.warn(class, "%s", msg)
+
+	w.annotations = append(w.annotations, &annotation{File: file, Class: class, Message: msg, text: line})
+}
+
+// maintainerFeed lists the warnings of a run, one item per class; ids only depend on the
+// processed head commit so regenerating the same state does not show up as new items
+func maintainerFeed(feed *feeds.Feed, w *warnings, head *object.Commit, authority string) *feeds.Feed {
+	mf := &feeds.Feed{
+		Title:       feed.Title + ": Warnings",
+		Link:        feed.Link,
+		Description: "Problems found while generating the feeds, meant for maintainers of the list.",
+		Created:     feed.Created,
+		Updated:     head.Committer.When,
+	}
+
+	for _, c := range w.classes {
+		var b strings.Builder
+		fmt.Fprintf(&b, "<p>%d times at commit %s, for example:</p>\n<ul>\n", c.Count, head.Hash)
+		for _, e := range c.Examples {
+			fmt.Fprintf(&b, "<li>%s</li>\n", html.EscapeString(e))
+		}
+		b.WriteString("</ul>")
+
+		mf.Items = append(mf.Items, &feeds.Item{
+			Id:          fmt.Sprintf("tag:%s,%d:warnings/%s/%s", authority, head.Committer.When.UTC().Year(), head.Hash, c.Name),
+			Title:       fmt.Sprintf("%d warnings: %s", c.Count, c.Name),
+			Link:        &feeds.Link{Href: feed.Link.Href},
+			Description: b.String(),
+			Created:     head.Committer.When,
+		})
+	}
+
+	return mf
+}
+
+// merge adds the counts and examples of previously recorded classes
+func (w *warnings) merge(classes []*warningClass) {
+	for _, mc := range classes {
+		var wc *warningClass
+		for _, c := range w.classes {
+			if c.Name == mc.Name {
+				wc = c
+			}
+		}
+		if wc == nil {
+			wc = &warningClass{Name: mc.Name}
+			w.classes = append(w.classes, wc)
+		}
+
+		wc.Count += mc.Count
+		for _, e := range mc.Examples {
+			if len(wc.Examples) < warningExamples {
+				wc.Examples = append(wc.Examples, e)
+			}
+		}
+	}
+}
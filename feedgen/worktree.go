@@ -0,0 +1,122 @@
+package feedgen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// resolveWorkfile finds the repository and path whose history the work file has: a symlink is
+// followed to its target within the worktree, and a file inside a submodule needs the submodule's
+// own repository, which is only opened when follow is set
+func resolveWorkfile(r *git.Repository, workdir string, workfile string, follow bool) (*git.Repository, string, error) {
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, "", err
+	}
+
+	subs, err := w.Submodules()
+	if err != nil {
+		return nil, "", err
+	}
+	for _, s := range subs {
+		p := s.Config().Path
+		if !strings.HasPrefix(workfile, p+"/") {
+			continue
+		}
+
+		dir := filepath.Join(workdir, filepath.FromSlash(p))
+		if !follow {
+			return nil, "", fmt.Errorf("%s is inside submodule %s, run against its own repository in %s or use -follow-submodule", workfile, p, dir)
+		}
+
+		sr, err := git.PlainOpen(dir)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open submodule: %s: %v", p, err)
+		}
+
+		return resolveWorkfile(sr, dir, strings.TrimPrefix(workfile, p+"/"), follow)
+	}
+
+	// the history is the one of the file a symlink points to
+	root, err := filepath.EvalSymlinks(workdir)
+	if err != nil {
+		return nil, "", err
+	}
+	target, err := filepath.EvalSymlinks(filepath.Join(workdir, filepath.FromSlash(workfile)))
+	if errors.Is(err, fs.ErrNotExist) {
+		// a list moved away for a while still has its history, there is just no link to follow
+		target, err = filepath.Join(root, filepath.FromSlash(workfile)), nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to locate file: %v", err)
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, "", fmt.Errorf("%s points outside of the repository: %s", workfile, target)
+	}
+
+	return r, filepath.ToSlash(rel), nil
+}
+
+// resolveFiles turns the comma separated names and globs of -files into repository paths
+// below prefix, globs are matched against the files of the given commit and never match
+// the generated paths, so committed outputs are not mistaken for parts of the list
+func resolveFiles(c *object.Commit, prefix string, spec string, generated []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+
+	for _, s := range splitList(spec) {
+		pattern := path.Join(prefix, s)
+		if !strings.ContainsAny(s, "*?[") {
+			add(pattern)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in -files: %s", s)
+		}
+
+		iter, err := c.Files()
+		if err != nil {
+			return nil, err
+		}
+		err = iter.ForEach(func(f *object.File) error {
+			if ok, _ := path.Match(pattern, f.Name); ok && !belowAny(f.Name, generated) {
+				add(f.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match -files: %s", spec)
+	}
+
+	return files, nil
+}
+
+// belowAny reports whether name is one of the paths or inside one of them
+func belowAny(name string, paths []string) bool {
+	for _, p := range paths {
+		if name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
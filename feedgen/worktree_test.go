@@ -0,0 +1,199 @@
+package feedgen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+
+	"awesome-veganism-feed/feedgentest"
+)
+
+func TestResolveFilesSkipsGeneratedPaths(t *testing.T) {
+	r, err := feedgentest.NewRepository(feedgentest.Snapshot{
+		Files: map[string][]byte{
+			"lists/food.md":         feedgentest.File("# Food\n"),
+			"lists/fashion.md":      feedgentest.File("# Fashion\n"),
+			"lists/feeds/README.md": feedgentest.File("# Published feeds\n"),
+			"lists/manifest.json":   feedgentest.File("{}\n"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := resolveFiles(c, "lists", "*.md,*/*.md,*.json", []string{"lists/feeds", "lists/manifest.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"lists/fashion.md", "lists/food.md"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// files named explicitly are taken as they are
+	got, err = resolveFiles(c, "lists", "feeds/README.md", []string{"lists/feeds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"lists/feeds/README.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGeneratedPaths(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(workdir, "feeds"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &options{Config: Config{Workdir: workdir, Destdir: filepath.Join(workdir, "feeds"), AtomFile: "feed.xml"}}
+	o.Sink.Output = "fs"
+	if got, want := generatedPaths(o), []string{"feeds"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("destdir below the root: got %v, want %v", got, want)
+	}
+
+	o.Destdir = workdir
+	o.Sink.Manifest = true
+	o.Sink.HeadersFile = "netlify"
+	o.FilterFeeds = listValue{"tofu=filtered/tofu.xml"}
+	want := []string{"feed.xml", "_headers", "manifest.json", "filtered/tofu.xml"}
+	if got := generatedPaths(o); !reflect.DeepEqual(got, want) {
+		t.Errorf("destdir at the root: got %v, want %v", got, want)
+	}
+
+	o.Destdir = t.TempDir()
+	if got := generatedPaths(o); got != nil {
+		t.Errorf("destdir outside: got %v", got)
+	}
+}
+
+func TestGeneratedPathsAtTheRoot(t *testing.T) {
+	r, err := feedgentest.NewRepository(feedgentest.Snapshot{
+		Files: map[string][]byte{
+			"README.md":  feedgentest.File("# List\n"),
+			"food.md":    feedgentest.File("# Food\n"),
+			"removed.md": feedgentest.File("# Removed entries\n"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the feeds published next to the lists, where a glob over the lists matches the removed page
+	workdir := t.TempDir()
+	o := &options{Config: Config{Workdir: workdir, Destdir: workdir, AtomFile: "feed.xml", RemovedPage: "removed.md"}}
+	o.Sink.Output = "fs"
+
+	got, err := resolveFiles(c, "", "*.md", generatedPaths(o))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"README.md", "food.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGitExcludeOutputs(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workdir, ".git", "info"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// names starting with two dots are still inside, only the parent directory is not
+	files := []string{
+		filepath.Join(workdir, "feed.xml"),
+		filepath.Join(workdir, "..feed.xml"),
+		filepath.Join(workdir, "feed.xml"),
+		filepath.Join(filepath.Dir(workdir), "feed.xml"),
+	}
+	if err := gitExcludeOutputs(workdir, files); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workdir, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "/feed.xml\n/..feed.xml\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestResolveWorkfileSymlinks(t *testing.T) {
+	workdir := t.TempDir()
+	r, err := git.PlainInit(workdir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outside := filepath.Join(t.TempDir(), "list.md")
+	for name, content := range map[string]string{"lists/food.md": "# Food\n", outside: "# Elsewhere\n"} {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(workdir, name)
+		}
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"README.md": filepath.Join("lists", "food.md"),
+		"food":      "lists",
+		"escape.md": outside,
+		"up.md":     filepath.Join("..", filepath.Base(workdir), "lists", "food.md"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(workdir, name)); err != nil {
+			t.Skipf("no symlinks: %v", err)
+		}
+	}
+
+	tests := []struct {
+		workfile string
+		want     string
+		err      string
+	}{
+		{"README.md", "lists/food.md", ""},
+		{"food/food.md", "lists/food.md", ""},
+		{"up.md", "lists/food.md", ""},
+		{"lists/food.md", "lists/food.md", ""},
+		// a list gone from the worktree keeps its path
+		{"lists/gone.md", "lists/gone.md", ""},
+		{"escape.md", "", "escape.md points outside of the repository"},
+	}
+
+	for _, tt := range tests {
+		_, got, err := resolveWorkfile(r, workdir, tt.workfile, false)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got %q, %v, want error %q", tt.workfile, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.workfile, got, err, tt.want)
+		}
+	}
+}
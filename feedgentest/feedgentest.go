@@ -0,0 +1,108 @@
+// Package feedgentest builds in-memory git repositories from a sequence of
+// file snapshots, for tests and examples of the feed generator.
+package feedgentest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Snapshot is the complete content of the worktree at one commit; files missing
+// from a snapshot are deleted, so a rename is the same content under a new name
+type Snapshot struct {
+	Files   map[string][]byte
+	Author  string
+	Email   string
+	When    time.Time
+	Message string
+	// indexes of the earlier snapshots the commit is based on, the previous one by default;
+	// several make a merge, the first one being the branch merged into
+	Parents []int
+}
+
+// File returns the content of a single file, for snapshots built from strings
+func File(s string) []byte {
+	return []byte(s)
+}
+
+// NewRepository commits the snapshots in order onto the master branch of a new in-memory repository
+func NewRepository(snapshots ...Snapshot) (*git.Repository, error) {
+	fs := memfs.New()
+	r, err := git.Init(memory.NewStorage(), fs)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []plumbing.Hash
+	prev := make(map[string]bool)
+	for n, s := range snapshots {
+		// remove what the snapshot no longer has
+		for name := range prev {
+			if _, found := s.Files[name]; !found {
+				if _, err := w.Remove(name); err != nil {
+					return nil, fmt.Errorf("snapshot %d: %v", n, err)
+				}
+			}
+		}
+
+		var names []string
+		for name := range s.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		prev = make(map[string]bool)
+		for _, name := range names {
+			f, err := fs.Create(name)
+			if err != nil {
+				return nil, fmt.Errorf("snapshot %d: %v", n, err)
+			}
+			if _, err := f.Write(s.Files[name]); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("snapshot %d: %v", n, err)
+			}
+			if err := f.Close(); err != nil {
+				return nil, fmt.Errorf("snapshot %d: %v", n, err)
+			}
+
+			if _, err := w.Add(name); err != nil {
+				return nil, fmt.Errorf("snapshot %d: %v", n, err)
+			}
+			prev[name] = true
+		}
+
+		sig := &object.Signature{Name: s.Author, Email: s.Email, When: s.When}
+		msg := s.Message
+		if msg == "" {
+			msg = fmt.Sprintf("Snapshot %d", n)
+		}
+
+		opts := &git.CommitOptions{Author: sig, Committer: sig, AllowEmptyCommits: true}
+		for _, p := range s.Parents {
+			if p < 0 || p >= n {
+				return nil, fmt.Errorf("snapshot %d: invalid parent %d", n, p)
+			}
+			opts.Parents = append(opts.Parents, hashes[p])
+		}
+
+		h, err := w.Commit(msg, opts)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %d: %v", n, err)
+		}
+		hashes = append(hashes, h)
+	}
+
+	return r, nil
+}
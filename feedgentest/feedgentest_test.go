@@ -0,0 +1,86 @@
+package feedgentest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestNewRepository(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	r, err := NewRepository(
+		Snapshot{Files: map[string][]byte{"README.md": File("# List\n")}, Author: "Alice", Email: "alice@example.org", When: start, Message: "Start"},
+		Snapshot{Files: map[string][]byte{"README.md": File("# List\n- a\n")}, Author: "Bob", When: start.Add(time.Hour)},
+		Snapshot{Files: map[string][]byte{"docs/README.md": File("# List\n")}, Author: "Carol", When: start.Add(2 * time.Hour), Parents: []int{0}},
+		Snapshot{Files: map[string][]byte{"docs/README.md": File("# List\n- a\n")}, Author: "Alice", When: start.Add(3 * time.Hour), Message: "Merge", Parents: []int{2, 1}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Name() != "refs/heads/master" {
+		t.Errorf("head is %s", head.Name())
+	}
+
+	var commits []*object.Commit
+	iter, err := r.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := iter.ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 4 {
+		t.Fatalf("got %d commits", len(commits))
+	}
+	merge, branch, second, first := commits[0], commits[1], commits[2], commits[3]
+
+	// the merge has the branch it was merged into first
+	if merge.NumParents() != 2 || merge.ParentHashes[0] != branch.Hash || merge.ParentHashes[1] != second.Hash {
+		t.Errorf("merge parents: %v", merge.ParentHashes)
+	}
+	if branch.NumParents() != 1 || branch.ParentHashes[0] != first.Hash {
+		t.Errorf("branch parents: %v", branch.ParentHashes)
+	}
+	if first.NumParents() != 0 {
+		t.Errorf("first commit has parents: %v", first.ParentHashes)
+	}
+
+	if first.Author.Name != "Alice" || first.Author.Email != "alice@example.org" || !first.Author.When.Equal(start) || first.Message != "Start" {
+		t.Errorf("first commit: %+v, %q", first.Author, first.Message)
+	}
+	if second.Message != "Snapshot 1" {
+		t.Errorf("default message: %q", second.Message)
+	}
+
+	// files missing from a snapshot are deleted, so the list moved
+	if _, err := branch.File("README.md"); err != object.ErrFileNotFound {
+		t.Errorf("README.md still in the branch: %v", err)
+	}
+	f, err := merge.File("docs/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := f.Contents(); content != "# List\n- a\n" {
+		t.Errorf("merged content: %q", content)
+	}
+}
+
+func TestNewRepositoryInvalidParent(t *testing.T) {
+	_, err := NewRepository(
+		Snapshot{Files: map[string][]byte{"README.md": File("a\n")}},
+		Snapshot{Files: map[string][]byte{"README.md": File("b\n")}, Parents: []int{1}},
+	)
+	if err == nil {
+		t.Error("a snapshot based on itself was accepted")
+	}
+}
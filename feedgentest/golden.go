@@ -0,0 +1,109 @@
+package feedgentest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// ReadTree returns the content of all files below dir by their slash separated path
+func ReadTree(t testing.TB, dir string) map[string][]byte {
+	t.Helper()
+
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return files
+}
+
+// CheckGolden compares the files with the ones below dir, or replaces those with update
+func CheckGolden(t testing.TB, dir string, files map[string][]byte, update bool) {
+	t.Helper()
+
+	name := filepath.Base(dir)
+	if update {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+		for p, data := range files {
+			path := filepath.Join(dir, filepath.FromSlash(p))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+
+	want := ReadTree(t, dir)
+
+	var names []string
+	for p := range files {
+		names = append(names, p)
+	}
+	for p := range want {
+		if _, found := files[p]; !found {
+			names = append(names, p)
+		}
+	}
+	sort.Strings(names)
+
+	for _, p := range names {
+		got, found := files[p]
+		if !found {
+			t.Errorf("%s: %s was not generated", name, p)
+			continue
+		}
+		if _, found := want[p]; !found {
+			t.Errorf("%s: %s is not expected, run go test -update-golden to add it", name, p)
+			continue
+		}
+		if !bytes.Equal(got, want[p]) {
+			t.Errorf("%s: %s differs from the golden file, run go test -update-golden after checking the change:\n%s", name, p, FirstDifference(got, want[p]))
+		}
+	}
+}
+
+// FirstDifference shows the first line in which two documents differ
+func FirstDifference(got []byte, want []byte) string {
+	g := strings.Split(string(got), "\n")
+	w := strings.Split(string(want), "\n")
+	for n := 0; n < len(g) || n < len(w); n++ {
+		var gl, wl string
+		if n < len(g) {
+			gl = g[n]
+		}
+		if n < len(w) {
+			wl = w[n]
+		}
+		if gl != wl {
+			return fmt.Sprintf("line %d\n got: %s\nwant: %s", n+1, gl, wl)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"gopkg.in/yaml.v3"
+
+	"awesome-veganism-feed/feedgen"
+)
+
+// initConfig is the configuration written by init, keyed by flag name like every config file
+type initConfig struct {
+	Workfile    string `yaml:"workfile"`
+	Title       string `yaml:"title"`
+	Link        string `yaml:"link,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	RepoURL     string `yaml:"repo-url,omitempty"`
+}
+
+// listCandidate is a markdown file of the repository with the entries found in it
+type listCandidate struct {
+	Path    string
+	Entries int
+	// number of entries per style, e.g. "- [name](url) - description"
+	Styles map[string]int
+	// list items with a link that are no entry in any known style
+	Unknown int
+	// first heading and the first paragraph below it
+	Heading   string
+	Paragraph string
+}
+
+// runInit inspects the repository in the working directory and writes a config file with the
+// list file and feed metadata it finds, asking for confirmation of every value unless -yes
+func runInit(args []string) {
+	var workdir, file string
+	var yes bool
+
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	fs.StringVar(&workdir, "workdir", ".", "directory inside the git repository of the list")
+	fs.StringVar(&file, "config", "avfeed.yml", "config file to write, relative to the top of the repository")
+	fs.BoolVar(&yes, "yes", false, "accept all proposed values without asking")
+	fs.Parse(args)
+
+	r, err := git.PlainOpenWithOptions(workdir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		log.Fatalf("failed to open repository: %s: %v", workdir, err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		log.Fatalf("failed to get worktree: %v", err)
+	}
+	top := wt.Filesystem.Root()
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(top, file)
+	}
+	fmt.Printf("repository: %s\n", top)
+
+	ref, err := r.Head()
+	if err != nil {
+		log.Fatalf("failed to get HEAD reference: %v", err)
+	}
+	head, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		log.Fatalf("failed to get HEAD commit: %v", err)
+	}
+
+	candidates, err := listCandidates(head)
+	if err != nil {
+		log.Fatalf("failed to look for list files: %v", err)
+	}
+	if len(candidates) == 0 {
+		log.Fatal("no markdown file with list entries found in HEAD")
+	}
+
+	fmt.Println("list files:")
+	for _, c := range candidates {
+		fmt.Printf("  %s: %d entries\n", c.Path, c.Entries)
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	ask := func(question string, proposed string) string {
+		if yes {
+			fmt.Printf("%s: %s\n", question, proposed)
+			return proposed
+		}
+		fmt.Printf("%s [%s]: ", question, proposed)
+		answer, err := in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Fatalf("failed to read answer: %v", err)
+		}
+		if answer = strings.TrimSpace(answer); answer != "" {
+			return answer
+		}
+		return proposed
+	}
+
+	cfg := &initConfig{Workfile: ask("list file", candidates[0].Path)}
+	var list *listCandidate
+	for n := range candidates {
+		if candidates[n].Path == cfg.Workfile {
+			list = &candidates[n]
+		}
+	}
+	if list == nil {
+		log.Fatalf("no list entries found in %s", cfg.Workfile)
+	}
+
+	fmt.Println("entry styles:")
+	for _, s := range sortedKeys(list.Styles) {
+		fmt.Printf("  %d %s\n", list.Styles[s], s)
+	}
+	if list.Unknown > 0 {
+		fmt.Printf("  %d list items with a link in no known style are left out\n", list.Unknown)
+	}
+
+	title := list.Heading
+	if title == "" {
+		title = feedgen.NewConfig().Title
+	}
+	cfg.Title = ask("title", title)
+	if host, repo := feedgen.OriginRepo(r); host != "" {
+		cfg.RepoURL = "https://" + host + "/" + repo
+	}
+	cfg.Link = ask("site url", cfg.RepoURL)
+	cfg.Description = ask("description", list.Paragraph)
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		log.Fatalf("failed to generate config: %v", err)
+	}
+
+	if _, err := os.Stat(file); err == nil {
+		if yes || !strings.HasPrefix(strings.ToLower(ask(fmt.Sprintf("%s exists, overwrite it? (y/n)", file), "n")), "y") {
+			log.Fatalf("not overwriting existing config: %s", file)
+		}
+	}
+	if err := writeInitConfig(file, data, cfg); err != nil {
+		log.Fatalf("failed to write config: %v", err)
+	}
+
+	rel, _ := filepath.Rel(top, file)
+	fmt.Printf("wrote %s, generate the feeds with: awesome-veganism-feed -config %s -destdir public\n", rel, rel)
+}
+
+// writeInitConfig writes the config file once the config loader accepts it as written and
+// reads back every value unchanged
+func writeInitConfig(file string, data []byte, cfg *initConfig) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".avfeed-*.yml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	var o feedgen.Config
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+	if err := applyConfig(fs, tmp.Name()); err != nil {
+		return err
+	}
+	if o.Workfile != cfg.Workfile || o.Title != cfg.Title || o.RepoURL != cfg.RepoURL ||
+		(cfg.Link != "" && o.Link != cfg.Link) || (cfg.Description != "" && o.Description != cfg.Description) {
+		return fmt.Errorf("values changed when read back")
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+// listCandidates finds the markdown files of a commit with list entries, readme files first
+// and then by the number of entries
+func listCandidates(c *object.Commit) ([]listCandidate, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []listCandidate
+	err = tree.Files().ForEach(func(f *object.File) error {
+		ext := strings.ToLower(path.Ext(f.Name))
+		if ext != ".md" && ext != ".markdown" {
+			return nil
+		}
+		contents, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		if lc := sampleList(f.Name, contents); lc.Entries > 0 {
+			candidates = append(candidates, lc)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	readme := func(p string) bool {
+		return strings.HasPrefix(strings.ToLower(path.Base(p)), "readme.")
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if readme(a.Path) != readme(b.Path) {
+			return readme(a.Path)
+		}
+		if a.Entries != b.Entries {
+			return a.Entries > b.Entries
+		}
+		return a.Path < b.Path
+	})
+
+	return candidates, nil
+}
+
+// sampleList counts the entries of a markdown file by style and finds its title and description
+func sampleList(name string, contents string) listCandidate {
+	lc := listCandidate{Path: name, Styles: make(map[string]int)}
+
+	lines := strings.Split(strings.ReplaceAll(contents, "\r\n", "\n"), "\n")
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+		s := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(s, "# ") && lc.Heading == "":
+			lc.Heading = strings.TrimSpace(s[2:])
+			continue
+		case lc.Heading != "" && lc.Paragraph == "" && lc.Entries == 0 && isProse(s):
+			lc.Paragraph = s
+			continue
+		}
+
+		if _, _, ok := feedgen.ParseTitle(line); ok && n+1 < len(lines) && feedgen.IsDescription(lines[n+1]) {
+			lc.Styles[s[:2]+"[name](url) with the description indented below"]++
+			for n+1 < len(lines) && feedgen.IsDescription(lines[n+1]) {
+				n++
+			}
+		} else if _, _, desc, ok := feedgen.ParseEntry(line); ok {
+			_, _, rest, _ := feedgen.ParseLink(s[2:])
+			lc.Styles[s[:2]+"[name](url)"+strings.TrimSuffix(rest, desc)+"description"]++
+		} else if _, _, _, ok := feedgen.ParseHTMLEntry(line); ok {
+			lc.Styles["html list item"]++
+		} else {
+			if feedgen.IsBulleted(s) && strings.HasPrefix(s[2:], "[") {
+				lc.Unknown++
+			}
+			continue
+		}
+		lc.Entries++
+	}
+
+	return lc
+}
+
+// isProse tells lines of text apart from headings, lists, badges, tables and html
+func isProse(s string) bool {
+	return s != "" && !strings.ContainsAny(s[:1], "#-*+>|<![") && !strings.HasPrefix(s, "```")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"awesome-veganism-feed/feedgen"
+)
+
+// runInspect prints the provenance of a published feed, read from a file or url
+func runInspect(args []string) {
+	var userAgent, contactEmail string
+
+	fs := flag.NewFlagSet("inspect-feed", flag.ExitOnError)
+	fs.StringVar(&userAgent, "user-agent", feedgen.DefaultUserAgent(), "user agent for all outbound http requests")
+	fs.StringVar(&contactEmail, "contact-email", "", "contact address sent as from header with all outbound http requests")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s: %s [flags] <file or url>\n", fs.Name(), fs.Name())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	src := fs.Arg(0)
+
+	data, err := readSource(src, feedgen.NewHTTPClient(userAgent, contactEmail))
+	if err != nil {
+		log.Fatalf("failed to read feed: %s: %v", src, err)
+	}
+
+	p, err := feedgen.ParseProvenance(data)
+	if err != nil {
+		log.Fatalf("%v in %s", err, src)
+	}
+
+	fmt.Printf("head: %s\nversion: %s\nparser version: %s\noptions: %s\n", p.Head, p.Version, p.Parser, p.Options)
+	if p.Tiered != "" {
+		fmt.Printf("tiered: monthly chunks below %s\n", p.Tiered)
+	}
+}
+
+// readSource reads a published file from a path or an http url
+func readSource(src string, client *http.Client) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+
+	res, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
@@ -1,248 +1,131 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"regexp"
-	"strings"
 
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/gorilla/feeds"
-	"github.com/natefinch/atomic"
+	"awesome-veganism-feed/feedgen"
 )
 
-func main() {
-	var destdir string
-	var workdir string
-	var stylesheet string
-	var verbose bool
-
-	flag.StringVar(&destdir, "destdir", ".", "destination directory for feed files")
-	flag.StringVar(&workdir, "workdir", ".", "working directory with a git repository")
-	flag.StringVar(&stylesheet, "stylesheet", "", "xslt stylesheet to inject into atom feed")
-	flag.BoolVar(&verbose, "verbose", false, "turn on verbose mode")
-	flag.Parse()
-
-	// regular expression to find relevant items in diffs
-	re, err := regexp.Compile(`\n([+-])\s*[-] \[([^\]]+)\]\(([^\)]+)\) [-] ([^\n]+)`)
-	if err != nil {
-		log.Fatalf("failed to compile regular expression: %v", err)
-	}
-
-	// open checked out repository
-	r, err := git.PlainOpen(workdir)
-	if err != nil {
-		log.Fatalf("failed to open repository: %s: %v", workdir, err)
-	}
-
-	// file to work with
-	workfile := "README.md"
-
-	// make sure file exists
-	if _, err := os.Stat(filepath.Join(workdir, workfile)); err != nil {
-		log.Fatalf("failed to locate file: %v", err)
-	}
-
-	// get HEAD reference
-	ref, err := r.Head()
-	if err != nil {
-		log.Fatalf("failed to get HEAD reference: %v", err)
-	}
-
-	logopts := &git.LogOptions{
-		From:     ref.Hash(),
-		FileName: &workfile,
-		Order:    git.LogOrderCommitterTime,
+// exit code of a run that wrote its outputs but skipped commits, fatal errors exit with 1
+const exitSkipped = 2
+
+// parseFlags defines the flags of all settings and -config, parses the arguments and fills
+// in the environment and the config file; it returns the flags set in any of them
+func parseFlags(fs *flag.FlagSet, cfg *feedgen.Config, args []string) map[string]bool {
+	var config string
+	cfg.RegisterFlags(fs)
+	fs.StringVar(&config, "config", "", "yaml file with flag names as keys, lists for repeatable flags; flags and environment take precedence")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nEvery flag can also be set with an %s prefixed environment variable, e.g. %s.\n", envPrefix, envName("destdir"))
+	}
+	fs.Parse(args)
+
+	// flags take precedence over the environment, which takes precedence over the config file
+	source := make(map[string]string)
+	mark := func(s string) {
+		fs.Visit(func(f *flag.Flag) {
+			if source[f.Name] == "" {
+				source[f.Name] = s
+			}
+		})
 	}
+	mark("flag")
 
-	// get commit history
-	iter, err := r.Log(logopts)
-	if err != nil {
-		log.Fatalf("failed to get log: %v", err)
+	if err := applyEnv(fs); err != nil {
+		log.Fatalf("failed to apply environment: %v", err)
 	}
+	mark("environment")
 
-	// build list with all commits
-	var commits []*object.Commit
-	err = iter.ForEach(func(c *object.Commit) error {
-		commits = append(commits, c)
-
-		return nil
-	})
-	if err != nil {
-		log.Fatalf("failed to iterate commit log: %v", err)
+	if config != "" {
+		if err := applyConfig(fs, config); err != nil {
+			log.Fatalf("failed to apply config file: %v", err)
+		}
+		mark("config")
 	}
 
-	if len(commits) == 0 {
-		log.Fatal("failed to find commits")
+	if cfg.Verbose {
+		logSettings(fs, source)
 	}
-
-	// setup feed
-	feed := &feeds.Feed{
-		Title:       "Awesome Veganism Feed",
-		Link:        &feeds.Link{Href: "https://awesome-veganism.com/"},
-		Description: "A curated list of awesome resources, pointers, and tips to make veganism easy and accessible to everyone.",
-		Created: commits[len(commits)-1].Author.When,
+	explicit := make(map[string]bool)
+	for name := range source {
+		explicit[name] = true
 	}
 
-	for n := len(commits) - 1; n >= 0; n-- {
-		c := commits[n]
+	return explicit
+}
 
-		// skip initial commit in this project as it happens to have no relevant content
-		if n == 0 {
-			break
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "stats":
+			runStats(os.Args[2:])
+			return
+		case "inspect-feed":
+			runInspect(os.Args[2:])
+			return
+		case "simulate":
+			runSimulate(os.Args[2:])
+			return
+		case "diff-feeds":
+			runDiffFeeds(os.Args[2:])
+			return
+		case "batch":
+			runBatch(os.Args[2:])
+			return
+		case "preview":
+			runPreview(os.Args[2:])
+			return
+		case "init":
+			runInit(os.Args[2:])
+			return
+		case "demo":
+			runDemo(os.Args[2:])
+			return
+		case "eventlog":
+			runEventlog(os.Args[2:])
+			return
+		case "prune":
+			runPrune(os.Args[2:])
+			return
 		}
+	}
 
-		p := commits[n-1]
-
-		if verbose {
-			log.Printf("===> commit: %s by %s at %s: %s", p.Hash, p.Author.Name, p.Author.When, p.Message)
-		}
+	var cfg feedgen.Config
+	parseFlags(flag.CommandLine, &cfg, os.Args[1:])
 
-		patch, err := c.Patch(p)
+	// runs sharing a destination directory would remove each other's staging directory, and a
+	// prune must not take it away while files are staged in it
+	if cfg.Sink.Output == "fs" || cfg.StateFile != "" {
+		lock, err := feedgen.LockDir(cfg.Destdir)
 		if err != nil {
-			log.Fatalf("failed to get patch: %v", err)
-		}
-
-		matches := re.FindAllStringSubmatch(patch.String(), -1)
-
-		// filter out moving items around: a plus and a minus cancel each other out
-		changes := make(map[string]int)
-		for _, m := range matches {
-			x := 1
-			if m[1] == "-" {
-				x = -1
-			}
-
-			v, found := changes[m[2]]
-			if !found {
-				v = x
-			} else {
-				v += x
-			}
-
-			changes[m[2]] = v
-		}
-
-		if verbose {
-			log.Printf("changes: %v", changes)
-		}
-
-		for _, m := range matches {
-			// skip when there was only a move of an entry
-			// safe to access without check due to full iteration in previous loop
-			if changes[m[2]] == 0 {
-				continue
-			}
-
-			t := "Addition"
-			if m[1] == "-" {
-				t = "Removal"
-			}
-
-			if verbose {
-				log.Printf("=====>> %s: %s -- %s -- %s", t, m[2], m[3], m[4])
-			}
-
-			feed.Items = append(feed.Items, &feeds.Item{
-				Title:       fmt.Sprintf("%s of %s", t, m[2]),
-				Link:        &feeds.Link{Href: m[3]},
-				Description: m[4],
-				Author:      &feeds.Author{Name: p.Author.Name},
-				Created:     p.Author.When,
-			})
-
-			feed.Updated = p.Author.When
+			log.Fatalf("failed to lock destination directory: %v", err)
 		}
+		defer lock.Close()
 	}
 
-	atom, err := feed.ToAtom()
-	if err != nil {
-		log.Fatalf("failed to generate atom feed: %v", err)
-	}
-	if stylesheet != "" {
-		atom = injectAtomStylesheet(atom, stylesheet)
-	}
-	atom = adjustAtomLinks(atom, "feed.xml")
-	if err := atomic.WriteFile(filepath.Join(destdir, "feed.xml"), bytes.NewReader([]byte(atom))); err != nil {
-		log.Fatalf("failed to write atom feed: %v", err)
-	}
-
-	json, err := feed.ToJSON()
+	res, err := feedgen.GenerateFeed(context.Background(), cfg)
 	if err != nil {
-		log.Fatalf("failed to generate json feed: %v", err)
-	}
-	if err := atomic.WriteFile(filepath.Join(destdir, "feed.json"), bytes.NewReader([]byte(json))); err != nil {
-		log.Fatalf("failed to write json feed: %v", err)
-	}
-
-	rss, err := feed.ToRss()
-	if err != nil {
-		log.Fatalf("failed to generate rss feed: %v", err)
-	}
-	rss = adjustRssAuthors(rss)
-	rss = addRssAtomLink(rss, "feed.rss")
-	if err := atomic.WriteFile(filepath.Join(destdir, "feed.rss"), bytes.NewReader([]byte(rss))); err != nil {
-		log.Fatalf("failed to write rss feed: %v", err)
+		log.Fatal(err)
 	}
 
-	files := []string{
-		filepath.Join(destdir, "feed.xml"),
-		filepath.Join(destdir, "feed.json"),
-		filepath.Join(destdir, "feed.rss"),
-	}
-	for _, f := range files {
-		if err := os.Chmod(f, 0644); err != nil {
-			log.Fatalf("failed to change file permission: %s: %v", f, err)
+	if cfg.Annotations != "" {
+		if err := res.WriteAnnotations(os.Stdout, cfg.Annotations); err != nil {
+			log.Fatalf("failed to print annotations: %v", err)
 		}
 	}
 
-	if verbose {
-		log.Printf("files written: %s", strings.Join(files, ", "))
-	}
-}
-
-func injectAtomStylesheet(atom string, style string) string {
-	preamble := `<?xml version="1.0" encoding="UTF-8"?>`
-	stylesheet := fmt.Sprintf(`<?xml-stylesheet href="%s" type="text/xsl"?>`, style)
-
-	return strings.Replace(atom, preamble, fmt.Sprintf("%s\n%s\n", preamble, stylesheet), 1)
-}
-
-func adjustAtomLinks(atom string, file string) string {
-	re := regexp.MustCompile(`(?m)^(\s*<link href="[^"]+)"></link>`)
-
-	return re.ReplaceAllString(atom, `${1}`+file+`" rel="self"/>`+"\n"+`${1}" rel="alternate"/>`)
-}
-
-func adjustRssAuthors(rss string) string {
-	dcre := regexp.MustCompile(`(<rss [^>]+)>`)
-	re := regexp.MustCompile(`<author>(.*?)</author>`)
-
-	rss = dcre.ReplaceAllString(rss, "\n"+`$1 xmlns:dc="http://purl.org/dc/elements/1.1/">`)
-
-	return re.ReplaceAllString(rss, `<dc:creator>$1</dc:creator>`)
-}
-
-func addRssAtomLink(rss string, file string) string {
-	// inject atom namespace
-	atomre := regexp.MustCompile(`(<rss [^>]+)>`)
-	rss = atomre.ReplaceAllString(rss, `$1 xmlns:atom="http://www.w3.org/2005/Atom">`)
-
-	re := regexp.MustCompile(`(?m)^(\s+)<link>([^<]+)</link>`)
-	subst := "$1<link>$2</link>\n"+`$1<atom:link href="${2}`+file+`" rel="self" type="application/rss+xml" />`
-
-	done := false
-	return re.ReplaceAllStringFunc(rss, func(a string) string {
-		if done {
-			return a
+	// the feeds are written but miss whatever the skipped commits changed
+	if len(res.Skipped) > 0 {
+		log.Printf("skipped %d commits:", len(res.Skipped))
+		for _, s := range res.Skipped {
+			log.Printf("  %s", s)
 		}
-		done = true
-
-		return re.ReplaceAllString(a, subst)
-	})
+		os.Exit(exitSkipped)
+	}
 }
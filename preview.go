@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"awesome-veganism-feed/feedgen"
+
+	"github.com/gorilla/feeds"
+)
+
+// runPreview shows the items the commits of an unmerged branch would add to the feeds, those
+// after its merge base with the branch it would be merged into; nothing is published and no
+// state is read or written
+func runPreview(args []string) {
+	var cfg feedgen.Config
+	var prRef, head, base, file string
+
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	fs.StringVar(&prRef, "pr-ref", "", "fetched pull request ref to preview, e.g. refs/pull/123/head")
+	fs.StringVar(&head, "head", "", "branch or commit to preview")
+	fs.StringVar(&base, "merge-base", "HEAD", "branch or commit the previewed one would be merged into")
+	fs.StringVar(&file, "preview-file", "", "atom feed file to write the preview to instead of printing it")
+	parseFlags(fs, &cfg, args)
+
+	if (prRef == "") == (head == "") {
+		log.Fatal("expected either -pr-ref or -head")
+	}
+	if prRef != "" {
+		head = prRef
+	}
+
+	res, err := feedgen.Preview(context.Background(), cfg, head, base)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if file == "" {
+		printPreview(res.Feed)
+		return
+	}
+
+	atom, err := res.Atom(filepath.Base(file))
+	if err != nil {
+		log.Fatalf("failed to generate preview feed: %v", err)
+	}
+	if err := os.WriteFile(file, []byte(atom), 0644); err != nil {
+		log.Fatalf("failed to write preview feed: %v", err)
+	}
+}
+
+// printPreview shows the items of a preview in a human readable form
+func printPreview(feed *feeds.Feed) {
+	if len(feed.Items) == 0 {
+		fmt.Println("no changes to the feeds")
+		return
+	}
+
+	for _, item := range feed.Items {
+		fmt.Println(item.Title)
+		if item.Link != nil && item.Link.Href != "" {
+			fmt.Printf("    %s\n", item.Link.Href)
+		}
+		if desc := strings.TrimSpace(item.Description); desc != "" {
+			fmt.Printf("    %s\n", desc)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"awesome-veganism-feed/feedgen"
+)
+
+// pruneTarget is a file or directory left behind by earlier runs that is no longer needed
+type pruneTarget struct {
+	Path   string
+	Size   int64
+	Reason string
+}
+
+// runPrune removes what accumulates between runs: clones of batch sources that are no longer
+// listed, cached github metadata older than -max-age and the leftovers of crashed runs in a
+// destination directory. The cache and the destination directory are locked like batch and
+// generation runs lock them, so pruning never races one of those.
+func runPrune(args []string) {
+	var cacheDir, sources, destdir, state string
+	var maxAge time.Duration
+	var dryRun bool
+
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	fs.StringVar(&cacheDir, "cache-dir", "", "directory of the clones of batch runs and the cached github metadata, in the user cache directory by default")
+	fs.StringVar(&sources, "sources", "", "sources file of batch runs, clones of sources not listed in it are removed; without it all clones are kept")
+	fs.DurationVar(&maxAge, "max-age", 30*24*time.Hour, "age after which cached github metadata is removed")
+	fs.StringVar(&destdir, "destdir", "", "destination directory of generation runs to remove the leftovers of crashed runs from")
+	fs.StringVar(&state, "state", "", "state file in -destdir whose leftover temporary file is removed")
+	fs.BoolVar(&dryRun, "dry-run", false, "only print what would be removed")
+	fs.Parse(args)
+
+	if cacheDir == "" {
+		dir, err := defaultCacheDir()
+		if err != nil {
+			log.Fatalf("missing -cache-dir: %v", err)
+		}
+		cacheDir = dir
+	}
+	if maxAge <= 0 {
+		log.Fatalf("invalid -max-age: %v", maxAge)
+	}
+
+	var listed map[string]bool
+	if sources != "" {
+		f, err := os.Open(sources)
+		if err != nil {
+			log.Fatalf("failed to open sources: %v", err)
+		}
+		list, err := readSources(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("failed to read sources: %v", err)
+		}
+		listed = make(map[string]bool)
+		for _, s := range list {
+			listed[s.Name] = true
+		}
+	}
+
+	var targets []pruneTarget
+	if _, err := os.Stat(cacheDir); err == nil {
+		lock, err := feedgen.LockDir(cacheDir)
+		if err != nil {
+			log.Fatalf("failed to lock cache directory: %v", err)
+		}
+		defer lock.Close()
+
+		found, err := cacheTargets(cacheDir, listed, time.Now().Add(-maxAge))
+		if err != nil {
+			log.Fatalf("failed to inspect cache directory: %v", err)
+		}
+		targets = append(targets, found...)
+	}
+	if destdir != "" {
+		lock, err := feedgen.LockDir(destdir)
+		if err != nil {
+			log.Fatalf("failed to lock destination directory: %v", err)
+		}
+		defer lock.Close()
+
+		found, err := destdirTargets(destdir, state)
+		if err != nil {
+			log.Fatalf("failed to inspect destination directory: %v", err)
+		}
+		targets = append(targets, found...)
+	}
+
+	if err := removeTargets(os.Stdout, targets, dryRun); err != nil {
+		log.Fatalf("failed to prune: %v", err)
+	}
+}
+
+// cacheTargets finds the clones in the cache directory of sources not listed, unless there is
+// no list, and the github responses last written before the given time; responses are not
+// locked, removing one a run is about to read only costs that run a full request
+func cacheTargets(dir string, listed map[string]bool, before time.Time) ([]pruneTarget, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []pruneTarget
+	for _, e := range entries {
+		// the github responses live next to the clones and are no clone themselves
+		if !e.IsDir() || e.Name() == "github" || listed == nil || listed[e.Name()] {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if fi, err := os.Stat(filepath.Join(path, ".git")); err != nil || !fi.IsDir() {
+			continue
+		}
+		size, err := diskUsage(path)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, pruneTarget{Path: path, Size: size, Reason: "clone of a source no longer listed"})
+	}
+
+	responses, err := os.ReadDir(filepath.Join(dir, "github"))
+	if errors.Is(err, os.ErrNotExist) {
+		return targets, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, e := range responses {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		if !fi.Mode().IsRegular() || !fi.ModTime().Before(before) {
+			continue
+		}
+		targets = append(targets, pruneTarget{Path: filepath.Join(dir, "github", e.Name()), Size: fi.Size(), Reason: "github metadata past its age"})
+	}
+
+	return targets, nil
+}
+
+// destdirTargets finds the staging directory and the temporary state file a crashed run left
+// behind in a destination directory
+func destdirTargets(dir string, state string) ([]pruneTarget, error) {
+	leftovers := []string{filepath.Join(dir, feedgen.StagingDir)}
+	if state != "" {
+		leftovers = append(leftovers, filepath.Join(dir, state+".tmp"))
+	}
+
+	var targets []pruneTarget
+	for _, path := range leftovers {
+		size, err := diskUsage(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		targets = append(targets, pruneTarget{Path: path, Size: size, Reason: "left behind by a crashed run"})
+	}
+
+	return targets, nil
+}
+
+// diskUsage adds up the sizes of the regular files below a path
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += fi.Size()
+		}
+		return nil
+	})
+
+	return size, err
+}
+
+// removeTargets removes every target, or only lists them in a dry run, and prints how much
+// space that reclaims
+func removeTargets(w io.Writer, targets []pruneTarget, dryRun bool) error {
+	var total int64
+	for _, t := range targets {
+		if dryRun {
+			fmt.Fprintf(w, "would remove %s (%s): %s\n", t.Path, formatSize(t.Size), t.Reason)
+		} else {
+			if err := os.RemoveAll(t.Path); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "removed %s (%s): %s\n", t.Path, formatSize(t.Size), t.Reason)
+		}
+		total += t.Size
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "would reclaim %s in %d paths\n", formatSize(total), len(targets))
+	} else {
+		fmt.Fprintf(w, "reclaimed %s in %d paths\n", formatSize(total), len(targets))
+	}
+
+	return nil
+}
+
+// formatSize shows a number of bytes in binary units
+func formatSize(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	size, unit := float64(n)/1024, 0
+	for size >= 1024 && unit < 3 {
+		size /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", size, []string{"KiB", "MiB", "GiB", "TiB"}[unit])
+}
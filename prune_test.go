@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"awesome-veganism-feed/feedgen"
+	"awesome-veganism-feed/feedgentest"
+)
+
+func TestPruneTargets(t *testing.T) {
+	cache := t.TempDir()
+	write := func(path string, data string, mtime time.Time) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	write(filepath.Join(cache, "kept", ".git", "HEAD"), "ref: refs/heads/main\n", now)
+	write(filepath.Join(cache, "gone", ".git", "HEAD"), "ref: refs/heads/main\n", now)
+	write(filepath.Join(cache, "gone", "README.md"), "# gone\n", now)
+	write(filepath.Join(cache, "no-clone", "README.md"), "# no clone\n", now)
+	write(filepath.Join(cache, "github", "old_list.json"), `{"etag":"1"}`, now.Add(-48*time.Hour))
+	write(filepath.Join(cache, "github", "new_list.json"), `{"etag":"2"}`, now)
+
+	paths := func(targets []pruneTarget) []string {
+		var p []string
+		for _, t := range targets {
+			rel, _ := filepath.Rel(cache, t.Path)
+			p = append(p, filepath.ToSlash(rel))
+		}
+		sort.Strings(p)
+		return p
+	}
+
+	targets, err := cacheTargets(cache, map[string]bool{"kept": true}, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(paths(targets), " "), "github/old_list.json gone"; got != want {
+		t.Errorf("targets %s, want %s", got, want)
+	}
+	for _, target := range targets {
+		if strings.HasSuffix(target.Path, "gone") && target.Size != int64(len("ref: refs/heads/main\n# gone\n")) {
+			t.Errorf("size of the clone %d", target.Size)
+		}
+	}
+
+	// without a list of sources every clone is kept
+	targets, err = cacheTargets(cache, nil, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(paths(targets), " "), "github/old_list.json"; got != want {
+		t.Errorf("targets without sources %s, want %s", got, want)
+	}
+}
+
+func TestPruneDestdir(t *testing.T) {
+	dir := t.TempDir()
+	r, err := feedgentest.NewRepository(demoSnapshots()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := feedgen.NewConfig()
+	cfg.Repository, cfg.Destdir, cfg.StateFile = r, dir, "state.json"
+	if _, err := feedgen.GenerateFeed(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	files := feedgentest.ReadTree(t, dir)
+
+	// what a run crashing while staging and while saving the state leaves behind
+	if err := os.MkdirAll(filepath.Join(dir, feedgen.StagingDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, feedgen.StagingDir, "feed.xml"), []byte("<feed/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "state.json.tmp"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := destdirTargets(dir, "state.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("%d targets, want 2: %v", len(targets), targets)
+	}
+
+	var out strings.Builder
+	if err := removeTargets(&out, targets, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "would reclaim 9 B in 2 paths\n") {
+		t.Errorf("dry run summary:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "state.json.tmp")); err != nil {
+		t.Errorf("dry run removed a file: %v", err)
+	}
+
+	out.Reset()
+	if err := removeTargets(&out, targets, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "reclaimed 9 B in 2 paths\n") {
+		t.Errorf("summary:\n%s", out.String())
+	}
+	if targets, err := destdirTargets(dir, "state.json"); err != nil || len(targets) != 0 {
+		t.Errorf("targets left after pruning: %v %v", targets, err)
+	}
+
+	// the outputs and the state stay
+	after := feedgentest.ReadTree(t, dir)
+	for name := range files {
+		if after[name] == nil {
+			t.Errorf("%s removed", name)
+		}
+	}
+	if after["state.json"] == nil {
+		t.Error("state removed")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1024: "1.0 KiB", 1536: "1.5 KiB", 5 << 20: "5.0 MiB", 3 << 30: "3.0 GiB"} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"awesome-veganism-feed/feedgen"
+)
+
+// stepChange is an extracted line of a step
+type stepChange struct {
+	Sign        string `json:"sign"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+// stepItem is a feed item created in a step
+type stepItem struct {
+	Kind    string `json:"kind"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	EntryID string `json:"entry_id,omitempty"`
+	Section string `json:"section,omitempty"`
+}
+
+// stepDump is the json document written per commit with -step-output
+type stepDump struct {
+	Index   int            `json:"index"`
+	Commit  string         `json:"commit"`
+	Parent  string         `json:"parent"`
+	Author  string         `json:"author"`
+	Date    time.Time      `json:"date"`
+	Message string         `json:"message"`
+	Changes []stepChange   `json:"changes"`
+	Net     map[string]int `json:"net"`
+	Running map[string]int `json:"running"`
+	Items   []stepItem     `json:"items"`
+}
+
+// runSimulate replays the history one commit at a time and shows what each one contributes,
+// nothing is ever written to the destination directory
+func runSimulate(args []string) {
+	var cfg feedgen.Config
+	var from, to, outdir string
+	var pause bool
+
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	fs.StringVar(&from, "from", "", "hash or hash prefix of the first commit to show")
+	fs.StringVar(&to, "to", "", "hash or hash prefix of the last commit to show, processing stops after it")
+	fs.BoolVar(&pause, "pause", false, "wait for enter after every commit")
+	fs.StringVar(&outdir, "step-output", "", "directory to write one json file per commit to instead of printing")
+	parseFlags(fs, &cfg, args)
+
+	if outdir != "" {
+		if err := os.MkdirAll(outdir, 0755); err != nil {
+			log.Fatalf("failed to create step output directory: %v", err)
+		}
+		if feedgen.InsideDir(cfg.Destdir, outdir) {
+			os.Remove(outdir)
+			log.Fatalf("step output directory must not be inside destdir: %s", outdir)
+		}
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	running := make(map[string]int)
+	showing := from == ""
+	n := 0
+
+	cfg.Step = func(s *feedgen.Step) bool {
+		hash := s.Commit.Hash.String()
+		if !showing && strings.HasPrefix(hash, from) {
+			showing = true
+		}
+
+		// the running map sums up all commits processed so far, shown or not
+		for name, v := range s.Changes {
+			running[name] += v
+			if running[name] == 0 {
+				delete(running, name)
+			}
+		}
+
+		if !showing {
+			return true
+		}
+		n++
+
+		d := &stepDump{
+			Index:   n,
+			Commit:  hash,
+			Parent:  s.Parent.Hash.String(),
+			Author:  s.Commit.Author.Name,
+			Date:    s.Commit.Author.When,
+			Message: strings.TrimSpace(s.Commit.Message),
+			Changes: []stepChange{},
+			Net:     s.Changes,
+			Running: running,
+			Items:   []stepItem{},
+		}
+		for _, m := range s.Matches {
+			d.Changes = append(d.Changes, stepChange{Sign: m[1], Name: m[2], URL: m[3], Description: m[4]})
+		}
+		for _, item := range s.Items {
+			si := stepItem{Title: item.Title, Link: item.Link.Href}
+			if m := s.Meta[item]; m != nil {
+				si.Kind, si.EntryID, si.Section = m.Kind, m.EntryID, m.Section
+			}
+			d.Items = append(d.Items, si)
+		}
+
+		if outdir != "" {
+			data, err := json.MarshalIndent(d, "", "  ")
+			if err != nil {
+				log.Fatalf("failed to generate json: %v", err)
+			}
+			name := filepath.Join(outdir, fmt.Sprintf("%04d-%s.json", n, hash[:12]))
+			if err := os.WriteFile(name, append(data, '\n'), 0644); err != nil {
+				log.Fatalf("failed to write step: %v", err)
+			}
+		} else {
+			printStep(d)
+		}
+
+		if pause {
+			fmt.Fprint(os.Stderr, "press enter to continue")
+			if _, err := stdin.ReadString('\n'); err != nil {
+				return false
+			}
+		}
+
+		return to == "" || !strings.HasPrefix(hash, to)
+	}
+
+	if _, err := feedgen.Collect(context.Background(), cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	if !showing {
+		log.Fatalf("commit not found in history of the work file: %s", from)
+	}
+}
+
+// printStep shows a step in a human readable form
+func printStep(d *stepDump) {
+	fmt.Printf("=== %d: %s by %s at %s\n", d.Index, d.Commit, d.Author, d.Date.Format(time.RFC3339))
+	if msg, _, _ := strings.Cut(d.Message, "\n"); msg != "" {
+		fmt.Printf("    %s\n", msg)
+	}
+
+	fmt.Println("changes:")
+	for _, c := range d.Changes {
+		fmt.Printf("  %s %s -- %s -- %s\n", c.Sign, c.Name, c.URL, c.Description)
+	}
+
+	fmt.Println("net:")
+	printCounts(d.Net)
+	fmt.Println("running:")
+	printCounts(d.Running)
+
+	fmt.Println("items:")
+	for _, i := range d.Items {
+		fmt.Printf("  %s\n", i.Title)
+	}
+	fmt.Println()
+}
+
+// printCounts lists counts sorted by name, a zero marks a move that cancelled out
+func printCounts(counts map[string]int) {
+	var names []string
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("  %+d %s\n", counts[name], name)
+	}
+}
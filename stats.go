@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"awesome-veganism-feed/feedgen"
+)
+
+// runStats runs the extraction and prints a summary without writing any files
+func runStats(args []string) {
+	var cfg feedgen.Config
+	var format string
+	var top int
+
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.StringVar(&format, "format", "table", "output format: table or json")
+	fs.IntVar(&top, "top", 10, "number of contributors and categories to list")
+	parseFlags(fs, &cfg, args)
+
+	if format != "table" && format != "json" {
+		log.Fatalf("invalid format: %s", format)
+	}
+
+	st, err := feedgen.Summarize(context.Background(), cfg, top)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to generate json: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Current entries\t%d\n", st.CurrentEntries)
+	if st.OldestAdded != nil {
+		fmt.Fprintf(w, "Oldest surviving entry\t%s (added %s)\n", st.OldestEntry, st.OldestAdded.Format("2006-01-02"))
+	}
+	fmt.Fprintf(w, "Added\t%d\n", st.Added)
+	fmt.Fprintf(w, "Removed\t%d\n", st.Removed)
+	fmt.Fprintf(w, "Updated\t%d\n", st.Updated)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Year\tAdded\tRemoved\tUpdated")
+	for _, y := range st.Years {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\n", y.Year, y.Added, y.Removed, y.Updated)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Contributor\tItems")
+	for _, c := range st.Contributors {
+		fmt.Fprintf(w, "%s\t%d\n", c.Name, c.Count)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Category\tItems")
+	for _, c := range st.Categories {
+		fmt.Fprintf(w, "%s\t%d\n", c.Name, c.Count)
+	}
+	w.Flush()
+}